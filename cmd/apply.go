@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/mreider/a-cli/internal/jira"
@@ -11,14 +13,36 @@ import (
 )
 
 var (
-	applyFile string
-	dryRun    bool
+	applyFile           string
+	dryRun              bool
+	applyDeleteComments bool
+	applyAttachments    bool
 )
 
 var applyCmd = &cobra.Command{
 	Use:   "apply",
 	Short: "Push markdown changes back to JIRA",
-	Long:  `Reads a markdown file with YAML frontmatter, compares it to the current JIRA state, and applies changes. Use --dry-run to preview without applying.`,
+	Long: `Reads a markdown file with YAML frontmatter, compares it to the current JIRA state, and applies changes. Use --dry-run to preview without applying.
+
+New comments can be added under the ## Comments section with a "### new"
+heading; they are posted to the issue, and their headings in the file are
+replaced with the posted comments' so they aren't posted again. Other ###
+headings a comment didn't have on pull are refused, so use #### for headings
+inside a comment. Edited comments are updated in place. Comments removed from the file are
+only deleted in JIRA when --delete-comments is given.
+
+Lines added to or removed from the ## Links section ("- blocks PRODUCT-12",
+"- is blocked by PRODUCT-9") create or remove issue links. Files without a
+## Links section leave links alone.
+
+With --attachments, local files referenced by relative image links in the
+description (e.g. ![diagram](img/flow.png)) are uploaded as attachments
+unless the issue already has an attachment with that file name.
+
+A status that can't be reached with a single transition is reached through
+the shortest chain of transitions in the workflow; the diff lists the
+statuses passed through. If a step fails, apply reports the status the
+issue was left in.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if applyFile == "" {
 			return fmt.Errorf("--file (-f) is required")
@@ -39,11 +63,21 @@ var applyCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("parsing markdown: %w", err)
 		}
+		if err := checkOrigin(applyFile, ticket.Site, ticket.Profile, ticket.URL); err != nil {
+			return err
+		}
 
-		client := jira.NewClient(appConfig)
+		client := newClient()
+		ctx := cmd.Context()
+
+		fieldMap, err := resolveFieldMap(ctx, client)
+		if err != nil {
+			return err
+		}
+		_, extraFields := fieldMapIDs(fieldMap)
 
 		// Fetch current JIRA state
-		current, err := client.GetIssue(ticket.Key)
+		current, err := client.GetIssue(ctx, ticket.Key, extraFields...)
 		if err != nil {
 			return fmt.Errorf("fetching current state of %s: %w", ticket.Key, err)
 		}
@@ -58,9 +92,52 @@ var applyCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("building update payload: %w", err)
 		}
+		// Only send the description when it was edited
+		if sameBody(markdown.ADFToBody(current.Fields.Description), ticket.Body) {
+			payload.Fields.Description = nil
+		}
+
+		// Priority and people are only sent when they differ, since assignee and
+		// reporter need a user lookup and not every screen allows them.
+		if err := setPeopleAndPriority(ctx, client, current, ticket, payload); err != nil {
+			return err
+		}
+		if err := setCustomFields(ctx, client, fieldMap, current, ticket, payload); err != nil {
+			return err
+		}
+		if err := setComponentsAndVersions(ctx, client, current, ticket, payload); err != nil {
+			return err
+		}
+		var plan applyPlan
+		plan.linksToAdd, plan.linksToRemove, err = planLinkChanges(ctx, client, current, ticket)
+		if err != nil {
+			return err
+		}
+		if applyAttachments {
+			plan.uploads, err = pendingUploads(current, ticket, applyFile)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Work out the status path up front so an unreachable status fails
+		// before anything is changed.
+		if ticket.Status != "" && !strings.EqualFold(ticket.Status, current.Fields.Status.Name) {
+			plan.statusPath, err = planStatusPath(ctx, client, current, ticket.Status)
+			if err != nil {
+				return fmt.Errorf("planning status change: %w", err)
+			}
+		}
+
+		if err := checkCommentHeadings(current, ticket); err != nil {
+			return err
+		}
 
 		// Show diff
-		changes := computeChanges(current, ticket, payload)
+		changes := computeChanges(current, ticket, payload, fieldMap, plan)
+		if removed := removedComments(current, ticket); len(removed) > 0 && !applyDeleteComments {
+			fmt.Fprintf(os.Stderr, "Note: %d comment(s) removed from the file are kept in JIRA; use --delete-comments to delete them\n", len(removed))
+		}
 		if len(changes) == 0 {
 			fmt.Println("No changes detected.")
 			return nil
@@ -77,7 +154,15 @@ var applyCmd = &cobra.Command{
 			return nil
 		}
 
-		// Apply field updates (summary, labels, description)
+		// Upload attachments referenced from the description
+		for _, path := range plan.uploads {
+			if _, err := uploadAttachment(ctx, client, ticket.Key, path); err != nil {
+				return err
+			}
+			fmt.Printf("Attached %s to %s\n", filepath.Base(path), ticket.Key)
+		}
+
+		// Apply field updates (summary, labels, description, priority, people, versions)
 		hasFieldChanges := false
 		if payload.Fields.Summary != current.Fields.Summary {
 			hasFieldChanges = true
@@ -88,20 +173,70 @@ var applyCmd = &cobra.Command{
 		if payload.Fields.Description != nil {
 			hasFieldChanges = true
 		}
+		if payload.Fields.Priority != nil || payload.Fields.Assignee != nil || payload.Fields.Reporter != nil {
+			hasFieldChanges = true
+		}
+		if len(payload.Fields.Custom) > 0 {
+			hasFieldChanges = true
+		}
+		if payload.Fields.Components != nil || payload.Fields.FixVersions != nil || payload.Fields.Versions != nil {
+			hasFieldChanges = true
+		}
 
 		if hasFieldChanges {
-			if err := client.UpdateIssue(ticket.Key, *payload); err != nil {
+			if err := client.UpdateIssue(ctx, ticket.Key, *payload); err != nil {
 				return fmt.Errorf("updating issue: %w", err)
 			}
 			fmt.Printf("Updated fields for %s\n", ticket.Key)
 		}
 
-		// Handle status transition
-		if ticket.Status != "" && !strings.EqualFold(ticket.Status, current.Fields.Status.Name) {
-			if err := transitionIssue(client, ticket.Key, ticket.Status); err != nil {
+		// Create and remove links
+		for _, l := range plan.linksToAdd {
+			if err := client.CreateIssueLink(ctx, *l.Link); err != nil {
+				return fmt.Errorf("adding link %q: %w", l, err)
+			}
+			fmt.Printf("Linked %s: %s\n", ticket.Key, l)
+		}
+		for _, l := range plan.linksToRemove {
+			if err := client.DeleteIssueLink(ctx, l.LinkID); err != nil {
+				return fmt.Errorf("removing link %q: %w", l, err)
+			}
+			fmt.Printf("Unlinked %s: %s\n", ticket.Key, l)
+		}
+
+		// Post comments written locally
+		if err := postNewComments(ctx, client, ticket, applyFile, content); err != nil {
+			return err
+		}
+
+		// Update comments edited locally
+		for _, c := range editedComments(current, ticket) {
+			adf, err := markdown.BodyToADF(c.Body)
+			if err != nil {
+				return fmt.Errorf("converting comment to ADF: %w", err)
+			}
+			if err := client.UpdateComment(ctx, ticket.Key, c.ID, adf); err != nil {
+				return fmt.Errorf("updating comment %s: %w", c.ID, err)
+			}
+			fmt.Printf("Updated comment %s on %s\n", c.ID, ticket.Key)
+		}
+
+		// Delete comments removed locally
+		if applyDeleteComments {
+			for _, c := range removedComments(current, ticket) {
+				if err := client.DeleteComment(ctx, ticket.Key, c.ID); err != nil {
+					return fmt.Errorf("deleting comment %s: %w", c.ID, err)
+				}
+				fmt.Printf("Deleted comment %s from %s\n", c.ID, ticket.Key)
+			}
+		}
+
+		// Handle status transition, one hop at a time
+		if len(plan.statusPath) > 0 {
+			if err := executeStatusPath(ctx, client, ticket.Key, current.Fields.Status.Name, plan.statusPath); err != nil {
 				return fmt.Errorf("transitioning status: %w", err)
 			}
-			fmt.Printf("Transitioned %s to '%s'\n", ticket.Key, ticket.Status)
+			fmt.Printf("Transitioned %s to '%s'\n", ticket.Key, plan.statusPath[len(plan.statusPath)-1].To.Name)
 		}
 
 		fmt.Println("Done.")
@@ -109,7 +244,15 @@ var applyCmd = &cobra.Command{
 	},
 }
 
-func computeChanges(current *jira.Issue, ticket *markdown.Ticket, payload *jira.UpdatePayload) []string {
+// applyPlan holds the changes apply makes outside the field update.
+type applyPlan struct {
+	linksToAdd    []linkChange
+	linksToRemove []linkChange
+	uploads       []string
+	statusPath    []jira.TransitionInfo // transitions to reach the new status, in order
+}
+
+func computeChanges(current *jira.Issue, ticket *markdown.Ticket, payload *jira.UpdatePayload, fields map[string]jira.FieldInfo, plan applyPlan) []string {
 	var changes []string
 
 	// Summary
@@ -127,14 +270,391 @@ func computeChanges(current *jira.Issue, ticket *markdown.Ticket, payload *jira.
 		changes = append(changes, "description: (updated)")
 	}
 
-	// Status
-	if ticket.Status != "" && !strings.EqualFold(ticket.Status, current.Fields.Status.Name) {
-		changes = append(changes, fmt.Sprintf("status: %q -> %q", current.Fields.Status.Name, ticket.Status))
+	// Priority and people
+	if payload.Fields.Priority != nil {
+		changes = append(changes, fmt.Sprintf("priority: %q -> %q", current.Fields.Priority.Name, payload.Fields.Priority.Name))
+	}
+	if payload.Fields.Assignee != nil {
+		changes = append(changes, fmt.Sprintf("assignee: %q -> %q", userEmail(current.Fields.Assignee), ticket.Assignee))
+	}
+	if payload.Fields.Reporter != nil {
+		changes = append(changes, fmt.Sprintf("reporter: %q -> %q", userEmail(current.Fields.Reporter), ticket.Reporter))
+	}
+
+	// Components and versions
+	if payload.Fields.Components != nil {
+		changes = append(changes, fmt.Sprintf("components: %v -> %v", jira.ComponentNames(current.Fields.Components), jira.ComponentNames(payload.Fields.Components)))
+	}
+	if payload.Fields.FixVersions != nil {
+		changes = append(changes, fmt.Sprintf("fixVersions: %v -> %v", jira.VersionNames(current.Fields.FixVersions), jira.VersionNames(payload.Fields.FixVersions)))
+	}
+	if payload.Fields.Versions != nil {
+		changes = append(changes, fmt.Sprintf("affectsVersions: %v -> %v", jira.VersionNames(current.Fields.Versions), jira.VersionNames(payload.Fields.Versions)))
+	}
+
+	// Mapped custom fields
+	for _, name := range fieldMapKeys(fields) {
+		f := fields[name]
+		if _, ok := payload.Fields.Custom[f.ID]; ok {
+			from := normalizeFieldValue(markdown.CustomFieldValue(current.Fields.Custom[f.ID]))
+			to := normalizeFieldValue(ticket.Extra[name])
+			changes = append(changes, fmt.Sprintf("%s: %q -> %q", name, from, to))
+		}
+	}
+
+	// Attachments
+	for _, path := range plan.uploads {
+		changes = append(changes, fmt.Sprintf("attachment: upload %q", path))
+	}
+
+	// Links
+	for _, l := range plan.linksToAdd {
+		changes = append(changes, fmt.Sprintf("link: add %q", l))
+	}
+	for _, l := range plan.linksToRemove {
+		changes = append(changes, fmt.Sprintf("link: remove %q", l))
+	}
+
+	// Comments
+	for _, c := range pendingComments(ticket) {
+		changes = append(changes, fmt.Sprintf("comment: add %q", commentPreview(c.Body)))
+	}
+	for _, c := range editedComments(current, ticket) {
+		changes = append(changes, fmt.Sprintf("comment %s: edit -> %q", c.ID, commentPreview(c.Body)))
+	}
+	if applyDeleteComments {
+		for _, c := range removedComments(current, ticket) {
+			changes = append(changes, fmt.Sprintf("comment %s: delete %q", c.ID, commentPreview(markdown.ADFToBody(c.Body))))
+		}
+	}
+
+	// Status, with any intermediate statuses the issue passes through
+	if n := len(plan.statusPath); n > 0 {
+		change := fmt.Sprintf("status: %q -> %q", current.Fields.Status.Name, plan.statusPath[n-1].To.Name)
+		if n > 1 {
+			var via []string
+			for _, t := range plan.statusPath[:n-1] {
+				via = append(via, t.To.Name)
+			}
+			change += fmt.Sprintf(" (via %s)", strings.Join(via, ", "))
+		}
+		changes = append(changes, change)
 	}
 
 	return changes
 }
 
+// setPeopleAndPriority adds priority, assignee and reporter to the payload when
+// the frontmatter differs from JIRA. Emails are resolved to users.
+// An empty frontmatter value leaves the JIRA field unchanged.
+func setPeopleAndPriority(ctx context.Context, client jira.JIRA, current *jira.Issue, ticket *markdown.Ticket, payload *jira.UpdatePayload) error {
+	if ticket.Priority != "" && !strings.EqualFold(ticket.Priority, current.Fields.Priority.Name) {
+		payload.Fields.Priority = &jira.Priority{Name: ticket.Priority}
+	}
+
+	if ticket.Assignee != "" && !strings.EqualFold(ticket.Assignee, userEmail(current.Fields.Assignee)) {
+		assignee, err := resolveUser(ctx, client, ticket.Assignee)
+		if err != nil {
+			return fmt.Errorf("resolving assignee: %w", err)
+		}
+		payload.Fields.Assignee = &assignee
+	}
+
+	if ticket.Reporter != "" && !strings.EqualFold(ticket.Reporter, userEmail(current.Fields.Reporter)) {
+		reporter, err := resolveUser(ctx, client, ticket.Reporter)
+		if err != nil {
+			return fmt.Errorf("resolving reporter: %w", err)
+		}
+		payload.Fields.Reporter = &reporter
+	}
+
+	return nil
+}
+
+// setCustomFields adds mapped custom fields whose frontmatter value differs
+// from JIRA to the payload. Fields absent from the frontmatter are left alone.
+func setCustomFields(ctx context.Context, client jira.JIRA, fields map[string]jira.FieldInfo, current *jira.Issue, ticket *markdown.Ticket, payload *jira.UpdatePayload) error {
+	for _, name := range fieldMapKeys(fields) {
+		local, ok := ticket.Extra[name]
+		if !ok {
+			continue
+		}
+		f := fields[name]
+		if fieldValuesEqual(local, markdown.CustomFieldValue(current.Fields.Custom[f.ID])) {
+			continue
+		}
+
+		value, err := customFieldUpdate(ctx, client, f, local)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+		if payload.Fields.Custom == nil {
+			payload.Fields.Custom = make(map[string]any)
+		}
+		payload.Fields.Custom[f.ID] = value
+	}
+	return nil
+}
+
+// setComponentsAndVersions adds components, fix versions and affects versions
+// to the payload when the frontmatter list differs from JIRA. Names are checked
+// against the project before anything is sent. A list absent from the
+// frontmatter is left unchanged; an empty list clears the field.
+func setComponentsAndVersions(ctx context.Context, client jira.JIRA, current *jira.Issue, ticket *markdown.Ticket, payload *jira.UpdatePayload) error {
+	componentsChanged := ticket.Components != nil && !labelsEqual(ticket.Components, jira.ComponentNames(current.Fields.Components))
+	fixChanged := ticket.FixVersions != nil && !labelsEqual(ticket.FixVersions, jira.VersionNames(current.Fields.FixVersions))
+	affectsChanged := ticket.AffectsVersions != nil && !labelsEqual(ticket.AffectsVersions, jira.VersionNames(current.Fields.Versions))
+	if !componentsChanged && !fixChanged && !affectsChanged {
+		return nil
+	}
+
+	projectKey := projectKeyOf(ticket.Key)
+
+	if componentsChanged {
+		available, err := client.GetProjectComponents(ctx, projectKey)
+		if err != nil {
+			return fmt.Errorf("fetching components of %s: %w", projectKey, err)
+		}
+		names, err := matchProjectNames("component", ticket.Components, jira.ComponentNames(available))
+		if err != nil {
+			return err
+		}
+		payload.Fields.Components = make([]jira.Component, 0, len(names))
+		for _, name := range names {
+			payload.Fields.Components = append(payload.Fields.Components, jira.Component{Name: name})
+		}
+	}
+
+	if fixChanged || affectsChanged {
+		available, err := client.GetProjectVersions(ctx, projectKey)
+		if err != nil {
+			return fmt.Errorf("fetching versions of %s: %w", projectKey, err)
+		}
+		if fixChanged {
+			names, err := matchProjectNames("version", ticket.FixVersions, jira.VersionNames(available))
+			if err != nil {
+				return fmt.Errorf("fixVersions: %w", err)
+			}
+			payload.Fields.FixVersions = versionRefs(names)
+		}
+		if affectsChanged {
+			names, err := matchProjectNames("version", ticket.AffectsVersions, jira.VersionNames(available))
+			if err != nil {
+				return fmt.Errorf("affectsVersions: %w", err)
+			}
+			payload.Fields.Versions = versionRefs(names)
+		}
+	}
+
+	return nil
+}
+
+// matchProjectNames maps each wanted name to the project's spelling of it,
+// ignoring case, and reports every name the project doesn't have.
+func matchProjectNames(kind string, wanted, available []string) ([]string, error) {
+	byLower := make(map[string]string, len(available))
+	for _, name := range available {
+		byLower[strings.ToLower(name)] = name
+	}
+
+	matched := make([]string, 0, len(wanted))
+	var unknown []string
+	for _, name := range wanted {
+		if canonical, ok := byLower[strings.ToLower(name)]; ok {
+			matched = append(matched, canonical)
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown %s(s) %s; available: %s", kind, strings.Join(unknown, ", "), strings.Join(available, ", "))
+	}
+	return matched, nil
+}
+
+func versionRefs(names []string) []jira.Version {
+	refs := make([]jira.Version, 0, len(names))
+	for _, name := range names {
+		refs = append(refs, jira.Version{Name: name})
+	}
+	return refs
+}
+
+// projectKeyOf returns the project part of an issue key, e.g. "PRODUCT" for "PRODUCT-123".
+func projectKeyOf(key string) string {
+	if i := strings.LastIndex(key, "-"); i > 0 {
+		return key[:i]
+	}
+	return key
+}
+
+// userEmail returns the user's email address, or "" for a nil user.
+func userEmail(u *jira.User) string {
+	if u == nil {
+		return ""
+	}
+	return u.EmailAddress
+}
+
+// postNewComments posts the comments written locally, then records the
+// posted ones in the file with their JIRA heading and id so that applying
+// the file again doesn't post them twice.
+func postNewComments(ctx context.Context, client jira.JIRA, ticket *markdown.Ticket, path string, content []byte) error {
+	var posted []*jira.Comment // one per "### new" heading, nil if not posted
+	var postErr error
+	for _, c := range ticket.Comments {
+		if !c.New {
+			continue
+		}
+		if c.Body == "" || postErr != nil {
+			posted = append(posted, nil)
+			continue
+		}
+		adf, err := markdown.BodyToADF(c.Body)
+		if err != nil {
+			postErr = fmt.Errorf("converting comment to ADF: %w", err)
+			posted = append(posted, nil)
+			continue
+		}
+		jc, err := client.AddComment(ctx, ticket.Key, adf)
+		if err != nil {
+			postErr = fmt.Errorf("adding comment: %w", err)
+			posted = append(posted, nil)
+			continue
+		}
+		posted = append(posted, jc)
+		fmt.Printf("Added comment to %s\n", ticket.Key)
+	}
+
+	if updated := markdown.RecordPostedComments(string(content), posted); updated != string(content) {
+		if err := writeFileAtomic(path, []byte(updated)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not record the posted comments in %s (%v); re-pull it before applying again\n", path, err)
+		}
+	}
+	return postErr
+}
+
+// pendingComments returns the comments written locally that don't exist in JIRA yet.
+func pendingComments(ticket *markdown.Ticket) []markdown.TicketComment {
+	var pending []markdown.TicketComment
+	for _, c := range ticket.Comments {
+		if c.New && c.Body != "" {
+			pending = append(pending, c)
+		}
+	}
+	return pending
+}
+
+// editedComments returns local comments whose body differs from the JIRA comment
+// with the same id. Comments whose id no longer exists in JIRA are ignored.
+func editedComments(current *jira.Issue, ticket *markdown.Ticket) []markdown.TicketComment {
+	existing := currentComments(current)
+	var edited []markdown.TicketComment
+	for _, c := range ticket.Comments {
+		if c.ID == "" {
+			continue
+		}
+		jc, ok := existing[c.ID]
+		if !ok {
+			continue
+		}
+		if !sameBody(markdown.ADFToBody(jc.Body), c.Body) {
+			edited = append(edited, c)
+		}
+	}
+	return edited
+}
+
+// sameBody reports whether two markdown bodies say the same thing once both
+// are converted to ADF and back, so that differences only in how the text is
+// written (line endings, trailing spaces, blank lines, list markers) don't
+// count as edits.
+func sameBody(a, b string) bool {
+	return normalizeBody(a) == normalizeBody(b)
+}
+
+func normalizeBody(body string) string {
+	lines := strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	adf, err := markdown.BodyToADF(strings.Join(lines, "\n"))
+	if err != nil {
+		return strings.TrimSpace(body)
+	}
+	return markdown.ADFToBody(adf)
+}
+
+// checkCommentHeadings refuses ### headings added inside a comment. They
+// were most likely meant to start a new comment, and would otherwise be sent
+// as part of the comment above them, possibly someone else's.
+func checkCommentHeadings(current *jira.Issue, ticket *markdown.Ticket) error {
+	existing := currentComments(current)
+	for _, c := range ticket.Comments {
+		if c.ID == "" && !c.New {
+			continue // pulled before ids were recorded; never sent
+		}
+		pulled := map[string]bool{}
+		if jc, ok := existing[c.ID]; ok && c.ID != "" {
+			for _, line := range strings.Split(markdown.ADFToBody(jc.Body), "\n") {
+				pulled[strings.TrimSpace(line)] = true
+			}
+		}
+		for _, line := range strings.Split(c.Body, "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "### ") && !pulled[line] {
+				return fmt.Errorf("comment heading %q doesn't start a comment: start new comments with \"### new\", and use #### for headings inside a comment", line)
+			}
+		}
+	}
+	return nil
+}
+
+// removedComments returns JIRA comments that no longer appear in the file.
+// Files pulled before comment ids were recorded can't be mapped back, so
+// nothing is reported as removed for them.
+func removedComments(current *jira.Issue, ticket *markdown.Ticket) []jira.Comment {
+	if current.Fields.Comment == nil {
+		return nil
+	}
+
+	local := make(map[string]bool)
+	for _, c := range ticket.Comments {
+		if c.ID == "" && !c.New {
+			return nil
+		}
+		if c.ID != "" {
+			local[c.ID] = true
+		}
+	}
+
+	var removed []jira.Comment
+	for _, jc := range current.Fields.Comment.Comments {
+		if jc.ID != "" && !local[jc.ID] {
+			removed = append(removed, jc)
+		}
+	}
+	return removed
+}
+
+// currentComments indexes the issue's JIRA comments by id.
+func currentComments(current *jira.Issue) map[string]jira.Comment {
+	byID := make(map[string]jira.Comment)
+	if current.Fields.Comment == nil {
+		return byID
+	}
+	for _, jc := range current.Fields.Comment.Comments {
+		byID[jc.ID] = jc
+	}
+	return byID
+}
+
+// commentPreview returns the first line of a comment body, truncated for display.
+func commentPreview(body string) string {
+	line := strings.TrimSpace(strings.SplitN(body, "\n", 2)[0])
+	return truncate(line, 60)
+}
+
 func labelsEqual(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
@@ -151,30 +671,21 @@ func labelsEqual(a, b []string) bool {
 	return true
 }
 
-func transitionIssue(client *jira.Client, key string, targetStatus string) error {
-	transitions, err := client.GetTransitions(key)
-	if err != nil {
-		return fmt.Errorf("fetching transitions: %w", err)
-	}
-
-	for _, t := range transitions {
+// findTransition returns the transition leading to targetStatus, matched
+// case-insensitively against the destination status or the transition name.
+func findTransition(transitions []jira.TransitionInfo, targetStatus string) *jira.TransitionInfo {
+	for i, t := range transitions {
 		if strings.EqualFold(t.To.Name, targetStatus) || strings.EqualFold(t.Name, targetStatus) {
-			return client.DoTransition(key, t.ID)
+			return &transitions[i]
 		}
 	}
-
-	// List available transitions for user
-	var available []string
-	for _, t := range transitions {
-		available = append(available, fmt.Sprintf("'%s' (-> %s)", t.Name, t.To.Name))
-	}
-
-	return fmt.Errorf("no transition found to status %q; available transitions: %s",
-		targetStatus, strings.Join(available, ", "))
+	return nil
 }
 
 func init() {
 	applyCmd.Flags().StringVarP(&applyFile, "file", "f", "", "markdown file to apply (required)")
 	applyCmd.Flags().BoolVar(&dryRun, "dry-run", false, "preview changes without applying")
+	applyCmd.Flags().BoolVar(&applyDeleteComments, "delete-comments", false, "delete JIRA comments that were removed from the file")
+	applyCmd.Flags().BoolVar(&applyAttachments, "attachments", false, "upload local files referenced by relative image links in the description")
 	rootCmd.AddCommand(applyCmd)
 }
@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mreider/a-cli/internal/jira"
+	"github.com/mreider/a-cli/internal/jira/jiratest"
+	"github.com/mreider/a-cli/internal/markdown"
+)
+
+func newApplyServer(t *testing.T) *jiratest.Server {
+	srv := jiratest.NewServer(t)
+	srv.AddVersions("PROJ", "2.3", "2.4")
+	srv.AddIssue(jira.Issue{Key: "PROJ-1", Fields: jira.Fields{
+		Summary:     "Fix login",
+		Labels:      []string{"auth"},
+		Description: adfText("Steps to reproduce."),
+	}})
+	return srv
+}
+
+func TestApply_UpdatesFieldsCommentsAndStatus(t *testing.T) {
+	srv := newApplyServer(t)
+	path := pullIssue(t, srv, "PROJ-1")
+	editFile(t, path,
+		"title: Fix login", "title: Fix login on Safari",
+		"labels: [auth]", "labels: [auth, safari]",
+		"status: To Do", "status: In Review",
+		"fixVersions: []", "fixVersions: [2.4]",
+		"Steps to reproduce.", "Steps to reproduce on Safari.",
+	)
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, append(data, "\n## Comments\n\n### new\n\nDeployed to staging.\n"...), 0644)
+
+	stdout, stderr, err := runCommand(t, srv, "apply", "-f", path)
+	if err != nil {
+		t.Fatalf("apply: %v\n%s", err, stderr)
+	}
+	for _, want := range []string{
+		`title: "Fix login" -> "Fix login on Safari"`,
+		`fixVersions: [] -> [2.4]`,
+		`status: "To Do" -> "In Review" (via In Progress)`,
+		`comment: add "Deployed to staging."`,
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("diff is missing %q:\n%s", want, stdout)
+		}
+	}
+
+	issue := srv.Issue("PROJ-1")
+	f := issue.Fields
+	if f.Summary != "Fix login on Safari" || strings.Join(f.Labels, ",") != "auth,safari" {
+		t.Errorf("fields not updated: %q %v", f.Summary, f.Labels)
+	}
+	if len(f.FixVersions) != 1 || f.FixVersions[0].Name != "2.4" {
+		t.Errorf("fixVersions not updated: %v", f.FixVersions)
+	}
+	if f.Status.Name != "In Review" {
+		t.Errorf("expected status In Review, got %q", f.Status.Name)
+	}
+	if f.Comment == nil || len(f.Comment.Comments) != 1 {
+		t.Fatalf("expected one comment, got %+v", f.Comment)
+	}
+	if !strings.Contains(issueText(f.Description), "on Safari") {
+		t.Errorf("description not updated: %+v", f.Description)
+	}
+}
+
+func TestApply_OnlyUpdatesEditedComments(t *testing.T) {
+	steps, _ := markdown.BodyToADF("Seen on Safari.\n\n### Steps\n\n* Open the login page\n* Submit")
+	srv := jiratest.NewServer(t)
+	srv.AddIssue(jira.Issue{Key: "PROJ-1", Fields: jira.Fields{
+		Summary:     "Fix login",
+		Description: adfText("Steps to reproduce."),
+		Comment: &jira.Comments{Comments: []jira.Comment{
+			{ID: "100", Author: jira.User{EmailAddress: "ana@example.com"}, Body: steps, Created: "2024-03-01T10:00:00.000+0000"},
+			{ID: "101", Author: jira.User{EmailAddress: "bo@example.com"}, Body: adfText("Fixed."), Created: "2024-03-02T10:00:00.000+0000"},
+		}},
+	}})
+	path := pullIssue(t, srv, "PROJ-1")
+
+	// Saving the file with Windows line endings doesn't edit the description
+	// or any comment.
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, []byte(strings.ReplaceAll(string(data), "\n", "\r\n")), 0644)
+
+	stdout, stderr, err := runCommand(t, srv, "apply", "-f", path)
+	if err != nil {
+		t.Fatalf("apply: %v\n%s", err, stderr)
+	}
+	if got := commentUpdates(srv); len(got) != 0 || !strings.Contains(stdout, "No changes detected.") {
+		t.Errorf("expected an unedited file to change nothing, got %v:\n%s", got, stdout)
+	}
+
+	path = pullIssue(t, srv, "PROJ-1")
+	editFile(t, path, "Fixed.", "Fixed in 2.4.")
+	if _, stderr, err := runCommand(t, srv, "apply", "-f", path); err != nil {
+		t.Fatalf("apply: %v\n%s", err, stderr)
+	}
+	if got := commentUpdates(srv); len(got) != 1 || !strings.HasSuffix(got[0], "/comment/101") {
+		t.Errorf("expected only comment 101 to be updated, got %v", got)
+	}
+}
+
+func TestApply_RefusesHeadingAddedToComment(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	srv.AddIssue(jira.Issue{Key: "PROJ-1", Fields: jira.Fields{
+		Summary: "Fix login",
+		Comment: &jira.Comments{Comments: []jira.Comment{
+			{ID: "100", Author: jira.User{EmailAddress: "ana@example.com"}, Body: adfText("Looks good."), Created: "2024-03-01T10:00:00.000+0000"},
+		}},
+	}})
+	path := pullIssue(t, srv, "PROJ-1")
+	editFile(t, path, "Looks good.", "Looks good.\n\n### Notes\n\nMeant as a new comment.")
+
+	_, _, err := runCommand(t, srv, "apply", "-f", path)
+	if err == nil || !strings.Contains(err.Error(), `"### Notes"`) {
+		t.Fatalf("expected the heading to be refused, got %v", err)
+	}
+	if got := commentUpdates(srv); len(got) != 0 {
+		t.Errorf("expected no comment updates, got %v", got)
+	}
+}
+
+func TestApply_DoesNotPostNewCommentsTwice(t *testing.T) {
+	srv := newApplyServer(t)
+	path := pullIssue(t, srv, "PROJ-1")
+	// Without an updated: value the staleness check can't catch a re-apply
+	content := regexp.MustCompile(`(?m)^updated: .*\n`).ReplaceAllString(readFile(t, path), "")
+	os.WriteFile(path, []byte(content+"\n## Comments\n\n### new\n\nDeployed to staging.\n"), 0644)
+
+	for i := 0; i < 2; i++ {
+		if _, stderr, err := runCommand(t, srv, "apply", "-f", path); err != nil {
+			t.Fatalf("apply %d: %v\n%s", i+1, err, stderr)
+		}
+	}
+	if f := srv.Issue("PROJ-1").Fields; f.Comment == nil || len(f.Comment.Comments) != 1 {
+		t.Fatalf("expected the comment to be posted once, got %+v", f.Comment)
+	}
+	ticket, err := markdown.Unmarshal(readFile(t, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ticket.Comments) != 1 || ticket.Comments[0].New || ticket.Comments[0].ID == "" {
+		t.Errorf("expected the file to record the posted comment, got %+v", ticket.Comments)
+	}
+}
+
+// commentUpdates returns the comment update requests served so far.
+func commentUpdates(srv *jiratest.Server) []string {
+	var updates []string
+	for _, req := range srv.Requests() {
+		if strings.HasPrefix(req, "PUT ") && strings.Contains(req, "/comment/") {
+			updates = append(updates, req)
+		}
+	}
+	return updates
+}
+
+func TestApply_DryRunChangesNothing(t *testing.T) {
+	srv := newApplyServer(t)
+	path := pullIssue(t, srv, "PROJ-1")
+	editFile(t, path, "title: Fix login", "title: Something else", "status: To Do", "status: Done")
+	before := srv.Issue("PROJ-1")
+
+	stdout, stderr, err := runCommand(t, srv, "apply", "-f", path, "--dry-run")
+	if err != nil {
+		t.Fatalf("apply --dry-run: %v\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, `status: "To Do" -> "Done" (via In Progress, In Review)`) {
+		t.Errorf("unexpected diff:\n%s", stdout)
+	}
+	if after := srv.Issue("PROJ-1"); after.Fields.Updated != before.Fields.Updated {
+		t.Errorf("dry run changed the issue")
+	}
+}
+
+func TestApply_RefusesStaleFile(t *testing.T) {
+	srv := newApplyServer(t)
+	path := pullIssue(t, srv, "PROJ-1")
+	client := testClient(srv)
+	if err := client.UpdateIssue(context.Background(), "PROJ-1", jira.UpdatePayload{Fields: jira.UpdateFields{Summary: "Changed elsewhere"}}); err != nil {
+		t.Fatal(err)
+	}
+	editFile(t, path, "title: Fix login", "title: Mine")
+
+	_, _, err := runCommand(t, srv, "apply", "-f", path)
+	if err == nil || !strings.Contains(err.Error(), "modified in JIRA since your last pull") {
+		t.Fatalf("expected a conflict error, got %v", err)
+	}
+	if got := srv.Issue("PROJ-1").Fields.Summary; got != "Changed elsewhere" {
+		t.Errorf("stale apply overwrote the summary with %q", got)
+	}
+}
+
+func TestApply_UnknownVersionChangesNothing(t *testing.T) {
+	srv := newApplyServer(t)
+	path := pullIssue(t, srv, "PROJ-1")
+	editFile(t, path, "title: Fix login", "title: Mine", "fixVersions: []", "fixVersions: [9.9]")
+
+	_, _, err := runCommand(t, srv, "apply", "-f", path)
+	if err == nil || !strings.Contains(err.Error(), "9.9") {
+		t.Fatalf("expected an unknown version error, got %v", err)
+	}
+	for _, req := range srv.Requests() {
+		if strings.HasPrefix(req, "PUT ") {
+			t.Errorf("expected no updates, saw %s", req)
+		}
+	}
+}
+
+func TestApply_LearnsWorkflowFromOtherIssues(t *testing.T) {
+	srv := newApplyServer(t)
+	// Without permission to read workflows, apply looks at the transitions
+	// offered on another issue sitting in the intermediate status.
+	srv.AddIssue(jira.Issue{Key: "PROJ-2", Fields: jira.Fields{Summary: "Other", Status: jira.Status{Name: "In Progress"}}})
+	path := pullIssue(t, srv, "PROJ-1")
+	editFile(t, path, "status: To Do", "status: In Review")
+	srv.FailNext("GET", "/rest/api/3/workflowscheme/project", http.StatusForbidden)
+
+	stdout, stderr, err := runCommand(t, srv, "apply", "-f", path)
+	if err != nil {
+		t.Fatalf("apply: %v\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, "(via In Progress)") {
+		t.Errorf("unexpected diff:\n%s", stdout)
+	}
+	if !containsRequest(srv, "POST /rest/api/3/search/jql") || containsRequest(srv, "GET /rest/api/3/workflow/search") {
+		t.Errorf("expected the path to be learned by searching, got %v", srv.Requests())
+	}
+	if got := srv.Issue("PROJ-1").Fields.Status.Name; got != "In Review" {
+		t.Errorf("expected status In Review, got %q", got)
+	}
+}
+
+// issueText returns the text in an ADF document.
+func issueText(node *jira.ADFNode) string {
+	if node == nil {
+		return ""
+	}
+	text := node.Text
+	for i := range node.Content {
+		text += issueText(&node.Content[i])
+	}
+	return text
+}
+
+func containsRequest(srv *jiratest.Server, want string) bool {
+	for _, req := range srv.Requests() {
+		if req == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mreider/a-cli/internal/jira"
+	"github.com/mreider/a-cli/internal/markdown"
+	"github.com/spf13/cobra"
+)
+
+var attachCmd = &cobra.Command{
+	Use:   "attach <issue-key> <file>...",
+	Short: "Upload files as attachments to a JIRA issue",
+	Long: `Uploads one or more local files to a JIRA issue as attachments.
+
+Examples:
+  a-cli attach PRODUCT-123 screenshot.png
+  a-cli attach PRODUCT-123 logs/*.txt`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+
+		issueKey := strings.ToUpper(args[0])
+		client := newClient()
+		ctx := cmd.Context()
+
+		for _, path := range args[1:] {
+			attachments, err := uploadAttachment(ctx, client, issueKey, path)
+			if err != nil {
+				return err
+			}
+			for _, a := range attachments {
+				fmt.Fprintf(os.Stderr, "Attached %s (%d bytes) to %s\n", a.Filename, a.Size, issueKey)
+			}
+		}
+		return nil
+	},
+}
+
+// uploadAttachment uploads a single local file to the issue.
+func uploadAttachment(ctx context.Context, client jira.JIRA, key, path string) ([]jira.Attachment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer f.Close()
+
+	attachments, err := client.AddAttachment(ctx, key, filepath.Base(path), f)
+	if err != nil {
+		return nil, fmt.Errorf("uploading %s to %s: %w", path, key, err)
+	}
+	return attachments, nil
+}
+
+// pendingUploads returns the local files referenced by relative image links in
+// the description that aren't attached to the issue yet (matched by file name).
+// Paths are resolved relative to the markdown file.
+func pendingUploads(current *jira.Issue, ticket *markdown.Ticket, mdPath string) ([]string, error) {
+	attached := make(map[string]bool)
+	for _, a := range current.Fields.Attachment {
+		attached[strings.ToLower(a.Filename)] = true
+	}
+	for _, name := range markdown.AttachmentFileNames(current.Fields.Attachment) {
+		attached[strings.ToLower(name)] = true
+	}
+
+	var uploads []string
+	for _, ref := range markdown.LocalImagePaths(ticket.Body) {
+		if attached[strings.ToLower(filepath.Base(ref))] {
+			continue
+		}
+		path := filepath.Join(filepath.Dir(mdPath), filepath.FromSlash(ref))
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("image %s referenced in the description: %w", ref, err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("image %s referenced in the description is a directory", ref)
+		}
+		uploads = append(uploads, path)
+	}
+	return uploads, nil
+}
+
+// assetsDirName is the directory, next to <KEY>.md, that holds an issue's attachments.
+func assetsDirName(key string) string {
+	return key + ".assets"
+}
+
+// downloadAttachments saves the issue's attachments into <dir>/<KEY>.assets/.
+// Files already present with the expected size are not downloaded again.
+// Returns the number of files downloaded.
+func downloadAttachments(ctx context.Context, client jira.JIRA, issue *jira.Issue, dir string) (int, error) {
+	if len(issue.Fields.Attachment) == 0 {
+		return 0, nil
+	}
+
+	assetsDir := filepath.Join(dir, assetsDirName(issue.Key))
+	if err := os.MkdirAll(assetsDir, 0755); err != nil {
+		return 0, fmt.Errorf("creating %s: %w", assetsDir, err)
+	}
+
+	downloaded := 0
+	for i, name := range markdown.AttachmentFileNames(issue.Fields.Attachment) {
+		a := issue.Fields.Attachment[i]
+		path := filepath.Join(assetsDir, name)
+		if info, err := os.Stat(path); err == nil && info.Size() == a.Size {
+			continue
+		}
+
+		if err := downloadAttachment(ctx, client, a, path); err != nil {
+			return downloaded, err
+		}
+		downloaded++
+	}
+	return downloaded, nil
+}
+
+// downloadAttachment writes one attachment to path. The download goes to a
+// temporary file that is renamed into place once complete, so a failed or
+// interrupted download never leaves a partial file.
+func downloadAttachment(ctx context.Context, client jira.JIRA, a jira.Attachment, path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.part")
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", path, err)
+	}
+	tmp := f.Name()
+
+	if err := client.DownloadAttachment(ctx, a.ID, f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("downloading %s: %w", a.Filename, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp, 0644); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(attachCmd)
+}
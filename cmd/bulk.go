@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mreider/a-cli/internal/jira"
+	"github.com/spf13/cobra"
+)
+
+// pullConcurrency is the --concurrency flag shared by the bulk pull commands.
+var pullConcurrency int
+
+func addConcurrencyFlag(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&pullConcurrency, "concurrency", jira.DefaultConcurrency, "number of items to fetch in parallel")
+}
+
+// pullResult is what a worker produces for one item of a bulk pull. Warnings
+// are printed when the item is written so output keeps the input order.
+type pullResult struct {
+	title    string
+	md       string
+	warnings []string
+}
+
+func (r *pullResult) warn(format string, args ...any) {
+	r.warnings = append(r.warnings, fmt.Sprintf(format, args...))
+}
+
+// pullFailure is an item a bulk pull couldn't write.
+type pullFailure struct {
+	Item string
+	Err  error
+}
+
+// errInterrupted is returned by a command stopped with Ctrl-C, after it has
+// reported what it finished.
+var errInterrupted = errors.New("interrupted")
+
+// reportInterrupted prints what a command finished before it was interrupted
+// and returns errInterrupted.
+func reportInterrupted(format string, args ...any) error {
+	fmt.Fprintf(os.Stderr, "\nInterrupted: "+format+"\n", args...)
+	return errInterrupted
+}
+
+// isCancelled reports whether err comes from the command being interrupted.
+// Such items are not listed as failures.
+func isCancelled(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
+
+// reportPullFailures lists the items that failed at the end of a bulk pull
+// and returns an error if there were any.
+func reportPullFailures(noun string, failures []pullFailure) error {
+	if len(failures) == 0 {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Not pulled:\n")
+	for _, f := range failures {
+		fmt.Fprintf(os.Stderr, "  %s: %v\n", f.Item, f.Err)
+	}
+	return fmt.Errorf("%d %s not pulled", len(failures), noun)
+}
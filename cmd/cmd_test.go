@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mreider/a-cli/internal/config"
+	"github.com/mreider/a-cli/internal/jira"
+	"github.com/mreider/a-cli/internal/jira/jiratest"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// runCommand runs a-cli with args against srv, with every flag back at its
+// default, and returns what the command wrote to stdout and stderr.
+func runCommand(t *testing.T, srv *jiratest.Server, args ...string) (stdout, stderr string, err error) {
+	t.Helper()
+	cfg := fmt.Sprintf("url: %s\nemail: me@example.com\ntoken: test-token\n", srv.URL)
+	return runWithConfig(t, cfg, args...)
+}
+
+// runWithConfig is like runCommand with cfg as the config file.
+func runWithConfig(t *testing.T, cfg string, args ...string) (stdout, stderr string, err error) {
+	t.Helper()
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte(cfg), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, env := range []string{"JIRA_URL", "JIRA_EMAIL", "JIRA_TOKEN", "A_CLI_TIMEOUT", "A_CLI_DEBUG", "A_CLI_PROFILE"} {
+		t.Setenv(env, "")
+	}
+
+	resetFlags(rootCmd)
+	rootCmd.SetArgs(append([]string{"--config", cfgPath}, args...))
+
+	outFile := captureFile(t, "stdout")
+	errFile := captureFile(t, "stderr")
+	origOut, origErr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = outFile, errFile
+	err = rootCmd.ExecuteContext(context.Background())
+	os.Stdout, os.Stderr = origOut, origErr
+
+	return readCapture(t, outFile), readCapture(t, errFile), err
+}
+
+// testClient returns a client for srv, for tests that call command helpers
+// directly.
+func testClient(srv *jiratest.Server) *jira.Client {
+	return jira.NewClient(config.Config{URL: srv.URL, Email: "me@example.com", Token: "test-token"})
+}
+
+// resetFlags puts every flag of cmd and its subcommands back to its default,
+// since flag variables are package globals that outlive a single run.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			slice.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
+
+// discardStderr sends what the test writes to os.Stderr, such as progress
+// output from command helpers it calls directly, to a temporary file.
+func discardStderr(t *testing.T) {
+	f := captureFile(t, "stderr")
+	orig := os.Stderr
+	os.Stderr = f
+	t.Cleanup(func() { os.Stderr = orig })
+}
+
+func captureFile(t *testing.T, name string) *os.File {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func readCapture(t *testing.T, f *os.File) string {
+	t.Helper()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// adfText returns a one-paragraph ADF document.
+func adfText(text string) *jira.ADFNode {
+	version := 1
+	return &jira.ADFNode{
+		Type:    "doc",
+		Version: &version,
+		Content: []jira.ADFNode{{Type: "paragraph", Content: []jira.ADFNode{{Type: "text", Text: text}}}},
+	}
+}
+
+// pullIssue runs get --output-dir for key and returns the file written.
+func pullIssue(t *testing.T, srv *jiratest.Server, key string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if _, stderr, err := runCommand(t, srv, "get", key, "--output-dir", dir); err != nil {
+		t.Fatalf("get %s: %v\n%s", key, err, stderr)
+	}
+	return filepath.Join(dir, key+".md")
+}
+
+// editFile applies replacements (old, new, old, new, ...) to a file and
+// fails the test if any old text is missing.
+func editFile(t *testing.T, path string, replacements ...string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	for i := 0; i < len(replacements); i += 2 {
+		if !strings.Contains(content, replacements[i]) {
+			t.Fatalf("%s has no %q:\n%s", path, replacements[i], content)
+		}
+		content = strings.Replace(content, replacements[i], replacements[i+1], 1)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// adfJSON returns a one-paragraph ADF document as JSON, the way Confluence
+// stores page bodies.
+func adfJSON(t *testing.T, text string) string {
+	t.Helper()
+	data, err := json.Marshal(adfText(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -2,28 +2,72 @@ package cmd
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/mreider/a-cli/internal/config"
+	"github.com/mreider/a-cli/internal/oauth"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 )
 
+var (
+	configOAuth bool
+	oauthPort   int
+)
+
 var configCmd = &cobra.Command{
 	Use:   "config",
 	Short: "Configure Atlassian connection settings",
-	Long:  `Interactively set up Atlassian URL, email, and API token. Settings are saved to ~/.a-cli.yaml.`,
+	Long: `Interactively set up Atlassian URL, email, and API token. Settings are saved to ~/.a-cli.yaml.
+
+To read the token from a password manager instead of saving it, leave it
+empty and add token_command: to the config file (see the README).
+
+For a Data Center or Server site, choose the datacenter flavor and enter a
+personal access token instead; no email is needed. If Confluence runs on a
+separate site, give its URL and token too.
+
+With --oauth, signs in to a Cloud site with OAuth 2.0 (3LO) instead of an
+API token. Create an OAuth 2.0 integration in the Atlassian developer
+console with http://127.0.0.1:8765/callback as its callback URL (or the
+port given with --oauth-port), then enter its client ID and secret. The
+refresh token is saved in the config file and renewed as it is used.
+
+With --profile, sets up a named profile (e.g. a sandbox site) under
+profiles: in the same file. A profile's email and token may be left
+empty to use the top-level ones.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		reader := bufio.NewReader(os.Stdin)
+		profile := strings.ToLower(selectedProfile())
 
 		// Load existing config for defaults
 		existing, _ := config.Load(cfgFile)
+		current := existing
+		if profile != "" {
+			// Only the profile's own settings are prompted for and saved, so
+			// the ones it leaves empty keep following the top level.
+			existing, _ = config.ReadFile(cfgFile)
+			current = existing.Profiles[profile]
+			fmt.Printf("Setting up profile %q. Leave email and token empty to use the top-level ones.\n", profile)
+		}
+
+		if configOAuth {
+			cfg, err := setupOAuth(cmd.Context(), reader, current)
+			if err != nil {
+				return err
+			}
+			return saveConfig(existing, cfg, profile)
+		}
 
 		// URL
-		defaultURL := existing.URL
+		defaultURL := current.URL
 		if defaultURL != "" {
 			fmt.Printf("JIRA URL [%s]: ", defaultURL)
 		} else {
@@ -35,55 +79,234 @@ var configCmd = &cobra.Command{
 			url = defaultURL
 		}
 
-		// Email
-		defaultEmail := existing.Email
-		if defaultEmail != "" {
-			fmt.Printf("Email [%s]: ", defaultEmail)
-		} else {
-			fmt.Print("Email: ")
+		// Flavor
+		defaultFlavor := current.Flavor
+		if defaultFlavor == "" {
+			defaultFlavor = config.FlavorCloud
+		}
+		fmt.Printf("Site flavor (%s or %s) [%s]: ", config.FlavorCloud, config.FlavorDataCenter, defaultFlavor)
+		flavor, _ := reader.ReadString('\n')
+		flavor = strings.ToLower(strings.TrimSpace(flavor))
+		if flavor == "" {
+			flavor = defaultFlavor
 		}
-		email, _ := reader.ReadString('\n')
-		email = strings.TrimSpace(email)
-		if email == "" {
-			email = defaultEmail
+		dataCenter := flavor == config.FlavorDataCenter
+
+		// Email (Data Center authenticates with the token alone)
+		email := current.Email
+		if !dataCenter {
+			if email != "" {
+				fmt.Printf("Email [%s]: ", email)
+			} else {
+				fmt.Print("Email: ")
+			}
+			input, _ := reader.ReadString('\n')
+			if input = strings.TrimSpace(input); input != "" {
+				email = input
+			}
 		}
 
 		// Token (masked input)
-		fmt.Print("API Token (input hidden): ")
-		tokenBytes, err := term.ReadPassword(int(syscall.Stdin))
-		fmt.Println() // newline after hidden input
+		tokenPrompt := "API Token"
+		if dataCenter {
+			tokenPrompt = "Personal access token"
+		}
+		token, err := readSecret(tokenPrompt, current.Token)
 		if err != nil {
 			return fmt.Errorf("reading token: %w", err)
 		}
-		token := strings.TrimSpace(string(tokenBytes))
-		if token == "" {
-			token = existing.Token
-		}
 
-		cfg := config.Config{
-			URL:   url,
-			Email: email,
-			Token: token,
+		// Start from the existing config so settings not prompted for are kept
+		cfg := current
+		cfg.URL = url
+		cfg.Email = email
+		cfg.Token = token
+		cfg.Flavor = flavor
+		cfg.OAuth = nil // an API token replaces an earlier OAuth sign-in
+		if flavor == config.FlavorCloud {
+			cfg.Flavor = ""
 		}
 
-		if err := cfg.Validate(); err != nil {
-			return fmt.Errorf("invalid config: %w", err)
+		if dataCenter {
+			if cfg.ConfluenceURL != "" {
+				fmt.Printf("Confluence URL [%s]: ", cfg.ConfluenceURL)
+			} else {
+				fmt.Print("Confluence URL (leave empty if it's on the JIRA site): ")
+			}
+			input, _ := reader.ReadString('\n')
+			if input = strings.TrimSpace(input); input != "" {
+				cfg.ConfluenceURL = input
+			}
+			if cfg.ConfluenceURL != "" {
+				if cfg.ConfluenceToken, err = readSecret("Confluence personal access token (empty to use the JIRA one)", cfg.ConfluenceToken); err != nil {
+					return fmt.Errorf("reading token: %w", err)
+				}
+			}
 		}
 
-		path := cfgFile
-		if path == "" {
-			path = config.DefaultPath()
-		}
+		return saveConfig(existing, cfg, profile)
+	},
+}
 
-		if err := config.Save(cfg, path); err != nil {
-			return err
+// saveConfig saves cfg as the top-level settings, or as the named profile
+// within existing, once the result is valid.
+func saveConfig(existing, cfg config.Config, profile string) error {
+	if profile != "" {
+		if existing.Profiles == nil {
+			existing.Profiles = map[string]config.Config{}
 		}
+		existing.Profiles[profile] = cfg
+		cfg = existing
+	}
 
-		fmt.Printf("Configuration saved to %s\n", path)
+	selected, err := cfg.Select(profile)
+	if err != nil {
+		return err
+	}
+	if err := selected.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	path := cfgFile
+	if path == "" {
+		path = config.DefaultPath()
+	}
+
+	if err := config.Save(cfg, path); err != nil {
+		return err
+	}
+
+	if profile != "" {
+		fmt.Printf("Profile %q saved to %s\n", profile, path)
 		return nil
-	},
+	}
+	fmt.Printf("Configuration saved to %s\n", path)
+	return nil
 }
 
+// readSecret prompts for a value without echoing it, keeping current when
+// nothing is typed.
+func readSecret(prompt, current string) (string, error) {
+	fmt.Printf("%s (input hidden): ", prompt)
+	data, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Println() // newline after hidden input
+	if err != nil {
+		return "", err
+	}
+	if value := strings.TrimSpace(string(data)); value != "" {
+		return value, nil
+	}
+	return current, nil
+}
+
+// setupOAuth prompts for the site and OAuth app, runs the authorization
+// flow in the browser and returns current signed in with OAuth.
+func setupOAuth(ctx context.Context, reader *bufio.Reader, current config.Config) (config.Config, error) {
+	var defaults config.OAuth
+	if current.OAuth != nil {
+		defaults = *current.OAuth
+	}
+
+	if current.URL != "" {
+		fmt.Printf("JIRA URL [%s]: ", current.URL)
+	} else {
+		fmt.Print("JIRA URL (e.g., https://your-org.atlassian.net): ")
+	}
+	site, _ := reader.ReadString('\n')
+	if site = strings.TrimSpace(site); site == "" {
+		site = current.URL
+	}
+
+	if defaults.ClientID != "" {
+		fmt.Printf("OAuth client ID [%s]: ", defaults.ClientID)
+	} else {
+		fmt.Print("OAuth client ID: ")
+	}
+	clientID, _ := reader.ReadString('\n')
+	if clientID = strings.TrimSpace(clientID); clientID == "" {
+		clientID = defaults.ClientID
+	}
+	if clientID == "" {
+		return config.Config{}, fmt.Errorf("an OAuth client ID is required")
+	}
+	clientSecret, err := readSecret("OAuth client secret", defaults.ClientSecret)
+	if err != nil {
+		return config.Config{}, fmt.Errorf("reading client secret: %w", err)
+	}
+
+	listener, err := oauth.Listen(oauthPort)
+	if err != nil {
+		return config.Config{}, err
+	}
+	defer listener.Close()
+
+	app := oauth.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  listener.RedirectURL,
+		Endpoint:     oauth.Atlassian,
+		HTTPClient:   &http.Client{Timeout: config.DefaultTimeout},
+	}
+	verifier, err := oauth.NewVerifier()
+	if err != nil {
+		return config.Config{}, err
+	}
+	state, err := oauth.NewState()
+	if err != nil {
+		return config.Config{}, err
+	}
+
+	fmt.Printf("\nOpen this URL in your browser and grant access:\n\n  %s\n\n", app.AuthCodeURL(state, verifier))
+	fmt.Printf("Waiting for the redirect to %s ...\n", listener.RedirectURL)
+
+	waitCtx, cancel := context.WithTimeout(ctx, oauthWait)
+	defer cancel()
+	code, err := listener.Wait(waitCtx, state)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return config.Config{}, fmt.Errorf("no redirect within %s; run 'a-cli config --oauth' again", oauthWait)
+	}
+	if err != nil {
+		return config.Config{}, err
+	}
+
+	token, err := app.Exchange(ctx, code, verifier)
+	if err != nil {
+		return config.Config{}, err
+	}
+	if token.RefreshToken == "" {
+		return config.Config{}, fmt.Errorf("no refresh token was issued; check that the app has the offline_access scope")
+	}
+	resources, err := app.Resources(ctx, token.AccessToken)
+	if err != nil {
+		return config.Config{}, err
+	}
+	resource, err := oauth.FindResource(resources, site)
+	if err != nil {
+		return config.Config{}, err
+	}
+	fmt.Printf("Signed in to %s (%s).\n", resource.Name, resource.URL)
+
+	cfg := current
+	if site == "" {
+		cfg.URL = resource.URL
+	} else {
+		cfg.URL = site
+	}
+	cfg.Flavor = ""
+	cfg.OAuth = &config.OAuth{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		CloudID:      resource.ID,
+		RefreshToken: token.RefreshToken,
+	}
+	return cfg, nil
+}
+
+// oauthWait is how long config --oauth waits for the browser redirect.
+const oauthWait = 5 * time.Minute
+
 func init() {
+	configCmd.Flags().BoolVar(&configOAuth, "oauth", false, "sign in with OAuth 2.0 in the browser instead of an API token")
+	configCmd.Flags().IntVar(&oauthPort, "oauth-port", 8765, "loopback port for the OAuth redirect (must match the app's callback URL)")
 	rootCmd.AddCommand(configCmd)
 }
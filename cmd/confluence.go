@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -56,9 +58,10 @@ in a hierarchical directory structure. Use --max-depth to limit crawl depth.`,
 			return fmt.Errorf("could not extract page ID from %q — expected a numeric ID or Confluence URL", args[0])
 		}
 
-		client := jira.NewClient(appConfig)
+		client := newClient()
+		ctx := cmd.Context()
 
-		page, err := client.GetConfluencePage(pageID)
+		page, err := client.GetConfluencePage(ctx, pageID)
 		if err != nil {
 			return fmt.Errorf("fetching page %s: %w", pageID, err)
 		}
@@ -66,7 +69,7 @@ in a hierarchical directory structure. Use --max-depth to limit crawl depth.`,
 		// Fetch space info for context
 		var space *jira.ConfluenceSpace
 		if page.SpaceID != "" {
-			s, err := client.GetConfluenceSpace(page.SpaceID)
+			s, err := client.GetConfluenceSpace(ctx, page.SpaceID)
 			if err == nil {
 				space = s
 			}
@@ -78,7 +81,7 @@ in a hierarchical directory structure. Use --max-depth to limit crawl depth.`,
 			if confluenceOutputDir == "" {
 				return fmt.Errorf("--recursive requires --output-dir")
 			}
-			return crawlConfluenceTree(client, page, space, confluenceOutputDir, confluenceMaxDepth)
+			return crawlConfluenceTree(ctx, client, page, space, confluenceOutputDir, confluenceMaxDepth)
 		}
 
 		// Check for existing custom properties to preserve on re-pull
@@ -93,18 +96,18 @@ in a hierarchical directory structure. Use --max-depth to limit crawl depth.`,
 
 		// Fetch comments
 		var footerComments, inlineComments []jira.ConfluenceComment
-		if fc, err := client.GetConfluenceFooterComments(pageID); err != nil {
+		if fc, err := client.GetConfluenceFooterComments(ctx, pageID); err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: could not fetch footer comments: %v\n", err)
 		} else {
 			footerComments = fc
 		}
-		if ic, err := client.GetConfluenceInlineComments(pageID); err != nil {
+		if ic, err := client.GetConfluenceInlineComments(ctx, pageID); err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: could not fetch inline comments: %v\n", err)
 		} else {
 			inlineComments = ic
 		}
 
-		md, err := markdown.MarshalConfluencePage(page, space, customProps, footerComments, inlineComments)
+		md, err := markdown.MarshalConfluencePage(page, space, pullOrigin(), customProps, footerComments, inlineComments)
 		if err != nil {
 			return fmt.Errorf("converting to markdown: %w", err)
 		}
@@ -117,7 +120,7 @@ in a hierarchical directory structure. Use --max-depth to limit crawl depth.`,
 			// Use sanitized title as filename
 			filename := sanitizeFilename(page.Title) + ".md"
 			outPath := filepath.Join(confluenceOutputDir, filename)
-			if err := os.WriteFile(outPath, []byte(md), 0644); err != nil {
+			if err := writeFileAtomic(outPath, []byte(md)); err != nil {
 				return fmt.Errorf("writing file: %w", err)
 			}
 			fmt.Fprintf(os.Stderr, "Written to %s\n", outPath)
@@ -193,6 +196,9 @@ Use --dry-run to preview the ADF output without applying.`,
 		if err != nil {
 			return fmt.Errorf("parsing markdown: %w", err)
 		}
+		if err := checkOrigin(confluencePushFile, doc.Site, doc.Profile, doc.URL); err != nil {
+			return err
+		}
 
 		// Convert body to ADF
 		adf, err := markdown.BodyToADF(doc.Body)
@@ -219,11 +225,12 @@ Use --dry-run to preview the ADF output without applying.`,
 			return nil
 		}
 
-		client := jira.NewClient(appConfig)
+		client := newClient()
+		ctx := cmd.Context()
 
 		// Check for unresolved inline comments before pushing
 		if !confluencePushForce {
-			inlineComments, err := client.GetConfluenceInlineComments(doc.PageID)
+			inlineComments, err := client.GetConfluenceInlineComments(ctx, doc.PageID)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Warning: could not check for inline comments: %v\n", err)
 			} else {
@@ -252,7 +259,7 @@ Use --dry-run to preview the ADF output without applying.`,
 		}
 
 		// Fetch current page to get latest version (in case it was updated since pull)
-		currentPage, err := client.GetConfluencePage(doc.PageID)
+		currentPage, err := client.GetConfluencePage(ctx, doc.PageID)
 		if err != nil {
 			return fmt.Errorf("fetching current page %s: %w", doc.PageID, err)
 		}
@@ -273,7 +280,10 @@ Use --dry-run to preview the ADF output without applying.`,
 			},
 		}
 
-		if err := client.UpdateConfluencePage(doc.PageID, payload); err != nil {
+		if err := client.UpdateConfluencePage(ctx, doc.PageID, payload); err != nil {
+			if errors.Is(err, jira.ErrConflict) {
+				return fmt.Errorf("conflict: page %s was updated in Confluence while pushing (version %d is already taken).\nRe-pull the page before pushing.", doc.PageID, newVersion)
+			}
 			return fmt.Errorf("pushing body to page %s: %w", doc.PageID, err)
 		}
 
@@ -309,10 +319,11 @@ Examples:
 			return err
 		}
 
-		client := jira.NewClient(appConfig)
+		client := newClient()
+		ctx := cmd.Context()
 
 		// Resolve space key → space ID
-		space, err := client.GetConfluenceSpaceByKey(confluenceCreateSpace)
+		space, err := client.GetConfluenceSpaceByKey(ctx, confluenceCreateSpace)
 		if err != nil {
 			return fmt.Errorf("looking up space %q: %w", confluenceCreateSpace, err)
 		}
@@ -361,7 +372,7 @@ Examples:
 			},
 		}
 
-		page, err := client.CreateConfluencePage(payload)
+		page, err := client.CreateConfluencePage(ctx, payload)
 		if err != nil {
 			return fmt.Errorf("creating page: %w", err)
 		}
@@ -382,14 +393,14 @@ Examples:
 				return fmt.Errorf("creating output directory: %w", err)
 			}
 
-			md, err := markdown.MarshalConfluencePage(page, space, nil, nil, nil)
+			md, err := markdown.MarshalConfluencePage(page, space, pullOrigin(), nil, nil, nil)
 			if err != nil {
 				return fmt.Errorf("converting created page to markdown: %w", err)
 			}
 
 			filename := sanitizeFilename(page.Title) + ".md"
 			outPath := filepath.Join(confluenceOutputDir, filename)
-			if err := os.WriteFile(outPath, []byte(md), 0644); err != nil {
+			if err := writeFileAtomic(outPath, []byte(md)); err != nil {
 				return fmt.Errorf("writing file: %w", err)
 			}
 			fmt.Fprintf(os.Stderr, "Written to %s\n", outPath)
@@ -432,7 +443,7 @@ type treeNode struct {
 	depth   int
 }
 
-func crawlConfluenceTree(client *jira.Client, rootPage *jira.ConfluencePage, space *jira.ConfluenceSpace, baseDir string, maxDepth int) error {
+func crawlConfluenceTree(ctx context.Context, client jira.Confluence, rootPage *jira.ConfluencePage, space *jira.ConfluenceSpace, baseDir string, maxDepth int) error {
 	if err := os.MkdirAll(baseDir, 0755); err != nil {
 		return fmt.Errorf("creating output directory: %w", err)
 	}
@@ -441,90 +452,113 @@ func crawlConfluenceTree(client *jira.Client, rootPage *jira.ConfluencePage, spa
 
 	// Start with the root page
 	rootDir := baseDir
-	var toFetch []treeNode
-	toFetch = append(toFetch, treeNode{
+	toFetch := []treeNode{{
 		pageID:  rootPage.ID,
 		title:   rootPage.Title,
 		dirPath: rootDir,
 		depth:   0,
-	})
-
-	// BFS to discover all descendant pages
-	queue := []treeNode{toFetch[0]}
-	for len(queue) > 0 {
-		current := queue[0]
-		queue = queue[1:]
-
-		if maxDepth > 0 && current.depth >= maxDepth {
-			continue
-		}
-
-		children, err := client.GetConfluenceChildPages(current.pageID)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: could not get children of %q: %v\n", current.title, err)
-			continue
-		}
-
-		for _, child := range children {
-			childDir := filepath.Join(current.dirPath, sanitizeFilename(child.Title))
-			node := treeNode{
-				pageID:  child.ID,
-				title:   child.Title,
-				dirPath: childDir,
-				depth:   current.depth + 1,
+	}}
+
+	// Discover descendants one level at a time, listing the children of every
+	// page in a level in parallel. Children are appended in the same order a
+	// sequential breadth-first walk would find them.
+	level := toFetch
+	for len(level) > 0 {
+		var next []treeNode
+		jira.Ordered(ctx, len(level), pullConcurrency, func(i int) ([]jira.ConfluenceChildPage, error) {
+			if maxDepth > 0 && level[i].depth >= maxDepth {
+				return nil, nil
+			}
+			return client.GetConfluenceChildPages(ctx, level[i].pageID)
+		}, func(i int, children []jira.ConfluenceChildPage, err error) {
+			current := level[i]
+			if isCancelled(err) {
+				return
 			}
-			toFetch = append(toFetch, node)
-			queue = append(queue, node)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not get children of %q: %v\n", current.title, err)
+				return
+			}
+			for _, child := range children {
+				next = append(next, treeNode{
+					pageID:  child.ID,
+					title:   child.Title,
+					dirPath: filepath.Join(current.dirPath, sanitizeFilename(child.Title)),
+					depth:   current.depth + 1,
+				})
+			}
+		})
+		if ctx.Err() != nil {
+			return reportInterrupted("nothing written; stopped while discovering pages (%d found)", len(toFetch)+len(next))
 		}
+		toFetch = append(toFetch, next...)
+		level = next
 	}
 
 	total := len(toFetch)
 	fmt.Fprintf(os.Stderr, "Found %d pages. Fetching content...\n\n", total)
 
 	fetched := 0
-	for i, node := range toFetch {
-		fmt.Fprintf(os.Stderr, "  [%d/%d] %s\n", i+1, total, node.title)
+	var failures []pullFailure
+	var writeErr error
+	jira.Ordered(ctx, total, pullConcurrency, func(i int) (pullResult, error) {
+		node := toFetch[i]
 
 		// For the root page, reuse the already-fetched data
-		var page *jira.ConfluencePage
-		if i == 0 {
-			page = rootPage
-		} else {
+		page := rootPage
+		if i > 0 {
 			var err error
-			page, err = client.GetConfluencePage(node.pageID)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "  Warning: could not fetch page %s (%s): %v\n", node.pageID, node.title, err)
-				continue
+			if page, err = client.GetConfluencePage(ctx, node.pageID); err != nil {
+				return pullResult{}, fmt.Errorf("fetching: %w", err)
 			}
 		}
 
-		if err := os.MkdirAll(node.dirPath, 0755); err != nil {
-			return fmt.Errorf("creating directory %s: %w", node.dirPath, err)
-		}
-
-		filename := sanitizeFilename(page.Title) + ".md"
-		outPath := filepath.Join(node.dirPath, filename)
-
 		// Check for existing file to preserve custom properties
 		var customProps map[string]interface{}
+		outPath := filepath.Join(node.dirPath, sanitizeFilename(page.Title)+".md")
 		if existing, err := os.ReadFile(outPath); err == nil {
 			customProps, _ = markdown.ExtractConfluenceCustomProperties(string(existing))
 		}
 
-		md, err := markdown.MarshalConfluencePage(page, space, customProps, nil, nil)
+		md, err := markdown.MarshalConfluencePage(page, space, pullOrigin(), customProps, nil, nil)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "  Warning: could not convert page %s: %v\n", node.pageID, err)
-			continue
+			return pullResult{}, fmt.Errorf("converting: %w", err)
+		}
+		return pullResult{title: page.Title, md: md}, nil
+	}, func(i int, r pullResult, err error) {
+		node := toFetch[i]
+		if isCancelled(err) {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "  [%d/%d] %s\n", i+1, total, node.title)
+		if err != nil {
+			failures = append(failures, pullFailure{Item: fmt.Sprintf("%s (%s)", node.title, node.pageID), Err: err})
+			return
+		}
+		if writeErr != nil {
+			return
 		}
 
-		if err := os.WriteFile(outPath, []byte(md), 0644); err != nil {
-			return fmt.Errorf("writing %s: %w", outPath, err)
+		if err := os.MkdirAll(node.dirPath, 0755); err != nil {
+			writeErr = fmt.Errorf("creating directory %s: %w", node.dirPath, err)
+			return
+		}
+		outPath := filepath.Join(node.dirPath, sanitizeFilename(r.title)+".md")
+		if err := writeFileAtomic(outPath, []byte(r.md)); err != nil {
+			writeErr = fmt.Errorf("writing %s: %w", outPath, err)
+			return
 		}
 		fetched++
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+	if ctx.Err() != nil {
+		return reportInterrupted("pulled %d/%d pages to %s", fetched, total, baseDir)
 	}
 
 	fmt.Fprintf(os.Stderr, "\nPulled %d/%d pages to %s\n", fetched, total, baseDir)
-	return nil
+	return reportPullFailures("page(s)", failures)
 }
 
 // confluenceCommentBodyText extracts plain text from a comment's storage-format body.
@@ -553,6 +587,7 @@ func init() {
 	confluenceGetCmd.Flags().StringVar(&confluenceOutputDir, "output-dir", "", "write output to <dir>/<Title>.md instead of stdout")
 	confluenceGetCmd.Flags().BoolVar(&confluenceRecursive, "recursive", false, "recursively crawl child pages (requires --output-dir)")
 	confluenceGetCmd.Flags().IntVar(&confluenceMaxDepth, "max-depth", 0, "maximum depth for recursive crawl (0 = unlimited)")
+	addConcurrencyFlag(confluenceGetCmd)
 	confluencePushCmd.Flags().StringVarP(&confluencePushFile, "file", "f", "", "markdown file to push (required)")
 	confluencePushCmd.Flags().BoolVar(&confluencePushDryRun, "dry-run", false, "preview ADF output without pushing")
 	confluencePushCmd.Flags().BoolVar(&confluencePushForce, "force", false, "push even if there are unresolved inline comments")
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -21,6 +23,7 @@ var (
 	confSearchCreated    string
 	confSearchContrib    string
 	confSearchMaxResults int
+	confSearchAll        bool
 )
 
 var confluenceSearchCmd = &cobra.Command{
@@ -40,6 +43,9 @@ Examples:
   a-cli confluence search --space ENG --updated recent
   a-cli confluence search --updated "last week"
 
+  # Every match, fetched page by page
+  a-cli confluence search --space ENG --all
+
   # Pull results to markdown files
   a-cli confluence search --space ENG --updated recent --output-dir ./pages
 
@@ -56,25 +62,70 @@ Smart date values for --updated/--created:
 			return err
 		}
 
-		client := jira.NewClient(appConfig)
+		client := newClient()
+		ctx := cmd.Context()
+
+		limit := confSearchMaxResults
+		if confSearchAll {
+			limit = 0
+		}
+
+		if confluenceOutputDir != "" {
+			if err := os.MkdirAll(confluenceOutputDir, 0755); err != nil {
+				return fmt.Errorf("creating output directory: %w", err)
+			}
+		}
+
+		// One table for the whole run, so the columns line up across pages
+		table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		found, pulled := 0, 0
+		var failures []pullFailure
+		err = client.SearchConfluencePages(ctx, cql, limit, func(page *jira.ConfluenceSearchResult) error {
+			if len(page.Results) == 0 {
+				return nil
+			}
+			if found == 0 && page.TotalSize > 0 {
+				fmt.Fprintf(os.Stderr, "Found %d pages  CQL: %s\n\n", page.TotalSize, cql)
+			}
+			first := found == 0
+			found += len(page.Results)
+
+			if confluenceOutputDir != "" {
+				n, failed, err := pullConfluenceSearchResults(ctx, client, page.Results)
+				pulled += n
+				failures = append(failures, failed...)
+				if err != nil {
+					return err
+				}
+			} else {
+				printConfluenceTable(table, page.Results, first)
+			}
 
-		result, err := client.SearchConfluence(cql, confSearchMaxResults, 0)
+			if page.Links.Next != "" && (limit <= 0 || found < limit) {
+				printSearchProgress("pages", found, page.TotalSize)
+			}
+			return nil
+		})
+		table.Flush()
+		if err != nil && ctx.Err() != nil {
+			if confluenceOutputDir != "" {
+				return reportInterrupted("pulled %d pages to %s", pulled, confluenceOutputDir)
+			}
+			return reportInterrupted("listed %d pages", found)
+		}
 		if err != nil {
 			return fmt.Errorf("search failed: %w", err)
 		}
 
-		if len(result.Results) == 0 {
+		if found == 0 {
 			fmt.Fprintf(os.Stderr, "No pages found.\nCQL: %s\n", cql)
 			return nil
 		}
 
-		fmt.Fprintf(os.Stderr, "Found %d pages (showing %d)  CQL: %s\n\n", result.TotalSize, len(result.Results), cql)
-
 		if confluenceOutputDir != "" {
-			return pullConfluenceSearchResults(client, result.Results)
+			fmt.Fprintf(os.Stderr, "\nPulled %d pages to %s\n", pulled, confluenceOutputDir)
+			return reportPullFailures("page(s)", failures)
 		}
-
-		printConfluenceTable(result.Results, client)
 		return nil
 	},
 }
@@ -121,10 +172,13 @@ func buildCQL(args []string) (string, error) {
 	return strings.Join(conditions, " AND "), nil
 }
 
-func printConfluenceTable(entries []jira.ConfluenceSearchEntry, client *jira.Client) {
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "ID\tSPACE\tLAST MODIFIED\tTITLE")
-	fmt.Fprintln(w, "--\t-----\t-------------\t-----")
+// printConfluenceTable writes a page of search results to w. The header is
+// written only for the first page.
+func printConfluenceTable(w io.Writer, entries []jira.ConfluenceSearchEntry, header bool) {
+	if header {
+		fmt.Fprintln(w, "ID\tSPACE\tLAST MODIFIED\tTITLE")
+		fmt.Fprintln(w, "--\t-----\t-------------\t-----")
+	}
 
 	for _, entry := range entries {
 		space := ""
@@ -141,9 +195,7 @@ func printConfluenceTable(entries []jira.ConfluenceSearchEntry, client *jira.Cli
 		if title == "" {
 			title = entry.Title
 		}
-		if len(title) > 60 {
-			title = title[:57] + "..."
-		}
+		title = truncate(title, 60)
 
 		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
 			entry.Content.ID,
@@ -152,51 +204,69 @@ func printConfluenceTable(entries []jira.ConfluenceSearchEntry, client *jira.Cli
 			title,
 		)
 	}
-	w.Flush()
 }
 
-func pullConfluenceSearchResults(client *jira.Client, entries []jira.ConfluenceSearchEntry) error {
-	if err := os.MkdirAll(confluenceOutputDir, 0755); err != nil {
-		return fmt.Errorf("creating output directory: %w", err)
+// pullConfluenceSearchResults fetches a page of search results in parallel
+// and writes them, in order, to markdown files in --output-dir. It returns how
+// many were written and the ones that couldn't be fetched or converted.
+func pullConfluenceSearchResults(ctx context.Context, client jira.Confluence, entries []jira.ConfluenceSearchEntry) (int, []pullFailure, error) {
+	var ids []string
+	for _, entry := range entries {
+		if entry.Content.ID != "" {
+			ids = append(ids, entry.Content.ID)
+		}
 	}
 
 	pulled := 0
-	for _, entry := range entries {
-		pageID := entry.Content.ID
-		if pageID == "" {
-			continue
+	var failures []pullFailure
+	var writeErr error
+	jira.Ordered(ctx, len(ids), pullConcurrency, func(i int) (pullResult, error) {
+		return fetchConfluenceSearchResult(ctx, client, ids[i])
+	}, func(i int, r pullResult, err error) {
+		if isCancelled(err) {
+			return
 		}
-
-		page, err := client.GetConfluencePage(pageID)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: could not fetch page %s: %v\n", pageID, err)
-			continue
+			failures = append(failures, pullFailure{Item: "page " + ids[i], Err: err})
+			return
 		}
-
-		var space *jira.ConfluenceSpace
-		if page.SpaceID != "" {
-			if s, err := client.GetConfluenceSpace(page.SpaceID); err == nil {
-				space = s
-			}
+		if writeErr != nil {
+			return
 		}
 
-		md, err := markdown.MarshalConfluencePage(page, space, nil, nil, nil)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: could not convert page %s: %v\n", pageID, err)
-			continue
-		}
-
-		filename := sanitizeFilename(page.Title) + ".md"
+		filename := sanitizeFilename(r.title) + ".md"
 		outPath := filepath.Join(confluenceOutputDir, filename)
-		if err := os.WriteFile(outPath, []byte(md), 0644); err != nil {
-			return fmt.Errorf("writing %s: %w", outPath, err)
+		if err := writeFileAtomic(outPath, []byte(r.md)); err != nil {
+			writeErr = fmt.Errorf("writing %s: %w", outPath, err)
+			return
 		}
-		fmt.Fprintf(os.Stderr, "  %s -> %s\n", page.Title, outPath)
+		fmt.Fprintf(os.Stderr, "  %s -> %s\n", r.title, outPath)
 		pulled++
+	})
+
+	return pulled, failures, writeErr
+}
+
+// fetchConfluenceSearchResult builds the markdown for one page. It runs on a
+// worker goroutine.
+func fetchConfluenceSearchResult(ctx context.Context, client jira.Confluence, pageID string) (pullResult, error) {
+	page, err := client.GetConfluencePage(ctx, pageID)
+	if err != nil {
+		return pullResult{}, fmt.Errorf("fetching: %w", err)
 	}
 
-	fmt.Fprintf(os.Stderr, "\nPulled %d pages to %s\n", pulled, confluenceOutputDir)
-	return nil
+	var space *jira.ConfluenceSpace
+	if page.SpaceID != "" {
+		if s, err := client.GetConfluenceSpace(ctx, page.SpaceID); err == nil {
+			space = s
+		}
+	}
+
+	md, err := markdown.MarshalConfluencePage(page, space, pullOrigin(), nil, nil, nil)
+	if err != nil {
+		return pullResult{}, fmt.Errorf("converting: %w", err)
+	}
+	return pullResult{title: page.Title, md: md}, nil
 }
 
 func init() {
@@ -207,6 +277,8 @@ func init() {
 	confluenceSearchCmd.Flags().StringVar(&confSearchCreated, "created", "", "filter by created date")
 	confluenceSearchCmd.Flags().StringVar(&confSearchContrib, "contributor", "", "filter by contributor")
 	confluenceSearchCmd.Flags().IntVar(&confSearchMaxResults, "max-results", 25, "maximum results to return")
+	confluenceSearchCmd.Flags().BoolVar(&confSearchAll, "all", false, "return every match, ignoring --max-results")
 	confluenceSearchCmd.Flags().StringVar(&confluenceOutputDir, "output-dir", "", "pull all results to markdown files in this directory")
+	addConcurrencyFlag(confluenceSearchCmd)
 	confluenceCmd.AddCommand(confluenceSearchCmd)
 }
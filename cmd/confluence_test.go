@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mreider/a-cli/internal/jira"
+)
+
+// newTreeServer returns a fake holding a Handbook page with Onboarding (and
+// its child Laptop setup) and Policies, which has enough children to need
+// more than one page of results.
+func newTreeServer(t *testing.T, policies int) (*jira.ConfluencePage, *jira.ConfluenceSpace, *jira.Client) {
+	discardStderr(t)
+	srv, rootID := newConfluenceServer(t)
+	onboarding := srv.AddPage(jira.ConfluencePage{Title: "Onboarding", SpaceID: "100"}, rootID, adfJSON(t, "Start here."))
+	srv.AddPage(jira.ConfluencePage{Title: "Laptop setup", SpaceID: "100"}, onboarding, adfJSON(t, "Install the tools."))
+	policiesID := srv.AddPage(jira.ConfluencePage{Title: "Policies", SpaceID: "100"}, rootID, adfJSON(t, "The rules."))
+	for i := 1; i <= policies; i++ {
+		srv.AddPage(jira.ConfluencePage{Title: fmt.Sprintf("Policy %03d", i), SpaceID: "100"}, policiesID, adfJSON(t, "A rule."))
+	}
+
+	client := testClient(srv)
+	root, err := client.GetConfluencePage(context.Background(), rootID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	space, err := client.GetConfluenceSpace(context.Background(), "100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root, space, client
+}
+
+func TestCrawlConfluenceTree(t *testing.T) {
+	root, space, client := newTreeServer(t, 60)
+	dir := t.TempDir()
+
+	if err := crawlConfluenceTree(context.Background(), client, root, space, dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{
+		"Handbook.md",
+		"Onboarding/Onboarding.md",
+		"Onboarding/Laptop setup/Laptop setup.md",
+		"Policies/Policies.md",
+		"Policies/Policy 001/Policy 001.md",
+		"Policies/Policy 060/Policy 060.md",
+	} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Errorf("expected %s: %v", path, err)
+		}
+	}
+	if n := countFiles(t, dir); n != 64 {
+		t.Errorf("expected 64 pages, got %d", n)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "Onboarding", "Laptop setup", "Laptop setup.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "Install the tools.") {
+		t.Errorf("page body missing:\n%s", data)
+	}
+}
+
+func TestCrawlConfluenceTree_MaxDepth(t *testing.T) {
+	root, space, client := newTreeServer(t, 3)
+	dir := t.TempDir()
+
+	if err := crawlConfluenceTree(context.Background(), client, root, space, dir, 1); err != nil {
+		t.Fatal(err)
+	}
+	if n := countFiles(t, dir); n != 3 {
+		t.Errorf("expected the root and its 2 children, got %d pages", n)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Onboarding", "Laptop setup")); !os.IsNotExist(err) {
+		t.Errorf("crawled past --max-depth: %v", err)
+	}
+}
+
+// countFiles counts the markdown files under dir.
+func countFiles(t *testing.T, dir string) int {
+	t.Helper()
+	n := 0
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && strings.HasSuffix(path, ".md") {
+			n++
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
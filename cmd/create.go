@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mreider/a-cli/internal/jira"
+	"github.com/mreider/a-cli/internal/markdown"
+	"github.com/spf13/cobra"
+)
+
+var (
+	createFile    string
+	createProject string
+	createDryRun  bool
+)
+
+var createCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a new JIRA issue from a markdown file",
+	Long: `Reads a markdown draft with YAML frontmatter and creates a new issue in the
+given project. The frontmatter supplies title, type, priority, labels, and
+assignee (an email address); the body becomes the description.
+
+On success the file is rewritten in place with the assigned key and freshly
+pulled frontmatter, so it can be edited and sent back with push or apply.
+If the issue is created but can't be pulled back, the key is still added to
+the frontmatter so that running create again doesn't make a duplicate.
+
+Example draft:
+
+  ---
+  title: Login fails on Safari
+  type: Bug
+  priority: High
+  labels: [frontend]
+  assignee: someone@example.com
+  ---
+
+  Steps to reproduce...
+
+If type is omitted, the issue is created as a Task.
+
+Use --dry-run to preview the create payload without sending it.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if createFile == "" {
+			return fmt.Errorf("--file (-f) is required")
+		}
+		if createProject == "" {
+			return fmt.Errorf("--project (-p) is required")
+		}
+
+		if err := loadConfig(); err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(createFile)
+		if err != nil {
+			return fmt.Errorf("reading file: %w", err)
+		}
+
+		ticket, err := markdown.UnmarshalDraft(string(content))
+		if err != nil {
+			return fmt.Errorf("parsing markdown: %w", err)
+		}
+		if ticket.Key != "" {
+			return fmt.Errorf("%s already has key %s; use 'a-cli apply' to update an existing issue", createFile, ticket.Key)
+		}
+
+		projectKey := strings.ToUpper(createProject)
+		payload, err := markdown.ToCreatePayload(ticket, projectKey)
+		if err != nil {
+			return fmt.Errorf("building create payload: %w", err)
+		}
+
+		client := newClient()
+		ctx := cmd.Context()
+
+		// Resolve the field map up front so a bad config fails before the
+		// issue exists
+		fieldMap, err := resolveFieldMap(ctx, client)
+		if err != nil {
+			return err
+		}
+		fieldIDs, extraFields := fieldMapIDs(fieldMap)
+
+		if ticket.Assignee != "" {
+			assignee, err := resolveUser(ctx, client, ticket.Assignee)
+			if err != nil {
+				return fmt.Errorf("resolving assignee: %w", err)
+			}
+			payload.Fields.Assignee = &assignee
+		}
+
+		if createDryRun {
+			fmt.Fprintf(os.Stderr, "Dry run: would create issue in %s\n\n", projectKey)
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(payload); err != nil {
+				return fmt.Errorf("encoding payload: %w", err)
+			}
+			return nil
+		}
+
+		created, err := client.CreateIssue(ctx, *payload)
+		if err != nil {
+			return fmt.Errorf("creating issue in %s: %w", projectKey, err)
+		}
+		fmt.Fprintf(os.Stderr, "Created %s: %s/browse/%s\n", created.Key, strings.TrimRight(appConfig.URL, "/"), created.Key)
+
+		// Re-pull so the file gets the same frontmatter as 'a-cli get' would produce
+		issue, err := client.GetIssue(ctx, created.Key, extraFields...)
+		if err != nil {
+			// Record the key anyway so running create again doesn't make a
+			// duplicate issue
+			keyed, werr := markdown.SetDraftKey(string(content), created.Key)
+			if werr == nil {
+				werr = writeFileAtomic(createFile, []byte(keyed))
+			}
+			if werr != nil {
+				return fmt.Errorf("fetching created issue %s: %w (recording its key in %s also failed: %v)", created.Key, err, createFile, werr)
+			}
+			fmt.Fprintf(os.Stderr, "Updated %s with key %s\n", createFile, created.Key)
+			return fmt.Errorf("fetching created issue %s: %w", created.Key, err)
+		}
+
+		customProps, _ := markdown.ExtractCustomProperties(string(content), fieldMapKeys(fieldMap)...)
+
+		md, err := markdown.Marshal(issue, appConfig.URL, customProps, markdown.MarshalOptions{CustomFields: fieldIDs, Profile: appConfig.Profile})
+		if err != nil {
+			return fmt.Errorf("converting to markdown: %w", err)
+		}
+
+		if err := writeFileAtomic(createFile, []byte(md)); err != nil {
+			return fmt.Errorf("writing file: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Updated %s with key %s\n", createFile, created.Key)
+
+		return nil
+	},
+}
+
+// resolveUser looks up the user with an email address and returns the
+// reference payloads identify them by. The search is a prefix match on name
+// and email, so it fails unless exactly one result has that email address.
+func resolveUser(ctx context.Context, client jira.JIRA, email string) (jira.AccountRef, error) {
+	users, err := client.FindUsers(ctx, email)
+	if err != nil {
+		return jira.AccountRef{}, fmt.Errorf("looking up user %q: %w", email, err)
+	}
+
+	var exact []jira.User
+	for _, u := range users {
+		if strings.EqualFold(u.EmailAddress, email) {
+			exact = append(exact, u)
+		}
+	}
+	switch len(exact) {
+	case 1:
+		return exact[0].Ref(), nil
+	case 0:
+		return jira.AccountRef{}, fmt.Errorf("no JIRA user with email %q", email)
+	}
+
+	var names []string
+	for _, u := range exact {
+		names = append(names, u.DisplayName)
+	}
+	return jira.AccountRef{}, fmt.Errorf("%q matches %d JIRA users (%s)", email, len(exact), strings.Join(names, ", "))
+}
+
+func init() {
+	createCmd.Flags().StringVarP(&createFile, "file", "f", "", "markdown draft to create the issue from (required)")
+	createCmd.Flags().StringVarP(&createProject, "project", "p", "", "project key to create the issue in (required)")
+	createCmd.Flags().BoolVar(&createDryRun, "dry-run", false, "preview the create payload without creating the issue")
+	rootCmd.AddCommand(createCmd)
+}
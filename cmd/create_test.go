@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mreider/a-cli/internal/jira"
+	"github.com/mreider/a-cli/internal/jira/jiratest"
+	"github.com/mreider/a-cli/internal/markdown"
+)
+
+const createDraft = "---\ntitle: Login fails on Safari\ntype: Bug\n---\n\nSteps to reproduce.\n"
+
+// writeDraft writes createDraft to a temporary file and returns its path.
+func writeDraft(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "draft.md")
+	if err := os.WriteFile(path, []byte(createDraft), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readDraft parses the file at path.
+func readDraft(t *testing.T, path string) *markdown.Ticket {
+	t.Helper()
+	ticket, err := markdown.UnmarshalDraft(readFile(t, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ticket
+}
+
+func TestCreate_RewritesDraftWithKey(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	path := writeDraft(t)
+
+	if _, stderr, err := runCommand(t, srv, "create", "-f", path, "-p", "proj"); err != nil {
+		t.Fatalf("create: %v\n%s", err, stderr)
+	}
+	if issue := srv.Issue("PROJ-1"); issue == nil || issue.Fields.Summary != "Login fails on Safari" {
+		t.Fatalf("issue not created: %+v", issue)
+	}
+	if ticket := readDraft(t, path); ticket.Key != "PROJ-1" || ticket.Body != "Steps to reproduce." {
+		t.Errorf("draft not rewritten with the key: %+v", ticket)
+	}
+}
+
+func TestCreate_BadFieldMapCreatesNothing(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	path := writeDraft(t)
+	cfg := fmt.Sprintf("url: %s\nemail: me@example.com\ntoken: test-token\nfields:\n  team: No Such Field\n", srv.URL)
+
+	_, _, err := runWithConfig(t, cfg, "create", "-f", path, "-p", "PROJ")
+	if err == nil || !strings.Contains(err.Error(), "No Such Field") {
+		t.Fatalf("expected a field map error, got %v", err)
+	}
+	if srv.Issue("PROJ-1") != nil {
+		t.Errorf("expected no issue to be created")
+	}
+}
+
+func TestCreate_RecordsKeyWhenPullFails(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	srv.FailNext("GET", "/rest/api/3/issue/PROJ-1", http.StatusNotFound)
+	path := writeDraft(t)
+
+	_, stderr, err := runCommand(t, srv, "create", "-f", path, "-p", "PROJ")
+	if err == nil || !strings.Contains(err.Error(), "fetching created issue PROJ-1") {
+		t.Fatalf("expected the pull to fail, got %v\n%s", err, stderr)
+	}
+	if ticket := readDraft(t, path); ticket.Key != "PROJ-1" || ticket.Title != "Login fails on Safari" || ticket.Type != "Bug" {
+		t.Errorf("expected the key to be recorded in the draft: %+v", ticket)
+	}
+
+	// Running create again refuses instead of making a duplicate
+	_, _, err = runCommand(t, srv, "create", "-f", path, "-p", "PROJ")
+	if err == nil || !strings.Contains(err.Error(), "already has key PROJ-1") {
+		t.Errorf("expected create to refuse the file, got %v", err)
+	}
+	if srv.Issue("PROJ-2") != nil {
+		t.Errorf("expected no duplicate issue")
+	}
+}
+
+func TestCreate_AssigneeMustMatchEmailExactly(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	srv.AddUser(jira.User{AccountID: "anabel", DisplayName: "Anabel", EmailAddress: "anabel@example.com"})
+	path := filepath.Join(t.TempDir(), "draft.md")
+	draft := strings.Replace(createDraft, "type: Bug\n", "type: Bug\nassignee: ana@example.com\n", 1)
+	if err := os.WriteFile(path, []byte(draft), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The search finds only anabel@example.com by prefix
+	_, _, err := runCommand(t, srv, "create", "-f", path, "-p", "PROJ")
+	if err == nil || !strings.Contains(err.Error(), `no JIRA user with email "ana@example.com"`) {
+		t.Fatalf("expected no exact match, got %v", err)
+	}
+	if srv.Issue("PROJ-1") != nil {
+		t.Fatalf("expected no issue to be created")
+	}
+
+	srv.AddUser(jira.User{AccountID: "ana", DisplayName: "Ana", EmailAddress: "Ana@Example.com"})
+	if _, stderr, err := runCommand(t, srv, "create", "-f", path, "-p", "PROJ"); err != nil {
+		t.Fatalf("create: %v\n%s", err, stderr)
+	}
+	if issue := srv.Issue("PROJ-1"); issue == nil || issue.Fields.Assignee == nil || issue.Fields.Assignee.AccountID != "ana" {
+		t.Errorf("expected the issue to be assigned to ana, got %+v", issue)
+	}
+}
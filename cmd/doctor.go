@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mreider/a-cli/internal/jira"
+	"github.com/spf13/cobra"
+)
+
+var (
+	doctorProjects     []string
+	doctorSpaces       []string
+	doctorNoConfluence bool
+)
+
+const doctorLong = `Checks that the configured site answers and the credentials work: signs in
+to JIRA and Confluence, and reports the authenticated user, the site flavor
+and how a-cli authenticates. With --project and --space, also checks that
+you can read and write issues in each project and pages in each space.
+
+Exits with status 1 if any check fails, so it can be used as a CI health
+check.
+
+Examples:
+  a-cli doctor
+  a-cli doctor --project PROJ --space ENG
+  a-cli config test --profile sandbox`
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the connection, credentials and permissions",
+	Long:  doctorLong,
+	Args:  cobra.NoArgs,
+	RunE:  runDoctor,
+}
+
+var configTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Check the saved settings against the site (same as doctor)",
+	Long:  doctorLong,
+	Args:  cobra.NoArgs,
+	RunE:  runDoctor,
+}
+
+// projectReadPermissions and projectWritePermissions are the JIRA project
+// permissions a-cli needs to pull issues and to push, create and comment.
+var (
+	projectReadPermissions  = []string{"BROWSE_PROJECTS"}
+	projectWritePermissions = []string{"CREATE_ISSUES", "EDIT_ISSUES", "ADD_COMMENTS"}
+)
+
+// doctorCheck is one line of the doctor report.
+type doctorCheck struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	if err := loadConfig(); err != nil {
+		return err
+	}
+
+	client := newClient()
+	checks := doctorChecks(cmd.Context(), client, client, doctorProjects, doctorSpaces, !doctorNoConfluence)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tRESULT\tDETAIL")
+	fmt.Fprintln(w, "-----\t------\t------")
+	failed := 0
+	for _, c := range checks {
+		result := "ok"
+		if !c.OK {
+			result = "FAIL"
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", c.Name, result, c.Detail)
+	}
+	w.Flush()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// doctorChecks signs in to JIRA and, when confluence is set, Confluence, and
+// checks the user's permissions in each project and space.
+func doctorChecks(ctx context.Context, client jira.JIRA, wiki jira.Confluence, projects, spaces []string, confluence bool) []doctorCheck {
+	checks := []doctorCheck{{Name: "site", OK: true, Detail: siteDescription()}}
+
+	if me, err := client.Myself(ctx); err != nil {
+		checks = append(checks, doctorCheck{Name: "jira", Detail: checkError(err)})
+	} else {
+		checks = append(checks, doctorCheck{Name: "jira", OK: true, Detail: "signed in as " + userDescription(me)})
+	}
+
+	if confluence {
+		if _, err := wiki.ListConfluenceSpaces(ctx, 1); err != nil {
+			checks = append(checks, doctorCheck{Name: "confluence", Detail: checkError(err)})
+		} else {
+			checks = append(checks, doctorCheck{Name: "confluence", OK: true, Detail: "spaces can be listed"})
+		}
+	}
+
+	for _, key := range projects {
+		checks = append(checks, projectCheck(ctx, client, strings.ToUpper(key)))
+	}
+	for _, key := range spaces {
+		checks = append(checks, spaceCheck(ctx, wiki, key))
+	}
+	return checks
+}
+
+func projectCheck(ctx context.Context, client jira.JIRA, key string) doctorCheck {
+	check := doctorCheck{Name: "project " + key}
+	permissions := append(append([]string{}, projectReadPermissions...), projectWritePermissions...)
+	have, err := client.MyPermissions(ctx, key, permissions...)
+	if err != nil {
+		check.Detail = checkError(err)
+		return check
+	}
+
+	var missing []string
+	for _, p := range permissions {
+		if !have[p] {
+			missing = append(missing, p)
+		}
+	}
+	check.OK = len(missing) == 0
+	check.Detail = accessDescription(!containsAny(missing, projectReadPermissions), !containsAny(missing, projectWritePermissions))
+	if !check.OK {
+		check.Detail += " (missing " + strings.Join(missing, ", ") + ")"
+	}
+	return check
+}
+
+func spaceCheck(ctx context.Context, wiki jira.Confluence, key string) doctorCheck {
+	check := doctorCheck{Name: "space " + key}
+	ops, err := wiki.GetConfluenceSpaceOperations(ctx, key)
+	if err != nil {
+		check.Detail = checkError(err)
+		return check
+	}
+
+	can := map[string]bool{}
+	for _, op := range ops {
+		can[op.Operation+" "+op.TargetType] = true
+	}
+	read := can["read space"]
+	write := can["create page"] && can["update page"]
+	check.OK = read && write
+	check.Detail = accessDescription(read, write)
+	if read && !write {
+		check.Detail += " (can't create or edit pages)"
+	}
+	return check
+}
+
+// siteDescription names the site, its flavor and how a-cli signs in to it.
+func siteDescription() string {
+	flavor, auth := "Cloud", "API token"
+	switch {
+	case appConfig.DataCenter():
+		flavor, auth = "Data Center", "personal access token"
+	case appConfig.OAuth != nil:
+		auth = "OAuth 2.0"
+	}
+	return fmt.Sprintf("%s (%s, %s)", appConfig.URL, flavor, auth)
+}
+
+func userDescription(u *jira.User) string {
+	name := u.DisplayName
+	if name == "" {
+		name = u.Name
+	}
+	switch {
+	case u.EmailAddress != "":
+		return fmt.Sprintf("%s <%s>", name, u.EmailAddress)
+	case u.Name != "" && u.Name != name:
+		return fmt.Sprintf("%s (%s)", name, u.Name)
+	}
+	return name
+}
+
+func accessDescription(read, write bool) string {
+	switch {
+	case read && write:
+		return "read, write"
+	case read:
+		return "read only"
+	case write:
+		return "write without read"
+	}
+	return "no access"
+}
+
+// checkError describes a failed request on one line, spelling out what
+// rejected credentials mean.
+func checkError(err error) string {
+	if errors.Is(err, jira.ErrUnauthorized) {
+		return "credentials rejected or lacking access; check the token (a-cli config)"
+	}
+	msg, _, _ := strings.Cut(err.Error(), "\n")
+	return msg
+}
+
+func containsAny(list, values []string) bool {
+	for _, v := range values {
+		for _, item := range list {
+			if item == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{doctorCmd, configTestCmd} {
+		cmd.Flags().StringSliceVar(&doctorProjects, "project", nil, "check read and write permissions in this JIRA project (repeatable)")
+		cmd.Flags().StringSliceVar(&doctorSpaces, "space", nil, "check read and write permissions in this Confluence space (repeatable)")
+		cmd.Flags().BoolVar(&doctorNoConfluence, "no-confluence", false, "skip the Confluence checks, for sites without Confluence")
+	}
+	configCmd.AddCommand(configTestCmd)
+	rootCmd.AddCommand(doctorCmd)
+}
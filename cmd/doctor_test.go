@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mreider/a-cli/internal/jira"
+	"github.com/mreider/a-cli/internal/jira/jiratest"
+)
+
+func newDoctorServer(t *testing.T) *jiratest.Server {
+	srv := newApplyServer(t)
+	srv.AddSpace(jira.ConfluenceSpace{ID: "100", Key: "ENG", Name: "Engineering"})
+	srv.AddSpace(jira.ConfluenceSpace{ID: "200", Key: "DOCS", Name: "Docs"})
+	return srv
+}
+
+// reportLine returns the doctor report line for check, with its columns
+// separated by single spaces.
+func reportLine(stdout, check string) string {
+	for _, line := range strings.Split(stdout, "\n") {
+		if strings.HasPrefix(line, check+" ") {
+			return regexp.MustCompile(`\s{2,}`).ReplaceAllString(line, " ")
+		}
+	}
+	return ""
+}
+
+func TestDoctor_AllChecksPass(t *testing.T) {
+	srv := newDoctorServer(t)
+
+	stdout, stderr, err := runCommand(t, srv, "doctor", "--project", "proj", "--space", "ENG")
+	if err != nil {
+		t.Fatalf("doctor: %v\n%s", err, stderr)
+	}
+	for check, want := range map[string]string{
+		"site":         "site ok " + srv.URL + " (Cloud, API token)",
+		"jira":         "jira ok signed in as Test User <me@example.com>",
+		"confluence":   "confluence ok spaces can be listed",
+		"project PROJ": "project PROJ ok read, write",
+		"space ENG":    "space ENG ok read, write",
+	} {
+		if got := reportLine(stdout, check); got != want {
+			t.Errorf("expected %q, got %q in:\n%s", want, got, stdout)
+		}
+	}
+}
+
+func TestDoctor_ReportsMissingPermissions(t *testing.T) {
+	srv := newDoctorServer(t)
+	srv.DenyPermissions("PROJ", "EDIT_ISSUES")
+	srv.SetSpaceReadOnly("DOCS")
+
+	stdout, _, err := runCommand(t, srv, "config", "test", "--project", "PROJ", "--project", "NOPE", "--space", "DOCS", "--space", "MISSING")
+	if err == nil || err.Error() != "4 of 7 checks failed" {
+		t.Fatalf("expected 4 failed checks, got %v\n%s", err, stdout)
+	}
+	for check, want := range map[string]string{
+		"project PROJ":  "project PROJ FAIL read only (missing EDIT_ISSUES)",
+		"project NOPE":  "project NOPE FAIL JIRA API 404: No project could be found with key 'NOPE'.",
+		"space DOCS":    "space DOCS FAIL read only (can't create or edit pages)",
+		"space MISSING": `space MISSING FAIL no space found with key "MISSING"`,
+	} {
+		if got := reportLine(stdout, check); got != want {
+			t.Errorf("expected %q, got %q in:\n%s", want, got, stdout)
+		}
+	}
+}
+
+func TestDoctor_RejectedCredentials(t *testing.T) {
+	srv := newDoctorServer(t)
+	srv.FailNext("GET", "/rest/api/3/myself", http.StatusUnauthorized)
+
+	stdout, _, err := runCommand(t, srv, "doctor", "--no-confluence")
+	if err == nil || err.Error() != "1 of 2 checks failed" {
+		t.Fatalf("expected the sign-in to fail, got %v\n%s", err, stdout)
+	}
+	if got := reportLine(stdout, "jira"); !strings.HasPrefix(got, "jira FAIL credentials rejected") {
+		t.Errorf("unexpected jira check %q", got)
+	}
+	if strings.Contains(stdout, "confluence") {
+		t.Errorf("expected Confluence to be skipped:\n%s", stdout)
+	}
+}
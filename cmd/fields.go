@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mreider/a-cli/internal/jira"
+	"github.com/mreider/a-cli/internal/markdown"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fieldsCustomOnly bool
+	fieldsFilter     string
+)
+
+var fieldsCmd = &cobra.Command{
+	Use:   "fields",
+	Short: "List JIRA fields for use in the config field map",
+	Long: `Lists the fields available on your JIRA site with their ids and value types.
+
+Custom fields can be pulled into frontmatter and pushed back with 'apply' by
+mapping friendly names to them in ~/.a-cli.yaml. Either the field id or the
+field name may be used; names are looked up on each run:
+
+  fields:
+    story_points: customfield_10016
+    team: Team
+    severity: Severity
+
+Friendly names are case-insensitive and written in lowercase.
+
+Examples:
+  a-cli fields --custom
+  a-cli fields -q points`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+
+		client := newClient()
+		ctx := cmd.Context()
+		fields, err := client.GetFields(ctx)
+		if err != nil {
+			return fmt.Errorf("fetching fields: %w", err)
+		}
+
+		sort.Slice(fields, func(i, j int) bool {
+			return strings.ToLower(fields[i].Name) < strings.ToLower(fields[j].Name)
+		})
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTYPE\tNAME")
+		fmt.Fprintln(w, "--\t----\t----")
+		for _, f := range fields {
+			if fieldsCustomOnly && !f.Custom {
+				continue
+			}
+			if fieldsFilter != "" && !strings.Contains(strings.ToLower(f.Name), strings.ToLower(fieldsFilter)) {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", f.ID, fieldTypeName(f.Schema), f.Name)
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+// fieldTypeName formats a field schema as e.g. "option" or "array<user>".
+func fieldTypeName(s jira.FieldSchema) string {
+	if s.Type == "array" && s.Items != "" {
+		return "array<" + s.Items + ">"
+	}
+	return s.Type
+}
+
+// resolveFieldMap resolves the config field map to JIRA field definitions,
+// keyed by friendly name. Values may be field ids or field names.
+// Returns nil without calling the API when no fields are configured.
+func resolveFieldMap(ctx context.Context, client jira.JIRA) (map[string]jira.FieldInfo, error) {
+	if len(appConfig.Fields) == 0 {
+		return nil, nil
+	}
+
+	all, err := client.GetFields(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching field definitions: %w", err)
+	}
+
+	byID := make(map[string]jira.FieldInfo, len(all))
+	byName := make(map[string][]jira.FieldInfo, len(all))
+	for _, f := range all {
+		byID[f.ID] = f
+		name := strings.ToLower(f.Name)
+		byName[name] = append(byName[name], f)
+	}
+
+	resolved := make(map[string]jira.FieldInfo, len(appConfig.Fields))
+	for friendly, ref := range appConfig.Fields {
+		if f, ok := byID[ref]; ok {
+			resolved[friendly] = f
+			continue
+		}
+		matches := byName[strings.ToLower(ref)]
+		switch len(matches) {
+		case 1:
+			resolved[friendly] = matches[0]
+		case 0:
+			return nil, fmt.Errorf("config field %q: no JIRA field with id or name %q (run 'a-cli fields' to list them)", friendly, ref)
+		default:
+			var ids []string
+			for _, m := range matches {
+				ids = append(ids, m.ID)
+			}
+			return nil, fmt.Errorf("config field %q: name %q is ambiguous (%s); use the field id", friendly, ref, strings.Join(ids, ", "))
+		}
+	}
+
+	return resolved, nil
+}
+
+// fieldMapIDs returns the friendly name -> field id map for markdown.Marshal
+// and the ids to request from GetIssue.
+func fieldMapIDs(fields map[string]jira.FieldInfo) (map[string]string, []string) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	byName := make(map[string]string, len(fields))
+	ids := make([]string, 0, len(fields))
+	for name, f := range fields {
+		byName[name] = f.ID
+		ids = append(ids, f.ID)
+	}
+	sort.Strings(ids)
+	return byName, ids
+}
+
+// fieldMapKeys returns the friendly names of the mapped custom fields.
+func fieldMapKeys(fields map[string]jira.FieldInfo) []string {
+	keys := make([]string, 0, len(fields))
+	for name := range fields {
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// customFieldUpdate converts a frontmatter value into the shape the JIRA API
+// expects for the field. A nil value clears the field.
+func customFieldUpdate(ctx context.Context, client jira.JIRA, f jira.FieldInfo, value interface{}) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch f.Schema.Type {
+	case "number":
+		return toNumber(value)
+	case "string":
+		s := fieldValueString(value)
+		if strings.HasSuffix(f.Schema.Custom, ":textarea") {
+			// Multi-line text fields take ADF in API v3
+			return markdown.BodyToADF(s)
+		}
+		return s, nil
+	case "date", "datetime":
+		return fieldValueString(value), nil
+	case "option":
+		return map[string]string{"value": fieldValueString(value)}, nil
+	case "user":
+		return resolveUser(ctx, client, fieldValueString(value))
+	case "array":
+		items := toList(value)
+		switch f.Schema.Items {
+		case "string":
+			out := make([]string, 0, len(items))
+			for _, v := range items {
+				out = append(out, fieldValueString(v))
+			}
+			return out, nil
+		case "option":
+			out := make([]map[string]string, 0, len(items))
+			for _, v := range items {
+				out = append(out, map[string]string{"value": fieldValueString(v)})
+			}
+			return out, nil
+		case "user":
+			out := make([]jira.AccountRef, 0, len(items))
+			for _, v := range items {
+				user, err := resolveUser(ctx, client, fieldValueString(v))
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, user)
+			}
+			return out, nil
+		}
+	}
+
+	return nil, fmt.Errorf("field %q has type %s, which a-cli can't update", f.Name, fieldTypeName(f.Schema))
+}
+
+// fieldValuesEqual compares a frontmatter value with a simplified JIRA value.
+// Lists compare as sets; numbers compare numerically regardless of YAML type.
+func fieldValuesEqual(a, b interface{}) bool {
+	return normalizeFieldValue(a) == normalizeFieldValue(b)
+}
+
+func normalizeFieldValue(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if list, ok := v.([]interface{}); ok {
+		parts := make([]string, 0, len(list))
+		for _, e := range list {
+			parts = append(parts, fieldValueString(e))
+		}
+		sort.Strings(parts)
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	return fieldValueString(v)
+}
+
+// fieldValueString formats a scalar frontmatter value as a string.
+func fieldValueString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case time.Time:
+		// YAML decodes unquoted dates as timestamps
+		if val.Hour() == 0 && val.Minute() == 0 && val.Second() == 0 {
+			return val.Format("2006-01-02")
+		}
+		return val.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+func toNumber(v interface{}) (float64, error) {
+	switch val := v.(type) {
+	case int:
+		return float64(val), nil
+	case float64:
+		return val, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", val)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("%v is not a number", v)
+}
+
+func toList(v interface{}) []interface{} {
+	if list, ok := v.([]interface{}); ok {
+		return list
+	}
+	return []interface{}{v}
+}
+
+func init() {
+	fieldsCmd.Flags().BoolVar(&fieldsCustomOnly, "custom", false, "only list custom fields")
+	fieldsCmd.Flags().StringVarP(&fieldsFilter, "query", "q", "", "only list fields whose name contains this text")
+	rootCmd.AddCommand(fieldsCmd)
+}
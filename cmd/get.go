@@ -6,30 +6,49 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/mreider/a-cli/internal/jira"
 	"github.com/mreider/a-cli/internal/markdown"
 	"github.com/spf13/cobra"
 )
 
 var (
-	outputDir    string
-	outputFormat string
+	outputDir      string
+	outputFormat   string
+	getAttachments bool
+	getWorklog     bool
+	getHistory     bool
 )
 
 var getCmd = &cobra.Command{
 	Use:   "get <issue-key>",
 	Short: "Fetch a JIRA issue and output as markdown",
-	Long:  `Fetches a JIRA issue by key and converts it to markdown with YAML frontmatter. Writes to stdout by default, or to a file with --output-dir.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Fetches a JIRA issue by key and converts it to markdown with YAML frontmatter. Writes to stdout by default, or to a file with --output-dir.
+
+With --attachments (requires --output-dir), the issue's attachments are
+downloaded into <dir>/<KEY>.assets/ and listed in an ## Attachments section.
+
+With --worklog, a ## Worklog section totals the time logged per author.
+With --history, a ## History section lists every field change.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if err := loadConfig(); err != nil {
 			return err
 		}
 
+		if getAttachments && outputDir == "" {
+			return fmt.Errorf("--attachments requires --output-dir")
+		}
+
 		issueKey := strings.ToUpper(args[0])
 
-		client := jira.NewClient(appConfig)
-		issue, err := client.GetIssue(issueKey)
+		client := newClient()
+		ctx := cmd.Context()
+		fieldMap, err := resolveFieldMap(ctx, client)
+		if err != nil {
+			return err
+		}
+		fieldIDs, extraFields := fieldMapIDs(fieldMap)
+
+		issue, err := client.GetIssue(ctx, issueKey, extraFields...)
 		if err != nil {
 			return fmt.Errorf("fetching issue %s: %w", issueKey, err)
 		}
@@ -39,11 +58,35 @@ var getCmd = &cobra.Command{
 		if outputDir != "" {
 			existingPath := filepath.Join(outputDir, issueKey+".md")
 			if existing, err := os.ReadFile(existingPath); err == nil {
-				customProps, _ = markdown.ExtractCustomProperties(string(existing))
+				customProps, _ = markdown.ExtractCustomProperties(string(existing), fieldMapKeys(fieldMap)...)
+			}
+		}
+
+		opts := markdown.MarshalOptions{CustomFields: fieldIDs, Profile: appConfig.Profile}
+		if getAttachments {
+			n, err := downloadAttachments(ctx, client, issue, outputDir)
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				fmt.Fprintf(os.Stderr, "Downloaded %d attachment(s) to %s\n", n, filepath.Join(outputDir, assetsDirName(issueKey)))
+			}
+			opts.AssetsDir = assetsDirName(issueKey)
+		}
+
+		if getWorklog {
+			if opts.Worklogs, err = fetchWorklogs(ctx, client, issueKey); err != nil {
+				return err
+			}
+		}
+
+		if getHistory {
+			if opts.History, err = fetchHistory(ctx, client, issueKey); err != nil {
+				return err
 			}
 		}
 
-		md, err := markdown.Marshal(issue, appConfig.URL, customProps)
+		md, err := markdown.Marshal(issue, appConfig.URL, customProps, opts)
 		if err != nil {
 			return fmt.Errorf("converting to markdown: %w", err)
 		}
@@ -55,7 +98,7 @@ var getCmd = &cobra.Command{
 			}
 
 			filename := filepath.Join(outputDir, issueKey+".md")
-			if err := os.WriteFile(filename, []byte(md), 0644); err != nil {
+			if err := writeFileAtomic(filename, []byte(md)); err != nil {
 				return fmt.Errorf("writing file: %w", err)
 			}
 			fmt.Fprintf(os.Stderr, "Written to %s\n", filename)
@@ -69,6 +112,9 @@ var getCmd = &cobra.Command{
 
 func init() {
 	getCmd.Flags().StringVar(&outputDir, "output-dir", "", "write output to <dir>/<KEY>.md instead of stdout")
+	getCmd.Flags().BoolVar(&getAttachments, "attachments", false, "download attachments into <dir>/<KEY>.assets/ (requires --output-dir)")
+	getCmd.Flags().BoolVar(&getWorklog, "worklog", false, "add a ## Worklog section with time logged per author")
+	getCmd.Flags().BoolVar(&getHistory, "history", false, "add a ## History section with the issue's field changes")
 	getCmd.Flags().StringVarP(&outputFormat, "output", "o", "md", "output format (currently only 'md' supported)")
 	rootCmd.AddCommand(getCmd)
 }
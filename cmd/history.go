@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mreider/a-cli/internal/jira"
+	"github.com/mreider/a-cli/internal/markdown"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyFields []string
+	historyOutput string
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history <issue-key>",
+	Short: "Show the change history of a JIRA issue",
+	Long: `Lists every field change on a JIRA issue, oldest first: the field, old and
+new values, who made the change and when.
+
+Examples:
+  a-cli history PRODUCT-123
+  a-cli history PRODUCT-123 --field status
+  a-cli history PRODUCT-123 --field status --field assignee -o json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if historyOutput != "table" && historyOutput != "json" {
+			return fmt.Errorf("unsupported output format %q (use table or json)", historyOutput)
+		}
+
+		if err := loadConfig(); err != nil {
+			return err
+		}
+
+		issueKey := strings.ToUpper(args[0])
+		client := newClient()
+		ctx := cmd.Context()
+
+		entries, err := client.GetChangelog(ctx, issueKey)
+		if err != nil {
+			return fmt.Errorf("fetching history of %s: %w", issueKey, err)
+		}
+
+		rows := markdown.HistoryRows(entries, historyFields...)
+
+		if historyOutput == "json" {
+			if rows == nil {
+				rows = []markdown.HistoryRow{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(rows)
+		}
+
+		if len(rows) == 0 {
+			fmt.Fprintf(os.Stderr, "No matching changes on %s.\n", issueKey)
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "FIELD\tFROM\tTO\tAUTHOR\tTIME")
+		fmt.Fprintln(w, "-----\t----\t--\t------\t----")
+		for _, r := range rows {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Field, historyValue(r.From), historyValue(r.To), r.Author, markdown.FormatDateTime(r.Time))
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+// fetchHistory returns the issue's changelog for markdown.MarshalOptions; the
+// result is non-nil so an issue without changes still gets a section.
+func fetchHistory(ctx context.Context, client jira.JIRA, key string) ([]jira.ChangelogEntry, error) {
+	entries, err := client.GetChangelog(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("fetching history of %s: %w", key, err)
+	}
+	if entries == nil {
+		entries = []jira.ChangelogEntry{}
+	}
+	return entries, nil
+}
+
+// historyValue shortens a changed value to one line for the table.
+func historyValue(s string) string {
+	return truncate(strings.Join(strings.Fields(s), " "), 40)
+}
+
+func init() {
+	historyCmd.Flags().StringSliceVar(&historyFields, "field", nil, "only show changes to this field (repeatable)")
+	historyCmd.Flags().StringVarP(&historyOutput, "output", "o", "table", "output format: table or json")
+	rootCmd.AddCommand(historyCmd)
+}
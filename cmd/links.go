@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mreider/a-cli/internal/jira"
+	"github.com/mreider/a-cli/internal/markdown"
+)
+
+// linkChange is a link to create or delete on apply.
+type linkChange struct {
+	Phrase string
+	Key    string
+
+	LinkID string          // set for deletions
+	Link   *jira.IssueLink // set for additions; both sides filled in
+}
+
+func (l linkChange) String() string {
+	return l.Phrase + " " + l.Key
+}
+
+// planLinkChanges compares the ## Links section with the issue's links in
+// JIRA. Phrases of new links are resolved against the site's link types before
+// anything is sent. A file without a ## Links section changes nothing.
+func planLinkChanges(ctx context.Context, client jira.JIRA, current *jira.Issue, ticket *markdown.Ticket) (add, remove []linkChange, err error) {
+	if ticket.Links == nil {
+		return nil, nil, nil
+	}
+
+	existing := make(map[string]bool)
+	for _, l := range current.Fields.IssueLinks {
+		phrase, other := markdown.LinkPhrase(l)
+		if other == nil {
+			continue
+		}
+		existing[linkID(phrase, other.Key)] = true
+	}
+
+	wanted := make(map[string]bool)
+	for _, l := range ticket.Links {
+		id := linkID(l.Phrase, l.Key)
+		if wanted[id] {
+			continue
+		}
+		wanted[id] = true
+		if !existing[id] {
+			add = append(add, linkChange{Phrase: l.Phrase, Key: l.Key})
+		}
+	}
+
+	for _, l := range current.Fields.IssueLinks {
+		phrase, other := markdown.LinkPhrase(l)
+		if other == nil || wanted[linkID(phrase, other.Key)] {
+			continue
+		}
+		remove = append(remove, linkChange{Phrase: phrase, Key: other.Key, LinkID: l.ID})
+	}
+
+	if len(add) == 0 {
+		return nil, remove, nil
+	}
+
+	types, err := client.GetIssueLinkTypes(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching issue link types: %w", err)
+	}
+	for i := range add {
+		link, err := resolveLink(types, ticket.Key, add[i].Phrase, add[i].Key)
+		if err != nil {
+			return nil, nil, err
+		}
+		add[i].Link = link
+	}
+
+	return add, remove, nil
+}
+
+// resolveLink builds the link payload for "key <phrase> other". The phrase may
+// be a type's outward or inward description, or the type name (read as outward).
+func resolveLink(types []jira.IssueLinkType, key, phrase, other string) (*jira.IssueLink, error) {
+	self := &jira.LinkedIssue{Key: key}
+	target := &jira.LinkedIssue{Key: other}
+
+	for _, t := range types {
+		ref := jira.IssueLinkType{Name: t.Name}
+		if strings.EqualFold(phrase, t.Outward) || strings.EqualFold(phrase, t.Name) {
+			return &jira.IssueLink{Type: ref, InwardIssue: self, OutwardIssue: target}, nil
+		}
+		if strings.EqualFold(phrase, t.Inward) {
+			return &jira.IssueLink{Type: ref, InwardIssue: target, OutwardIssue: self}, nil
+		}
+	}
+
+	var phrases []string
+	for _, t := range types {
+		phrases = append(phrases, t.Outward, t.Inward)
+	}
+	sort.Strings(phrases)
+	return nil, fmt.Errorf("unknown link type %q in '- %s %s'; available: %s", phrase, phrase, other, strings.Join(dedupe(phrases), ", "))
+}
+
+// linkID identifies a link by its phrase and the linked issue.
+func linkID(phrase, key string) string {
+	return strings.ToLower(phrase) + " " + strings.ToUpper(key)
+}
+
+// dedupe removes adjacent duplicates from a sorted slice.
+func dedupe(sorted []string) []string {
+	var out []string
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mreider/a-cli/internal/jira"
+	"github.com/mreider/a-cli/internal/jira/jiratest"
+)
+
+// newProfileSites returns production and sandbox sites that both have
+// PROJ-1, and a config with production at the top level and the sandbox as
+// a profile.
+func newProfileSites(t *testing.T) (prod, sandbox *jiratest.Server, cfg string) {
+	prod, sandbox = newApplyServer(t), newApplyServer(t)
+	cfg = fmt.Sprintf("url: %s\nemail: me@example.com\ntoken: test-token\nprofiles:\n  sandbox:\n    url: %s\n", prod.URL, sandbox.URL)
+	return prod, sandbox, cfg
+}
+
+func TestProfile_PulledFileRecordsSite(t *testing.T) {
+	_, sandbox, cfg := newProfileSites(t)
+	dir := t.TempDir()
+	if _, stderr, err := runWithConfig(t, cfg, "get", "PROJ-1", "--profile", "Sandbox", "--output-dir", dir); err != nil {
+		t.Fatalf("get: %v\n%s", err, stderr)
+	}
+	content := readFile(t, filepath.Join(dir, "PROJ-1.md"))
+	for _, want := range []string{"site: " + sandbox.URL + "\n", "profile: sandbox\n"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("file is missing %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestProfile_PushRefusesOtherSite(t *testing.T) {
+	prod, sandbox, cfg := newProfileSites(t)
+	dir := t.TempDir()
+	if _, stderr, err := runWithConfig(t, cfg, "get", "PROJ-1", "--profile", "sandbox", "--output-dir", dir); err != nil {
+		t.Fatalf("get: %v\n%s", err, stderr)
+	}
+	path := filepath.Join(dir, "PROJ-1.md")
+	editFile(t, path, "Steps to reproduce.", "Sandbox steps.")
+
+	for _, command := range []string{"push", "apply"} {
+		_, _, err := runWithConfig(t, cfg, command, "-f", path)
+		if err == nil || !strings.Contains(err.Error(), "Use --profile sandbox") {
+			t.Errorf("%s: expected a wrong-site error, got %v", command, err)
+		}
+	}
+	if got := issueText(prod.Issue("PROJ-1").Fields.Description); got != "Steps to reproduce." {
+		t.Errorf("production issue was changed: %q", got)
+	}
+
+	if _, stderr, err := runWithConfig(t, cfg, "push", "-f", path, "--profile", "sandbox"); err != nil {
+		t.Fatalf("push --profile sandbox: %v\n%s", err, stderr)
+	}
+	if got := issueText(sandbox.Issue("PROJ-1").Fields.Description); got != "Sandbox steps." {
+		t.Errorf("sandbox issue not updated: %q", got)
+	}
+}
+
+func TestProfile_OlderFileCheckedByURL(t *testing.T) {
+	_, sandbox, cfg := newProfileSites(t)
+	path := pullIssue(t, sandbox, "PROJ-1")
+	editFile(t, path, "site: "+sandbox.URL+"\n", "")
+
+	_, _, err := runWithConfig(t, cfg, "push", "-f", path)
+	if err == nil || !strings.Contains(err.Error(), "was pulled from "+sandbox.URL+"/browse/PROJ-1") {
+		t.Fatalf("expected a wrong-site error, got %v", err)
+	}
+}
+
+func TestProfile_ConfluencePushRefusesOtherSite(t *testing.T) {
+	prod, pageID := newConfluenceServer(t)
+	sandbox := jiratest.NewServer(t)
+	sandbox.AddSpace(jira.ConfluenceSpace{ID: "100", Key: "ENG"})
+	sandbox.AddPage(jira.ConfluencePage{ID: pageID, Title: "Handbook", SpaceID: "100"}, "", adfJSON(t, "Welcome aboard."))
+	cfg := fmt.Sprintf("url: %s\nemail: me@example.com\ntoken: test-token\nprofiles:\n  sandbox:\n    url: %s\n", prod.URL, sandbox.URL)
+
+	dir := t.TempDir()
+	if _, stderr, err := runWithConfig(t, cfg, "confluence", "get", pageID, "--output-dir", dir); err != nil {
+		t.Fatalf("confluence get: %v\n%s", err, stderr)
+	}
+	_, _, err := runWithConfig(t, cfg, "confluence", "push", "-f", filepath.Join(dir, "Handbook.md"), "--profile", "sandbox")
+	if err == nil || !strings.Contains(err.Error(), "Run without --profile") {
+		t.Fatalf("expected a wrong-site error, got %v", err)
+	}
+}
+
+func TestProfile_Unknown(t *testing.T) {
+	_, _, cfg := newProfileSites(t)
+	_, _, err := runWithConfig(t, cfg, "get", "PROJ-1", "--profile", "staging")
+	if err == nil || !strings.Contains(err.Error(), `no profile "staging" in the config file; profiles: sandbox`) {
+		t.Fatalf("expected an unknown profile error, got %v", err)
+	}
+}
+
+func TestSelectedProfile_FlagBeforeEnvironment(t *testing.T) {
+	resetFlags(rootCmd)
+	t.Setenv("A_CLI_PROFILE", "sandbox")
+	if got := selectedProfile(); got != "sandbox" {
+		t.Errorf("expected the profile from the environment, got %q", got)
+	}
+	rootCmd.PersistentFlags().Set("profile", "prod")
+	defer resetFlags(rootCmd)
+	if got := selectedProfile(); got != "prod" {
+		t.Errorf("expected --profile to win, got %q", got)
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 
@@ -41,6 +42,9 @@ Use --dry-run to preview the ADF output without applying.`,
 		if err != nil {
 			return fmt.Errorf("parsing markdown: %w", err)
 		}
+		if err := checkOrigin(pushFile, ticket.Site, ticket.Profile, ticket.URL); err != nil {
+			return err
+		}
 
 		// Convert body to ADF
 		adf, err := markdown.BodyToADF(ticket.Body)
@@ -48,11 +52,12 @@ Use --dry-run to preview the ADF output without applying.`,
 			return fmt.Errorf("converting body to ADF: %w", err)
 		}
 
-		client := jira.NewClient(appConfig)
+		client := newClient()
+		ctx := cmd.Context()
 
 		// Conflict check: compare updated timestamps
 		if ticket.Updated != "" {
-			current, err := client.GetIssue(ticket.Key)
+			current, err := client.GetIssue(ctx, ticket.Key)
 			if err != nil {
 				return fmt.Errorf("checking for conflicts on %s: %w", ticket.Key, err)
 			}
@@ -78,7 +83,10 @@ Use --dry-run to preview the ADF output without applying.`,
 			},
 		}
 
-		if err := client.UpdateIssue(ticket.Key, payload); err != nil {
+		if err := client.UpdateIssue(ctx, ticket.Key, payload); err != nil {
+			if errors.Is(err, jira.ErrConflict) {
+				return fmt.Errorf("conflict: %s was modified in JIRA while pushing.\nRe-pull the ticket before pushing.", ticket.Key)
+			}
 			return fmt.Errorf("pushing body to %s: %w", ticket.Key, err)
 		}
 
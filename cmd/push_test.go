@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mreider/a-cli/internal/jira"
+	"github.com/mreider/a-cli/internal/jira/jiratest"
+)
+
+func TestPush_UpdatesOnlyTheDescription(t *testing.T) {
+	srv := newApplyServer(t)
+	path := pullIssue(t, srv, "PROJ-1")
+	editFile(t, path, "title: Fix login", "title: Ignored", "Steps to reproduce.", "New steps.")
+
+	if _, stderr, err := runCommand(t, srv, "push", "-f", path); err != nil {
+		t.Fatalf("push: %v\n%s", err, stderr)
+	}
+	f := srv.Issue("PROJ-1").Fields
+	if got := issueText(f.Description); got != "New steps." {
+		t.Errorf("expected the new description, got %q", got)
+	}
+	if f.Summary != "Fix login" {
+		t.Errorf("push changed the summary to %q", f.Summary)
+	}
+}
+
+func TestPush_RefusesStaleFile(t *testing.T) {
+	srv := newApplyServer(t)
+	path := pullIssue(t, srv, "PROJ-1")
+	srv.AddIssue(jira.Issue{Key: "PROJ-1", Fields: jira.Fields{Summary: "Changed elsewhere"}})
+
+	_, _, err := runCommand(t, srv, "push", "-f", path)
+	if err == nil || !strings.Contains(err.Error(), "modified in JIRA since your last pull") {
+		t.Fatalf("expected a conflict error, got %v", err)
+	}
+}
+
+func TestPush_ReportsConflictWhilePushing(t *testing.T) {
+	srv := newApplyServer(t)
+	path := pullIssue(t, srv, "PROJ-1")
+	srv.FailNext("PUT", "/rest/api/3/issue/PROJ-1", http.StatusConflict)
+
+	_, _, err := runCommand(t, srv, "push", "-f", path)
+	if err == nil || !strings.Contains(err.Error(), "PROJ-1 was modified in JIRA while pushing") {
+		t.Fatalf("expected a conflict error, got %v", err)
+	}
+}
+
+func TestConfluencePush_ReportsVersionConflict(t *testing.T) {
+	srv, pageID := newConfluenceServer(t)
+	dir := t.TempDir()
+	if _, stderr, err := runCommand(t, srv, "confluence", "get", pageID, "--output-dir", dir); err != nil {
+		t.Fatalf("confluence get: %v\n%s", err, stderr)
+	}
+	path := dir + "/Handbook.md"
+	editFile(t, path, "Welcome aboard.", "Welcome, everyone.")
+
+	if _, stderr, err := runCommand(t, srv, "confluence", "push", "-f", path); err != nil {
+		t.Fatalf("confluence push: %v\n%s", err, stderr)
+	}
+	page := srv.Page(pageID)
+	if page.Version.Number != 2 || !strings.Contains(page.Body.AtlasDocFormat.Value, "Welcome, everyone.") {
+		t.Errorf("page not updated: version %d, body %s", page.Version.Number, page.Body.AtlasDocFormat.Value)
+	}
+
+	// Someone else saves version 3 between our read and our write.
+	srv.FailNext("PUT", "/wiki/api/v2/pages/"+pageID, http.StatusConflict)
+	_, _, err := runCommand(t, srv, "confluence", "push", "-f", path)
+	if err == nil || !strings.Contains(err.Error(), "was updated in Confluence while pushing") {
+		t.Fatalf("expected a version conflict error, got %v", err)
+	}
+}
+
+// newConfluenceServer returns a fake with an ENG space holding a Handbook page.
+func newConfluenceServer(t *testing.T) (*jiratest.Server, string) {
+	srv := jiratest.NewServer(t)
+	srv.AddSpace(jira.ConfluenceSpace{ID: "100", Key: "ENG", Name: "Engineering"})
+	id := srv.AddPage(jira.ConfluencePage{Title: "Handbook", SpaceID: "100"}, "", adfJSON(t, "Welcome aboard."))
+	return srv, id
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mreider/a-cli/internal/jira/jiratest"
+)
+
+func TestReplay_ReproducesGet(t *testing.T) {
+	srv := newApplyServer(t)
+	cassette := filepath.Join(t.TempDir(), "cassette")
+
+	recorded, stderr, err := runCommand(t, srv, "get", "PROJ-1", "--record", cassette)
+	if err != nil {
+		t.Fatalf("get --record: %v\n%s", err, stderr)
+	}
+
+	// The replay is configured for a site with nothing on it.
+	empty := jiratest.NewServer(t)
+	replayed, stderr, err := runCommand(t, empty, "get", "PROJ-1", "--replay", cassette)
+	if err != nil {
+		t.Fatalf("get --replay: %v\n%s", err, stderr)
+	}
+	if replayed != recorded {
+		t.Errorf("replayed output differs:\n--- recorded\n%s\n--- replayed\n%s", recorded, replayed)
+	}
+	if reqs := empty.Requests(); len(reqs) != 0 {
+		t.Errorf("replay went to the network: %v", reqs)
+	}
+}
+
+func TestReplay_ReproducesConfluenceGet(t *testing.T) {
+	srv, pageID := newConfluenceServer(t)
+	cassette := filepath.Join(t.TempDir(), "cassette")
+
+	recorded, stderr, err := runCommand(t, srv, "confluence", "get", pageID, "--record", cassette)
+	if err != nil {
+		t.Fatalf("confluence get --record: %v\n%s", err, stderr)
+	}
+	replayed, stderr, err := runCommand(t, jiratest.NewServer(t), "confluence", "get", pageID, "--replay", cassette)
+	if err != nil {
+		t.Fatalf("confluence get --replay: %v\n%s", err, stderr)
+	}
+	if replayed != recorded {
+		t.Errorf("replayed output differs:\n--- recorded\n%s\n--- replayed\n%s", recorded, replayed)
+	}
+}
+
+func TestReplay_FailsForUnrecordedRequest(t *testing.T) {
+	srv := newApplyServer(t)
+	cassette := filepath.Join(t.TempDir(), "cassette")
+	if _, stderr, err := runCommand(t, srv, "get", "PROJ-1", "--record", cassette); err != nil {
+		t.Fatalf("get --record: %v\n%s", err, stderr)
+	}
+
+	if _, _, err := runCommand(t, srv, "get", "PROJ-2", "--replay", cassette); err == nil {
+		t.Fatal("expected replaying an unrecorded request to fail")
+	}
+}
@@ -1,10 +1,21 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/mreider/a-cli/internal/config"
+	"github.com/mreider/a-cli/internal/jira"
+	"github.com/mreider/a-cli/internal/markdown"
+	"github.com/mreider/a-cli/internal/oauth"
 	"github.com/spf13/cobra"
 )
 
@@ -12,6 +23,15 @@ var (
 	cfgFile   string
 	appConfig config.Config
 	version   = "dev"
+
+	profileName string
+
+	recordDir string
+	replayDir string
+	debugHTTP bool
+
+	// clientOptions are passed to every client newClient creates.
+	clientOptions []jira.Option
 )
 
 var rootCmd = &cobra.Command{
@@ -21,26 +41,203 @@ var rootCmd = &cobra.Command{
 	Version: version,
 }
 
-// Execute runs the root command.
+// Execute runs the root command. Ctrl-C cancels the command's context so
+// it can stop cleanly and report what it finished; a second Ctrl-C exits
+// immediately.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		if ctx.Err() != nil {
+			os.Exit(130)
+		}
 		os.Exit(1)
 	}
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ~/.a-cli.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "use this profile from the config file (or set "+config.ProfileEnv+")")
+	rootCmd.PersistentFlags().StringVar(&recordDir, "record", "", "save every request and response to this directory, with credentials redacted")
+	rootCmd.PersistentFlags().StringVar(&replayDir, "replay", "", "answer requests from a directory saved with --record instead of the network")
+	rootCmd.PersistentFlags().BoolVar(&debugHTTP, "debug", false, "log every HTTP request and response to stderr, with credentials redacted (or set A_CLI_DEBUG=1)")
 }
 
 // loadConfig loads and validates configuration. Commands that need JIRA access call this.
 func loadConfig() error {
-	cfg, err := config.Load(cfgFile)
+	cfg, err := config.LoadProfile(cfgFile, selectedProfile())
 	if err != nil {
 		return fmt.Errorf("loading config: %w", err)
 	}
+	clientOptions = nil
+	markdown.Now = time.Now
+	token, confluenceToken := cfg.Token, cfg.ConfluenceToken
+
+	if recordDir != "" && replayDir != "" {
+		return fmt.Errorf("--record and --replay can't be used together")
+	}
+	if replayDir != "" {
+		// A replay needs no credentials and talks to the recorded site, with
+		// the recorded field map, so it reproduces the original output.
+		cassette, err := jira.OpenCassette(replayDir)
+		if err != nil {
+			return err
+		}
+		cfg.URL, cfg.Profile, cfg.Fields = cassette.URL, cassette.Profile, cassette.Fields
+		cfg.Flavor, cfg.ConfluenceURL = cassette.Flavor, cassette.ConfluenceURL
+		cfg.OAuth = nil
+		if cassette.CloudID != "" {
+			// Route through the recorded gateway path, without signing in.
+			cfg.OAuth = &config.OAuth{ClientID: "replay", CloudID: cassette.CloudID, RefreshToken: "replay"}
+			clientOptions = append(clientOptions, jira.WithTokenSource(oauth.StaticToken("replay")))
+		}
+		if cfg.Email == "" || cfg.Token == "" {
+			cfg.Email, cfg.Token = "replay", "replay"
+		}
+		markdown.Now = func() time.Time { return cassette.Recorded }
+		clientOptions = append(clientOptions, cassette.Replay())
+	}
+
+	if cfg.Token == "" && cfg.TokenCommand != "" && cfg.OAuth == nil {
+		if cfg.Token, err = config.CommandToken(cfg.TokenCommand); err != nil {
+			return err
+		}
+		token = cfg.Token
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return fmt.Errorf("invalid config: %w\nRun 'a-cli config' to set up credentials", err)
 	}
+
+	if cfg.OAuth != nil && replayDir == "" {
+		clientOptions = append(clientOptions, jira.WithTokenSource(oauthTokens(cfg)))
+	}
+
+	if recordDir != "" {
+		// Pulled files get the time recording started as their synced time,
+		// which a replay can reproduce.
+		cassette, err := jira.NewCassette(recordDir, cfg, time.Now().UTC().Truncate(time.Second))
+		if err != nil {
+			return err
+		}
+		markdown.Now = func() time.Time { return cassette.Recorded }
+		clientOptions = append(clientOptions, cassette.Record())
+	}
+
+	if debugEnabled() {
+		clientOptions = append(clientOptions, jira.Debug(os.Stderr, token, confluenceToken))
+	}
+
 	appConfig = cfg
 	return nil
 }
+
+// oauthTokens returns the token source every client shares, so the refresh
+// token is rotated once per run. Each rotated refresh token is saved back to
+// the config file; the one just used may stop working.
+func oauthTokens(cfg config.Config) *oauth.TokenSource {
+	timeout, _ := cfg.RequestTimeout()
+	return oauth.NewTokenSource(oauth.Config{
+		ClientID:     cfg.OAuth.ClientID,
+		ClientSecret: cfg.OAuth.ClientSecret,
+		Endpoint:     oauth.Atlassian,
+		HTTPClient:   &http.Client{Timeout: timeout},
+	}, cfg.OAuth.RefreshToken, func(refreshToken string) {
+		if err := config.SaveRefreshToken(cfgFile, cfg.Profile, refreshToken); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: saving the new OAuth refresh token: %v\nRun 'a-cli config --oauth' if the next run can't sign in.\n", err)
+		}
+	})
+}
+
+// selectedProfile returns the profile named by --profile or A_CLI_PROFILE,
+// or "" for the top-level settings.
+func selectedProfile() string {
+	if profileName != "" {
+		return profileName
+	}
+	return os.Getenv(config.ProfileEnv)
+}
+
+// debugEnabled reports whether --debug or A_CLI_DEBUG asks for HTTP tracing.
+func debugEnabled() bool {
+	if debugHTTP {
+		return true
+	}
+	on, _ := strconv.ParseBool(os.Getenv("A_CLI_DEBUG"))
+	return on
+}
+
+// newClient returns a client for the loaded config.
+func newClient() *jira.Client {
+	return jira.NewClient(appConfig, clientOptions...)
+}
+
+// pullOrigin returns the site and profile recorded in pulled files.
+func pullOrigin() markdown.Origin {
+	return markdown.Origin{Site: appConfig.URL, Profile: appConfig.Profile}
+}
+
+// checkOrigin refuses to send a file to a site other than the one it was
+// pulled from. Files pulled before the site was recorded are checked
+// against their url instead; files with neither are let through.
+func checkOrigin(path, site, profile, pageURL string) error {
+	current := strings.TrimRight(appConfig.URL, "/")
+	from := strings.TrimRight(site, "/")
+	if from == "" {
+		if pageURL == "" || strings.HasPrefix(pageURL, current+"/") {
+			return nil
+		}
+		from = pageURL
+	} else if from == current {
+		return nil
+	}
+
+	hint := "Select the site it came from with --profile or " + config.ProfileEnv + "."
+	if profile != "" {
+		hint = fmt.Sprintf("Use --profile %s to send it there.", profile)
+	} else if site != "" {
+		hint = "Run without --profile or " + config.ProfileEnv + " to send it there."
+	}
+	return fmt.Errorf("%s was pulled from %s, but the current site is %s%s.\n%s", path, from, current, profileSuffix(appConfig.Profile), hint)
+}
+
+func profileSuffix(profile string) string {
+	if profile == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (profile %s)", profile)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so an interrupted write never leaves a truncated file behind.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, 0644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDebug_TracesRequestsToStderr(t *testing.T) {
+	srv := newApplyServer(t)
+
+	stdout, stderr, err := runCommand(t, srv, "get", "PROJ-1", "--debug")
+	if err != nil {
+		t.Fatalf("get: %v\n%s", err, stderr)
+	}
+	if !strings.Contains(stderr, "[debug] GET "+srv.URL+"/rest/api/3/issue/PROJ-1?fields=") || !strings.Contains(stderr, "-> 200 OK") {
+		t.Errorf("expected a trace of the request:\n%s", stderr)
+	}
+	if strings.Contains(stderr, "test-token") || strings.Contains(stdout, "[debug]") {
+		t.Errorf("unexpected trace output:\n%s", stderr)
+	}
+}
+
+func TestDebugEnabled_FromEnvironment(t *testing.T) {
+	resetFlags(rootCmd)
+	for value, want := range map[string]bool{"1": true, "true": true, "0": false, "": false} {
+		t.Setenv("A_CLI_DEBUG", value)
+		if got := debugEnabled(); got != want {
+			t.Errorf("A_CLI_DEBUG=%q: got %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestTokenCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	srv := newApplyServer(t)
+
+	cfg := fmt.Sprintf("url: %s\nemail: me@example.com\ntoken_command: printf 'command-token\\nlogin me\\n'\n", srv.URL)
+	_, stderr, err := runWithConfig(t, cfg, "get", "PROJ-1", "--debug")
+	if err != nil {
+		t.Fatalf("get: %v\n%s", err, stderr)
+	}
+	if strings.Contains(stderr, "command-token") {
+		t.Errorf("the token was echoed:\n%s", stderr)
+	}
+
+	cfg = fmt.Sprintf("url: %s\nemail: me@example.com\ntoken_command: exit 3\n", srv.URL)
+	if _, _, err := runWithConfig(t, cfg, "get", "PROJ-1"); err == nil || !strings.Contains(err.Error(), "token_command \"exit 3\" failed with exit code 3") {
+		t.Errorf("expected the command's failure, got %v", err)
+	}
+}
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -25,8 +27,13 @@ var (
 	searchCreated    string
 	searchText       string
 	searchMaxResults int
+	searchAll        bool
 	searchOrderBy    string
 	searchOutputDir  string
+
+	searchAttachments bool
+	searchWorklog     bool
+	searchHistory     bool
 )
 
 var searchCmd = &cobra.Command{
@@ -48,8 +55,13 @@ Examples:
   a-cli search -q "login error"
   a-cli search --project PRODUCT -q "deployment"
 
+  # Every match, fetched page by page
+  a-cli search --project PRODUCT --status Done --all
+
   # Pull all results to markdown files
   a-cli search --project PRODUCT --updated recent --output-dir ./tickets
+  a-cli search --project PRODUCT --all --output-dir ./tickets
+  a-cli search --project PRODUCT --output-dir ./tickets --attachments
 
 Smart date values for --updated/--created:
   today, yesterday, recent (7 days), last week, this week,
@@ -59,34 +71,96 @@ Smart date values for --updated/--created:
 			return err
 		}
 
+		if searchAttachments && searchOutputDir == "" {
+			return fmt.Errorf("--attachments requires --output-dir")
+		}
+
 		jql, err := buildJQL(args)
 		if err != nil {
 			return err
 		}
 
-		client := jira.NewClient(appConfig)
+		client := newClient()
+		ctx := cmd.Context()
+
+		limit := searchMaxResults
+		if searchAll {
+			limit = 0
+		}
+
+		var puller *issuePuller
+		if searchOutputDir != "" {
+			if puller, err = newIssuePuller(ctx, client); err != nil {
+				return err
+			}
+		}
 
-		result, err := client.SearchIssues(jql, searchMaxResults, 0)
+		// The search endpoint doesn't report a total, so ask for an estimate
+		// to show with the progress lines. It's fine to go without.
+		estimate, _ := client.ApproximateIssueCount(ctx, jql)
+		if estimate > 0 {
+			fmt.Fprintf(os.Stderr, "Found about %d issues  JQL: %s\n\n", estimate, jql)
+		}
+
+		// One table for the whole run, so the columns line up across pages
+		table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		found := 0
+		err = client.SearchIssuesPages(ctx, jql, limit, func(page *jira.SearchResult) error {
+			if len(page.Issues) == 0 {
+				return nil
+			}
+			first := found == 0
+			found += len(page.Issues)
+
+			if puller != nil {
+				if err := puller.pull(ctx, page.Issues); err != nil {
+					return err
+				}
+			} else {
+				printIssueTable(table, page.Issues, first)
+			}
+
+			if !page.IsLast && page.NextPageToken != "" && (limit <= 0 || found < limit) {
+				printSearchProgress("issues", found, estimate)
+			}
+			return nil
+		})
+		table.Flush()
+		if err != nil && ctx.Err() != nil {
+			if puller != nil {
+				return reportInterrupted("pulled %d issues to %s", puller.pulled, searchOutputDir)
+			}
+			return reportInterrupted("listed %d issues", found)
+		}
 		if err != nil {
 			return fmt.Errorf("search failed: %w", err)
 		}
 
-		if len(result.Issues) == 0 {
+		if found == 0 {
 			fmt.Fprintf(os.Stderr, "No issues found.\nJQL: %s\n", jql)
 			return nil
 		}
 
-		fmt.Fprintf(os.Stderr, "Found %d issues (showing %d)  JQL: %s\n\n", result.Total, len(result.Issues), jql)
-
-		if searchOutputDir != "" {
-			return pullSearchResults(client, result.Issues)
+		if puller != nil {
+			fmt.Fprintf(os.Stderr, "\nPulled %d of %d issues to %s\n", puller.pulled, found, searchOutputDir)
+			return reportPullFailures("issue(s)", puller.failures)
+		} else if limit > 0 && found == limit && estimate > found {
+			fmt.Fprintf(os.Stderr, "\nShowing %d of about %d issues (use --all or raise --max-results for more)\n", found, estimate)
 		}
-
-		printIssueTable(result.Issues)
 		return nil
 	},
 }
 
+// printSearchProgress reports how many results have been read so far on
+// stderr, with the estimated total when there is one.
+func printSearchProgress(noun string, read, estimate int) {
+	if estimate > read {
+		fmt.Fprintf(os.Stderr, "  ... %d of about %d %s\n", read, estimate, noun)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "  ... %d %s so far\n", read, noun)
+}
+
 func buildJQL(args []string) (string, error) {
 	if searchJQL != "" {
 		if hasAnyFilter() {
@@ -157,10 +231,13 @@ func hasAnyFilter() bool {
 		searchUpdated != "" || searchCreated != "" || searchText != ""
 }
 
-func printIssueTable(issues []jira.Issue) {
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "KEY\tTYPE\tSTATUS\tASSIGNEE\tUPDATED\tSUMMARY")
-	fmt.Fprintln(w, "---\t----\t------\t--------\t-------\t-------")
+// printIssueTable writes a page of search results to w. The header is
+// written only for the first page.
+func printIssueTable(w io.Writer, issues []jira.Issue, header bool) {
+	if header {
+		fmt.Fprintln(w, "KEY\tTYPE\tSTATUS\tASSIGNEE\tUPDATED\tSUMMARY")
+		fmt.Fprintln(w, "---\t----\t------\t--------\t-------\t-------")
+	}
 
 	for _, issue := range issues {
 		assignee := ""
@@ -176,10 +253,7 @@ func printIssueTable(issues []jira.Issue) {
 			}
 		}
 
-		summary := issue.Fields.Summary
-		if len(summary) > 60 {
-			summary = summary[:57] + "..."
-		}
+		summary := truncate(issue.Fields.Summary, 60)
 
 		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
 			issue.Key,
@@ -190,44 +264,121 @@ func printIssueTable(issues []jira.Issue) {
 			summary,
 		)
 	}
-	w.Flush()
 }
 
-func pullSearchResults(client *jira.Client, issues []jira.Issue) error {
+// truncate shortens s to at most n characters, ending with "..." when it was
+// cut. It counts runes so that multi-byte characters aren't split.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
+
+// issuePuller writes search results to markdown files in --output-dir as
+// pages of results arrive, fetching up to --concurrency issues at a time.
+type issuePuller struct {
+	client      jira.JIRA
+	fieldMap    map[string]jira.FieldInfo
+	fieldIDs    map[string]string
+	extraFields []string
+
+	pulled   int
+	failures []pullFailure
+}
+
+func newIssuePuller(ctx context.Context, client jira.JIRA) (*issuePuller, error) {
 	if err := os.MkdirAll(searchOutputDir, 0755); err != nil {
-		return fmt.Errorf("creating output directory: %w", err)
+		return nil, fmt.Errorf("creating output directory: %w", err)
 	}
 
-	for _, issue := range issues {
-		// Re-fetch with full fields (description, comments)
-		full, err := client.GetIssue(issue.Key)
+	fieldMap, err := resolveFieldMap(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	fieldIDs, extraFields := fieldMapIDs(fieldMap)
+
+	return &issuePuller{client: client, fieldMap: fieldMap, fieldIDs: fieldIDs, extraFields: extraFields}, nil
+}
+
+// pull fetches and converts the issues in parallel, then writes them in order.
+// Issues that can't be fetched or converted are recorded as failures.
+func (p *issuePuller) pull(ctx context.Context, issues []jira.Issue) error {
+	var writeErr error
+	jira.Ordered(ctx, len(issues), pullConcurrency, func(i int) (pullResult, error) {
+		return p.fetch(ctx, issues[i].Key)
+	}, func(i int, r pullResult, err error) {
+		key := issues[i].Key
+		for _, w := range r.warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+		}
+		if isCancelled(err) {
+			return
+		}
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: could not fetch %s: %v\n", issue.Key, err)
-			continue
+			p.failures = append(p.failures, pullFailure{Item: key, Err: err})
+			return
+		}
+		if writeErr != nil {
+			return
 		}
 
-		// Check for existing custom properties
-		var customProps map[string]interface{}
-		existingPath := filepath.Join(searchOutputDir, issue.Key+".md")
-		if existing, err := os.ReadFile(existingPath); err == nil {
-			customProps, _ = markdown.ExtractCustomProperties(string(existing))
+		filename := filepath.Join(searchOutputDir, key+".md")
+		if err := writeFileAtomic(filename, []byte(r.md)); err != nil {
+			writeErr = fmt.Errorf("writing %s: %w", filename, err)
+			return
 		}
+		p.pulled++
+		fmt.Fprintf(os.Stderr, "  %s -> %s\n", key, filename)
+	})
+	return writeErr
+}
 
-		md, err := markdown.Marshal(full, appConfig.URL, customProps)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: could not convert %s: %v\n", issue.Key, err)
-			continue
+// fetch builds the markdown for one issue. It runs on a worker goroutine.
+func (p *issuePuller) fetch(ctx context.Context, key string) (pullResult, error) {
+	var r pullResult
+	client := p.client
+
+	// Re-fetch with full fields (description, comments)
+	full, err := client.GetIssue(ctx, key, p.extraFields...)
+	if err != nil {
+		return r, fmt.Errorf("fetching: %w", err)
+	}
+
+	// Check for existing custom properties
+	var customProps map[string]interface{}
+	existingPath := filepath.Join(searchOutputDir, key+".md")
+	if existing, err := os.ReadFile(existingPath); err == nil {
+		customProps, _ = markdown.ExtractCustomProperties(string(existing), fieldMapKeys(p.fieldMap)...)
+	}
+
+	opts := markdown.MarshalOptions{CustomFields: p.fieldIDs, Profile: appConfig.Profile}
+	if searchAttachments {
+		if _, err := downloadAttachments(ctx, client, full, searchOutputDir); err != nil {
+			r.warn("could not download attachments of %s: %v", key, err)
+		} else {
+			opts.AssetsDir = assetsDirName(key)
+		}
+	}
+
+	if searchWorklog {
+		if opts.Worklogs, err = fetchWorklogs(ctx, client, key); err != nil {
+			r.warn("%v", err)
 		}
+	}
 
-		filename := filepath.Join(searchOutputDir, issue.Key+".md")
-		if err := os.WriteFile(filename, []byte(md), 0644); err != nil {
-			return fmt.Errorf("writing %s: %w", filename, err)
+	if searchHistory {
+		if opts.History, err = fetchHistory(ctx, client, key); err != nil {
+			r.warn("%v", err)
 		}
-		fmt.Fprintf(os.Stderr, "  %s -> %s\n", issue.Key, filename)
 	}
 
-	fmt.Fprintf(os.Stderr, "\nPulled %d issues to %s\n", len(issues), searchOutputDir)
-	return nil
+	r.md, err = markdown.Marshal(full, appConfig.URL, customProps, opts)
+	if err != nil {
+		return r, fmt.Errorf("converting: %w", err)
+	}
+	return r, nil
 }
 
 func init() {
@@ -242,7 +393,12 @@ func init() {
 	searchCmd.Flags().StringVar(&searchCreated, "created", "", "filter by created date")
 	searchCmd.Flags().StringVarP(&searchText, "text", "q", "", "full text search")
 	searchCmd.Flags().IntVar(&searchMaxResults, "max-results", 25, "maximum results to return")
+	searchCmd.Flags().BoolVar(&searchAll, "all", false, "return every match, ignoring --max-results")
 	searchCmd.Flags().StringVar(&searchOrderBy, "order-by", "updated DESC", "JQL ORDER BY clause")
 	searchCmd.Flags().StringVar(&searchOutputDir, "output-dir", "", "pull all results to markdown files in this directory")
+	addConcurrencyFlag(searchCmd)
+	searchCmd.Flags().BoolVar(&searchWorklog, "worklog", false, "with --output-dir, add a ## Worklog section to each file")
+	searchCmd.Flags().BoolVar(&searchHistory, "history", false, "with --output-dir, add a ## History section to each file")
+	searchCmd.Flags().BoolVar(&searchAttachments, "attachments", false, "with --output-dir, also download attachments into <dir>/<KEY>.assets/")
 	rootCmd.AddCommand(searchCmd)
 }
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mreider/a-cli/internal/jira"
+	"github.com/mreider/a-cli/internal/jira/jiratest"
+)
+
+func newSearchServer(t *testing.T) *jiratest.Server {
+	srv := jiratest.NewServer(t)
+	for i := 1; i <= 120; i++ {
+		srv.AddIssue(jira.Issue{Key: fmt.Sprintf("PROJ-%d", i), Fields: jira.Fields{Summary: fmt.Sprintf("Issue %d", i)}})
+	}
+	srv.AddIssue(jira.Issue{Key: "OTHER-1", Fields: jira.Fields{Summary: "Elsewhere"}})
+	return srv
+}
+
+func TestSearch_OutputDirPullsEveryPage(t *testing.T) {
+	srv := newSearchServer(t)
+	dir := t.TempDir()
+
+	if _, stderr, err := runCommand(t, srv, "search", "--project", "PROJ", "--all", "--output-dir", dir); err != nil {
+		t.Fatalf("search: %v\n%s", err, stderr)
+	}
+	if n := countFiles(t, dir); n != 120 {
+		t.Errorf("expected 120 files, got %d", n)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "OTHER-1.md")); !os.IsNotExist(err) {
+		t.Errorf("pulled an issue from another project: %v", err)
+	}
+}
+
+func TestSearch_OutputDirStopsAtMaxResults(t *testing.T) {
+	srv := newSearchServer(t)
+	dir := t.TempDir()
+
+	if _, stderr, err := runCommand(t, srv, "search", "--project", "PROJ", "--max-results", "30", "--output-dir", dir); err != nil {
+		t.Fatalf("search: %v\n%s", err, stderr)
+	}
+	if n := countFiles(t, dir); n != 30 {
+		t.Errorf("expected 30 files, got %d", n)
+	}
+}
+
+func TestSearch_TableLinesUpAcrossPages(t *testing.T) {
+	srv := newSearchServer(t)
+	// A wide row on one page only, with a summary too long for its column
+	issue := srv.Issue("PROJ-1")
+	issue.Fields.Assignee = &jira.User{DisplayName: "Maximiliane Oberhuber-Schwarzenegger"}
+	issue.Fields.Summary = strings.Repeat("é", 70)
+	srv.AddIssue(*issue)
+
+	stdout, stderr, err := runCommand(t, srv, "search", "--project", "PROJ", "--all")
+	if err != nil {
+		t.Fatalf("search: %v\n%s", err, stderr)
+	}
+	if !utf8.ValidString(stdout) || !strings.Contains(stdout, strings.Repeat("é", 57)+"...") {
+		t.Errorf("expected the summary to be cut at 57 characters:\n%s", stdout)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 122 {
+		t.Fatalf("expected a header, a rule and 120 rows, got %d lines", len(lines))
+	}
+	column := utf8.RuneCountInString(lines[0][:strings.Index(lines[0], "SUMMARY")])
+	for _, line := range lines[2:] {
+		summary := strings.Index(line, "Issue ")
+		if summary < 0 {
+			summary = strings.Index(line, "é")
+		}
+		if got := utf8.RuneCountInString(line[:summary]); got != column {
+			t.Fatalf("expected the summary in column %d, got %d in %q", column, got, line)
+		}
+	}
+}
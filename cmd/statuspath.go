@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mreider/a-cli/internal/jira"
+)
+
+// maxTransitionHops bounds the search for a path between two statuses.
+const maxTransitionHops = 6
+
+// transitionSource lists the transitions available from a status.
+type transitionSource func(status string) ([]jira.TransitionInfo, error)
+
+// planStatusPath returns the transitions that take the issue from its current
+// status to target, shortest first. A direct transition is used when there is
+// one. Otherwise the workflow is read through the workflow API if the user may
+// read it, and failing that it is explored with GetTransitions on other issues
+// of the same project and type that sit in each intermediate status.
+func planStatusPath(ctx context.Context, client jira.JIRA, issue *jira.Issue, target string) ([]jira.TransitionInfo, error) {
+	transitions, err := client.GetTransitions(ctx, issue.Key)
+	if err != nil {
+		return nil, fmt.Errorf("fetching transitions: %w", err)
+	}
+	if t := findTransition(transitions, target); t != nil {
+		return []jira.TransitionInfo{*t}, nil
+	}
+
+	from := issue.Fields.Status.Name
+	if source, err := workflowTransitionSource(ctx, client, issue); err == nil {
+		return shortestStatusPath(from, target, source)
+	}
+
+	return shortestStatusPath(from, target, sampleTransitionSource(ctx, client, issue, transitions))
+}
+
+// shortestStatusPath runs a breadth-first search over statuses.
+func shortestStatusPath(from, target string, next transitionSource) ([]jira.TransitionInfo, error) {
+	type node struct {
+		status string
+		path   []jira.TransitionInfo
+	}
+
+	visited := map[string]bool{strings.ToLower(from): true}
+	queue := []node{{status: from}}
+	var unexplored []string
+
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		if len(n.path) >= maxTransitionHops {
+			continue
+		}
+
+		transitions, err := next(n.status)
+		if err != nil {
+			unexplored = append(unexplored, n.status)
+			continue
+		}
+
+		for _, t := range transitions {
+			path := append(append([]jira.TransitionInfo{}, n.path...), t)
+			if strings.EqualFold(t.To.Name, target) || (len(path) == 1 && strings.EqualFold(t.Name, target)) {
+				return path, nil
+			}
+			key := strings.ToLower(t.To.Name)
+			if visited[key] {
+				continue
+			}
+			visited[key] = true
+			queue = append(queue, node{status: t.To.Name, path: path})
+		}
+	}
+
+	if len(unexplored) > 0 {
+		return nil, fmt.Errorf("no path from %q to %q found (transitions out of %s could not be determined)", from, target, strings.Join(unexplored, ", "))
+	}
+	return nil, fmt.Errorf("no path from %q to %q in the workflow", from, target)
+}
+
+// workflowTransitionSource reads the issue's workflow through the workflow
+// API. It fails when the user lacks permission to read workflows.
+func workflowTransitionSource(ctx context.Context, client jira.JIRA, issue *jira.Issue) (transitionSource, error) {
+	if issue.Fields.Project == nil || issue.Fields.Project.ID == "" || issue.Fields.IssueType.ID == "" {
+		return nil, fmt.Errorf("issue has no project or issue type id")
+	}
+
+	scheme, err := client.GetProjectWorkflowScheme(ctx, issue.Fields.Project.ID)
+	if err != nil {
+		return nil, err
+	}
+	name := scheme.IssueTypeMappings[issue.Fields.IssueType.ID]
+	if name == "" {
+		name = scheme.DefaultWorkflow
+	}
+	workflow, err := client.GetWorkflow(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	statusNames := make(map[string]string, len(workflow.Statuses))
+	for _, s := range workflow.Statuses {
+		statusNames[s.ID] = s.Name
+	}
+
+	return func(status string) ([]jira.TransitionInfo, error) {
+		var out []jira.TransitionInfo
+		for _, t := range workflow.Transitions {
+			if t.Type == "initial" {
+				continue
+			}
+			if len(t.From) > 0 && !containsStatus(t.From, statusNames, status) {
+				continue
+			}
+			out = append(out, jira.TransitionInfo{ID: t.ID, Name: t.Name, To: jira.Status{Name: statusNames[t.To]}})
+		}
+		return out, nil
+	}, nil
+}
+
+func containsStatus(ids []string, names map[string]string, status string) bool {
+	for _, id := range ids {
+		if strings.EqualFold(names[id], status) {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleTransitionSource explores the workflow without admin rights: the
+// transitions out of a status are read from another issue of the same project
+// and type that is currently in that status.
+func sampleTransitionSource(ctx context.Context, client jira.JIRA, issue *jira.Issue, current []jira.TransitionInfo) transitionSource {
+	cache := map[string][]jira.TransitionInfo{
+		strings.ToLower(issue.Fields.Status.Name): current,
+	}
+
+	return func(status string) ([]jira.TransitionInfo, error) {
+		if t, ok := cache[strings.ToLower(status)]; ok {
+			return t, nil
+		}
+
+		jql := fmt.Sprintf("project = %q AND issuetype = %q AND status = %q", projectKeyOf(issue.Key), issue.Fields.IssueType.Name, status)
+		result, err := client.SearchIssues(ctx, jql, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(result.Issues) == 0 {
+			return nil, fmt.Errorf("no %s issue in status %q to learn from", issue.Fields.IssueType.Name, status)
+		}
+
+		transitions, err := client.GetTransitions(ctx, result.Issues[0].Key)
+		if err != nil {
+			return nil, err
+		}
+		cache[strings.ToLower(status)] = transitions
+		return transitions, nil
+	}
+}
+
+// executeStatusPath performs the planned transitions in order. Before each hop
+// after the first it checks that the transition is offered, matching by id or
+// destination status. If a hop fails, the error says which hops were made and
+// the status the issue was left in.
+func executeStatusPath(ctx context.Context, client jira.JIRA, key, from string, path []jira.TransitionInfo) error {
+	status := from
+	for i, hop := range path {
+		id := hop.ID
+		if i > 0 {
+			available, err := client.GetTransitions(ctx, key)
+			if err != nil {
+				return hopError(key, from, status, path[:i], hop, err)
+			}
+			t := findTransitionByID(available, hop)
+			if t == nil {
+				return hopError(key, from, status, path[:i], hop, fmt.Errorf("transition not available"))
+			}
+			id = t.ID
+		}
+
+		if err := client.DoTransition(ctx, key, id, nil); err != nil {
+			return hopError(key, from, status, path[:i], hop, err)
+		}
+		status = hop.To.Name
+	}
+	return nil
+}
+
+func findTransitionByID(transitions []jira.TransitionInfo, hop jira.TransitionInfo) *jira.TransitionInfo {
+	for i, t := range transitions {
+		if t.ID == hop.ID {
+			return &transitions[i]
+		}
+	}
+	return findTransition(transitions, hop.To.Name)
+}
+
+func hopError(key, from, status string, done []jira.TransitionInfo, hop jira.TransitionInfo, err error) error {
+	if len(done) == 0 {
+		return fmt.Errorf("%s -> %s: %w", from, hop.To.Name, err)
+	}
+	return fmt.Errorf("moved %s %s, then %s -> %s failed: %w\n%s is now in status %q; re-pull before applying again",
+		key, describeStatusPath(from, done), status, hop.To.Name, err, key, status)
+}
+
+// describeStatusPath formats a path as "To Do -> In Progress -> Done".
+func describeStatusPath(from string, path []jira.TransitionInfo) string {
+	parts := []string{from}
+	for _, t := range path {
+		parts = append(parts, t.To.Name)
+	}
+	return strings.Join(parts, " -> ")
+}
@@ -0,0 +1,323 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mreider/a-cli/internal/jira"
+	"github.com/spf13/cobra"
+)
+
+var (
+	transitionTo         string
+	transitionJQL        string
+	transitionMaxResults int
+	transitionDryRun     bool
+	transitionResolution string
+	transitionFields     []string
+)
+
+var transitionCmd = &cobra.Command{
+	Use:   "transition [issue-key...]",
+	Short: "Move one or more JIRA issues to a status",
+	Long: `Transitions issues given by key and/or matched by --jql to the status named
+by --to (a status or transition name).
+
+Each issue is handled on its own: issues already in the target status are
+skipped, and issues the target can't be reached from, or whose transition
+screen needs a value that wasn't given, are reported at the end instead of
+stopping the run. The command exits non-zero if any issue was not transitioned.
+
+Transition screens that require fields (commonly Resolution) can be filled
+with --resolution, or --field for any field by id or name. Values for
+select-style fields are matched against the allowed values by name.
+
+Examples:
+  a-cli transition PRODUCT-1 PRODUCT-2 --to "In Progress"
+  a-cli transition --jql "project = PRODUCT AND sprint in closedSprints()" --to Done --resolution Fixed
+  a-cli transition --jql "labels = stale" --to Closed --field "Reason=Won't fix" --dry-run`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if transitionTo == "" {
+			return fmt.Errorf("--to is required")
+		}
+		if len(args) == 0 && transitionJQL == "" {
+			return fmt.Errorf("give issue keys or --jql")
+		}
+
+		fieldValues, err := parseTransitionFieldFlags()
+		if err != nil {
+			return err
+		}
+
+		if err := loadConfig(); err != nil {
+			return err
+		}
+
+		client := newClient()
+		ctx := cmd.Context()
+
+		issues, fetchFailures := collectTransitionIssues(ctx, client, args)
+
+		var plans []transitionPlan
+		for _, issue := range issues {
+			plans = append(plans, planTransition(ctx, client, issue, transitionTo, fieldValues))
+		}
+
+		printTransitionPlans(plans)
+
+		if transitionDryRun {
+			fmt.Println("\n(dry run - no transitions performed)")
+			return nil
+		}
+
+		done := 0
+		for i := range plans {
+			p := &plans[i]
+			if p.Err != nil || p.Transition == nil {
+				continue
+			}
+			if err := client.DoTransition(ctx, p.Key, p.Transition.ID, p.Fields); err != nil {
+				if ctx.Err() != nil {
+					return reportInterrupted("transitioned %d of %d issue(s) to %s", done, len(plans)+len(fetchFailures), transitionTo)
+				}
+				p.Err = err
+				continue
+			}
+			done++
+			fmt.Fprintf(os.Stderr, "  %s -> %s\n", p.Key, p.Transition.To.Name)
+		}
+
+		failed := fetchFailures
+		already := 0
+		for _, p := range plans {
+			if p.Err != nil {
+				failed = append(failed, transitionFailure{Key: p.Key, Err: p.Err})
+			} else if p.Transition == nil {
+				already++
+			}
+		}
+
+		fmt.Fprintf(os.Stderr, "\nTransitioned %d of %d issue(s) to %s", done, len(plans)+len(fetchFailures), transitionTo)
+		if already > 0 {
+			fmt.Fprintf(os.Stderr, " (%d already there)", already)
+		}
+		fmt.Fprintln(os.Stderr)
+		if len(failed) > 0 {
+			fmt.Fprintf(os.Stderr, "Not transitioned:\n")
+			for _, f := range failed {
+				fmt.Fprintf(os.Stderr, "  %s: %v\n", f.Key, f.Err)
+			}
+			return fmt.Errorf("%d issue(s) not transitioned", len(failed))
+		}
+		return nil
+	},
+}
+
+// transitionPlan is what will happen to one issue.
+type transitionPlan struct {
+	Key        string
+	From       string
+	Transition *jira.TransitionInfo // nil when the issue is already in the target status
+	Fields     map[string]any
+	Err        error
+}
+
+type transitionFailure struct {
+	Key string
+	Err error
+}
+
+// collectTransitionIssues gathers the issues named on the command line and
+// matched by --jql, in that order and without duplicates. Keys that can't be
+// fetched are returned as failures.
+func collectTransitionIssues(ctx context.Context, client jira.JIRA, keys []string) ([]jira.Issue, []transitionFailure) {
+	var issues []jira.Issue
+	var failed []transitionFailure
+	seen := make(map[string]bool)
+
+	for _, key := range keys {
+		key = strings.ToUpper(key)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		issue, err := client.GetIssue(ctx, key)
+		if err != nil {
+			failed = append(failed, transitionFailure{Key: key, Err: err})
+			continue
+		}
+		issues = append(issues, *issue)
+	}
+
+	if transitionJQL != "" {
+		result, err := client.SearchIssues(ctx, transitionJQL, transitionMaxResults)
+		if err != nil {
+			failed = append(failed, transitionFailure{Key: "--jql", Err: fmt.Errorf("search failed: %w", err)})
+			return issues, failed
+		}
+		if !result.IsLast {
+			fmt.Fprintf(os.Stderr, "Warning: JQL matched more than %d issues; only those are transitioned (raise --max-results)\n", len(result.Issues))
+		}
+		for _, issue := range result.Issues {
+			if seen[issue.Key] {
+				continue
+			}
+			seen[issue.Key] = true
+			issues = append(issues, issue)
+		}
+	}
+
+	return issues, failed
+}
+
+// planTransition finds the transition to target for an issue and fills its
+// screen fields from the given values (keyed by lowercase field id or name).
+func planTransition(ctx context.Context, client jira.JIRA, issue jira.Issue, target string, values map[string]string) transitionPlan {
+	plan := transitionPlan{Key: issue.Key, From: issue.Fields.Status.Name}
+	if strings.EqualFold(plan.From, target) {
+		return plan
+	}
+
+	transitions, err := client.GetTransitions(ctx, issue.Key)
+	if err != nil {
+		plan.Err = fmt.Errorf("fetching transitions: %w", err)
+		return plan
+	}
+
+	t := findTransition(transitions, target)
+	if t == nil {
+		var available []string
+		for _, tr := range transitions {
+			available = append(available, tr.To.Name)
+		}
+		plan.Err = fmt.Errorf("%s is not reachable from %s (available: %s)", target, plan.From, strings.Join(available, ", "))
+		return plan
+	}
+	plan.Transition = t
+
+	ids := make([]string, 0, len(t.Fields))
+	for id := range t.Fields {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		f := t.Fields[id]
+		value, ok := values[strings.ToLower(id)]
+		if !ok {
+			value, ok = values[strings.ToLower(f.Name)]
+		}
+		if !ok {
+			if f.Required && !f.HasDefaultValue {
+				plan.Err = fmt.Errorf("transition %q requires %s (use --field %q)", t.Name, f.Name, f.Name+"=...")
+				if id == "resolution" {
+					plan.Err = fmt.Errorf("transition %q requires a resolution (use --resolution)", t.Name)
+				}
+				return plan
+			}
+			continue
+		}
+
+		v, err := transitionFieldValue(ctx, client, id, f, value)
+		if err != nil {
+			plan.Err = err
+			return plan
+		}
+		if plan.Fields == nil {
+			plan.Fields = make(map[string]any)
+		}
+		plan.Fields[id] = v
+	}
+
+	return plan
+}
+
+// transitionFieldValue converts a flag value into the shape a transition
+// screen field expects. Fields with allowed values (resolution, select lists)
+// are matched by name and sent by id.
+func transitionFieldValue(ctx context.Context, client jira.JIRA, id string, f jira.TransitionField, value string) (any, error) {
+	if len(f.AllowedValues) > 0 {
+		if f.Schema.Type == "array" {
+			var out []map[string]string
+			for _, v := range strings.Split(value, ",") {
+				ref, err := allowedValueRef(f, strings.TrimSpace(v))
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, ref)
+			}
+			return out, nil
+		}
+		return allowedValueRef(f, value)
+	}
+
+	return customFieldUpdate(ctx, client, jira.FieldInfo{ID: id, Name: f.Name, Schema: f.Schema}, value)
+}
+
+func allowedValueRef(f jira.TransitionField, value string) (map[string]string, error) {
+	var names []string
+	for _, av := range f.AllowedValues {
+		if strings.EqualFold(av.Name, value) || strings.EqualFold(av.Value, value) || av.ID == value {
+			return map[string]string{"id": av.ID}, nil
+		}
+		name := av.Name
+		if name == "" {
+			name = av.Value
+		}
+		names = append(names, name)
+	}
+	return nil, fmt.Errorf("%q is not a valid %s (allowed: %s)", value, f.Name, strings.Join(names, ", "))
+}
+
+// parseTransitionFieldFlags collects --resolution and --field name=value
+// flags, keyed by lowercase field id or name.
+func parseTransitionFieldFlags() (map[string]string, error) {
+	values := make(map[string]string)
+	if transitionResolution != "" {
+		values["resolution"] = transitionResolution
+	}
+	for _, kv := range transitionFields {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid --field %q (use name=value)", kv)
+		}
+		values[strings.ToLower(strings.TrimSpace(name))] = value
+	}
+	return values, nil
+}
+
+func printTransitionPlans(plans []transitionPlan) {
+	if len(plans) == 0 {
+		fmt.Fprintln(os.Stderr, "No issues to transition.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tFROM\tTRANSITION\tTO")
+	fmt.Fprintln(w, "---\t----\t----------\t--")
+	for _, p := range plans {
+		switch {
+		case p.Err != nil:
+			fmt.Fprintf(w, "%s\t%s\t(skipped)\t%v\n", p.Key, p.From, p.Err)
+		case p.Transition == nil:
+			fmt.Fprintf(w, "%s\t%s\t(none)\talready %s\n", p.Key, p.From, p.From)
+		default:
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Key, p.From, p.Transition.Name, p.Transition.To.Name)
+		}
+	}
+	w.Flush()
+}
+
+func init() {
+	transitionCmd.Flags().StringVar(&transitionTo, "to", "", "target status or transition name (required)")
+	transitionCmd.Flags().StringVar(&transitionJQL, "jql", "", "transition every issue matching this JQL")
+	transitionCmd.Flags().IntVar(&transitionMaxResults, "max-results", 50, "maximum issues to take from --jql")
+	transitionCmd.Flags().BoolVar(&transitionDryRun, "dry-run", false, "list the transition each issue would take without performing it")
+	transitionCmd.Flags().StringVar(&transitionResolution, "resolution", "", "resolution to set when the transition screen asks for one")
+	transitionCmd.Flags().StringArrayVar(&transitionFields, "field", nil, "transition screen field as name=value, by field id or name (repeatable)")
+	rootCmd.AddCommand(transitionCmd)
+}
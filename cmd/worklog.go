@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mreider/a-cli/internal/dateparse"
+	"github.com/mreider/a-cli/internal/jira"
+	"github.com/mreider/a-cli/internal/markdown"
+	"github.com/spf13/cobra"
+)
+
+var (
+	worklogMessage string
+	worklogStarted string
+)
+
+// jiraTimeLayout is the timestamp format JIRA uses for worklog start times.
+const jiraTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+var worklogCmd = &cobra.Command{
+	Use:   "worklog",
+	Short: "Log and list time spent on JIRA issues",
+}
+
+var worklogAddCmd = &cobra.Command{
+	Use:   "add <issue-key> <duration>",
+	Short: "Log time on a JIRA issue",
+	Long: `Logs time spent on a JIRA issue.
+
+Durations use JIRA notation: w, d, h and m, combined freely (2h30m, 1d 4h,
+45m, 1.5h). A day is 8 hours and a week is 5 days, as in JIRA's default time
+tracking settings.
+
+--started accepts now (default), today, yesterday, an ISO date (logged at
+09:00 local time), "2025-01-15 14:00", or an RFC 3339 timestamp.
+
+Examples:
+  a-cli worklog add PRODUCT-123 2h30m -m "pairing"
+  a-cli worklog add PRODUCT-123 1d --started yesterday
+  a-cli worklog add PRODUCT-123 45m --started "2025-01-15 14:00"`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		issueKey := strings.ToUpper(args[0])
+
+		seconds, err := dateparse.ParseDuration(args[1])
+		if err != nil {
+			return err
+		}
+		started, err := dateparse.ParseStartTime(worklogStarted, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if err := loadConfig(); err != nil {
+			return err
+		}
+
+		payload := jira.WorklogPayload{
+			TimeSpentSeconds: seconds,
+			Started:          started.Format(jiraTimeLayout),
+		}
+		if worklogMessage != "" {
+			adf, err := markdown.BodyToADF(worklogMessage)
+			if err != nil {
+				return fmt.Errorf("converting comment to ADF: %w", err)
+			}
+			payload.Comment = adf
+		}
+
+		client := newClient()
+		ctx := cmd.Context()
+		if _, err := client.AddWorklog(ctx, issueKey, payload); err != nil {
+			return fmt.Errorf("logging time on %s: %w", issueKey, err)
+		}
+
+		fmt.Fprintf(os.Stderr, "Logged %s on %s (started %s)\n", dateparse.FormatDuration(seconds), issueKey, started.Format("2006-01-02 15:04"))
+		return nil
+	},
+}
+
+var worklogListCmd = &cobra.Command{
+	Use:   "list <issue-key>",
+	Short: "List time logged on a JIRA issue",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(); err != nil {
+			return err
+		}
+
+		issueKey := strings.ToUpper(args[0])
+		client := newClient()
+		ctx := cmd.Context()
+
+		worklogs, err := client.GetWorklogs(ctx, issueKey)
+		if err != nil {
+			return fmt.Errorf("fetching worklogs for %s: %w", issueKey, err)
+		}
+
+		if len(worklogs) == 0 {
+			fmt.Fprintf(os.Stderr, "No time logged on %s.\n", issueKey)
+			return nil
+		}
+
+		printWorklogTable(worklogs)
+		return nil
+	},
+}
+
+// fetchWorklogs returns the issue's worklogs for markdown.MarshalOptions; the
+// result is non-nil so an issue without logged time still gets a section.
+func fetchWorklogs(ctx context.Context, client jira.JIRA, key string) ([]jira.Worklog, error) {
+	worklogs, err := client.GetWorklogs(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("fetching worklogs for %s: %w", key, err)
+	}
+	if worklogs == nil {
+		worklogs = []jira.Worklog{}
+	}
+	return worklogs, nil
+}
+
+func printWorklogTable(worklogs []jira.Worklog) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tAUTHOR\tTIME\tCOMMENT")
+	fmt.Fprintln(w, "-------\t------\t----\t-------")
+
+	total := 0
+	for _, wl := range worklogs {
+		started := wl.Started
+		if t, err := time.Parse(jiraTimeLayout, wl.Started); err == nil {
+			started = t.Local().Format("2006-01-02 15:04")
+		}
+
+		comment := ""
+		if wl.Comment != nil {
+			comment = commentPreview(markdown.ADFToBody(wl.Comment))
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", started, markdown.WorklogAuthor(wl), dateparse.FormatDuration(wl.TimeSpentSeconds), comment)
+		total += wl.TimeSpentSeconds
+	}
+	fmt.Fprintf(w, "\t\t%s\ttotal\n", dateparse.FormatDuration(total))
+	w.Flush()
+}
+
+func init() {
+	worklogAddCmd.Flags().StringVarP(&worklogMessage, "message", "m", "", "worklog comment")
+	worklogAddCmd.Flags().StringVar(&worklogStarted, "started", "now", "when the work started (now, yesterday, 2025-01-15, \"2025-01-15 14:00\")")
+	worklogCmd.AddCommand(worklogAddCmd)
+	worklogCmd.AddCommand(worklogListCmd)
+	rootCmd.AddCommand(worklogCmd)
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
@@ -14,8 +17,82 @@ type Config struct {
 	URL   string `yaml:"url"   mapstructure:"url"`
 	Email string `yaml:"email" mapstructure:"email"`
 	Token string `yaml:"token" mapstructure:"token"`
+
+	// TokenCommand is run by the shell when no Token is set, and the first
+	// line it prints is used as the token, so it can come from a password
+	// manager (pass show atlassian, op read ...) instead of this file.
+	TokenCommand string `yaml:"token_command,omitempty" mapstructure:"token_command"`
+
+	// Flavor is FlavorCloud (the default) or FlavorDataCenter for a
+	// self-hosted Data Center or Server site, which is reached with a
+	// personal access token in Token and needs no Email.
+	Flavor string `yaml:"flavor,omitempty" mapstructure:"flavor"`
+
+	// ConfluenceURL and ConfluenceToken are for Data Center, where
+	// Confluence is a separate site with its own tokens. They default to
+	// URL and Token.
+	ConfluenceURL   string `yaml:"confluence_url,omitempty" mapstructure:"confluence_url"`
+	ConfluenceToken string `yaml:"confluence_token,omitempty" mapstructure:"confluence_token"`
+
+	// OAuth, when set, authenticates a Cloud site with OAuth 2.0 (3LO) in
+	// place of Email and Token. It is written by 'a-cli config --oauth'.
+	OAuth *OAuth `yaml:"oauth,omitempty" mapstructure:"oauth"`
+
+	// Timeout bounds connecting to the server and waiting for it to start
+	// answering each request, as a Go duration ("30s", "2m"). Empty means
+	// DefaultTimeout; "0" waits indefinitely.
+	Timeout string `yaml:"timeout,omitempty" mapstructure:"timeout"`
+
+	// Fields maps friendly frontmatter names to JIRA custom fields, given either
+	// as an id (customfield_10016) or a field name (Story Points). Names are
+	// lowercased when the file is read.
+	Fields map[string]string `yaml:"fields,omitempty" mapstructure:"fields"`
+
+	// Profiles are named alternative sites, such as a sandbox. A profile's
+	// settings replace the top-level ones; settings it leaves empty are
+	// inherited. Profile names are lowercased when the file is read.
+	Profiles map[string]Config `yaml:"profiles,omitempty" mapstructure:"profiles"`
+
+	// Profile is the name of the profile that was loaded, or "" for the
+	// top-level settings. It isn't saved.
+	Profile string `yaml:"-" mapstructure:"-"`
+}
+
+// OAuth holds the OAuth 2.0 (3LO) credentials for a Cloud site. Requests go
+// through the api.atlassian.com gateway for CloudID, with access tokens
+// obtained from RefreshToken as needed.
+type OAuth struct {
+	ClientID     string `yaml:"client_id"               mapstructure:"client_id"`
+	ClientSecret string `yaml:"client_secret,omitempty" mapstructure:"client_secret"`
+	CloudID      string `yaml:"cloud_id"                mapstructure:"cloud_id"`
+	RefreshToken string `yaml:"refresh_token"           mapstructure:"refresh_token"`
 }
 
+// Flavors of Atlassian site.
+const (
+	FlavorCloud      = "cloud"
+	FlavorDataCenter = "datacenter"
+)
+
+// ProfileEnv names the environment variable that selects a profile when
+// --profile isn't given.
+const ProfileEnv = "A_CLI_PROFILE"
+
+// envOverrides maps environment variables to the settings they replace.
+var envOverrides = []struct {
+	name string
+	set  func(*Config, string)
+}{
+	{"JIRA_URL", func(c *Config, v string) { c.URL = v }},
+	{"JIRA_EMAIL", func(c *Config, v string) { c.Email = v }},
+	{"JIRA_TOKEN", func(c *Config, v string) { c.Token = v }},
+	{"A_CLI_TIMEOUT", func(c *Config, v string) { c.Timeout = v }},
+	{"A_CLI_FLAVOR", func(c *Config, v string) { c.Flavor = v }},
+}
+
+// DefaultTimeout is the request timeout used when none is configured.
+const DefaultTimeout = 60 * time.Second
+
 // DefaultPath returns the default config file path (~/.a-cli.yaml).
 // Also checks for legacy ~/.jira-cli.yaml if the new path doesn't exist.
 func DefaultPath() string {
@@ -38,6 +115,33 @@ func DefaultPath() string {
 // Load reads config from the YAML file and applies env var overrides.
 // configPath may be empty to use the default path.
 func Load(configPath string) (Config, error) {
+	return LoadProfile(configPath, "")
+}
+
+// LoadProfile is like Load, but with the named profile's settings in place
+// of the top-level ones. Env vars still take priority. An empty profile
+// loads the top-level settings.
+func LoadProfile(configPath, profile string) (Config, error) {
+	cfg, err := ReadFile(configPath)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if cfg, err = cfg.Select(profile); err != nil {
+		return Config{}, err
+	}
+
+	for _, env := range envOverrides {
+		if v := os.Getenv(env.name); v != "" {
+			env.set(&cfg, v)
+		}
+	}
+	return cfg, nil
+}
+
+// ReadFile reads the config file as is, without env var overrides or a
+// profile applied, for editing it.
+func ReadFile(configPath string) (Config, error) {
 	v := viper.New()
 
 	if configPath == "" {
@@ -47,11 +151,6 @@ func Load(configPath string) (Config, error) {
 	v.SetConfigFile(configPath)
 	v.SetConfigType("yaml")
 
-	// Env var overrides
-	v.BindEnv("url", "JIRA_URL")
-	v.BindEnv("email", "JIRA_EMAIL")
-	v.BindEnv("token", "JIRA_TOKEN")
-
 	// Read the config file (ignore "not found" errors so env vars still work)
 	if err := v.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
@@ -70,20 +169,128 @@ func Load(configPath string) (Config, error) {
 	return cfg, nil
 }
 
+// Select returns the settings of the named profile, with the ones it
+// leaves empty taken from c. An empty name returns c.
+func (c Config) Select(profile string) (Config, error) {
+	if profile == "" {
+		return c, nil
+	}
+	name := strings.ToLower(profile)
+	p, ok := c.Profiles[name]
+	if !ok {
+		return Config{}, fmt.Errorf("no profile %q in the config file%s", profile, c.profileList())
+	}
+	c = c.withProfile(p)
+	c.Profile = name
+	return c, nil
+}
+
+// withProfile returns c with the settings p sets replacing its own.
+func (c Config) withProfile(p Config) Config {
+	if p.URL != "" {
+		c.URL = p.URL
+	}
+	if p.Email != "" {
+		c.Email = p.Email
+	}
+	// A profile's own way of signing in replaces every inherited one, so an
+	// inherited OAuth grant never sends its requests to another site.
+	if p.Token != "" || p.TokenCommand != "" {
+		c.Token, c.TokenCommand = p.Token, p.TokenCommand
+		c.OAuth = nil
+	}
+	if p.Flavor != "" {
+		c.Flavor = p.Flavor
+	}
+	if p.ConfluenceURL != "" {
+		c.ConfluenceURL = p.ConfluenceURL
+	}
+	if p.ConfluenceToken != "" {
+		c.ConfluenceToken = p.ConfluenceToken
+	}
+	if p.OAuth != nil {
+		c.OAuth = p.OAuth
+		c.Token, c.TokenCommand = "", ""
+	}
+	if p.Timeout != "" {
+		c.Timeout = p.Timeout
+	}
+	if p.Fields != nil {
+		c.Fields = p.Fields
+	}
+	return c
+}
+
+// profileList returns "; profiles: a, b" for error messages, or "" when
+// there are none.
+func (c Config) profileList() string {
+	if len(c.Profiles) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "; profiles: " + strings.Join(names, ", ")
+}
+
 // Validate checks that required fields are present.
 func (c Config) Validate() error {
 	if c.URL == "" {
 		return fmt.Errorf("JIRA URL is required (set in config file or JIRA_URL env var)")
 	}
-	if c.Email == "" {
-		return fmt.Errorf("JIRA email is required (set in config file or JIRA_EMAIL env var)")
+	if c.Flavor != "" && c.Flavor != FlavorCloud && c.Flavor != FlavorDataCenter {
+		return fmt.Errorf("invalid flavor %q (use %s or %s)", c.Flavor, FlavorCloud, FlavorDataCenter)
+	}
+	if c.OAuth != nil {
+		if err := c.validateOAuth(); err != nil {
+			return err
+		}
+	} else {
+		if c.Email == "" && !c.DataCenter() {
+			return fmt.Errorf("JIRA email is required (set in config file or JIRA_EMAIL env var)")
+		}
+		if c.Token == "" && c.TokenCommand == "" {
+			return fmt.Errorf("JIRA token is required (set token or token_command in config file, or JIRA_TOKEN env var)")
+		}
+	}
+	if _, err := c.RequestTimeout(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c Config) validateOAuth() error {
+	if c.DataCenter() {
+		return fmt.Errorf("OAuth is only supported for Cloud sites")
 	}
-	if c.Token == "" {
-		return fmt.Errorf("JIRA token is required (set in config file or JIRA_TOKEN env var)")
+	if c.OAuth.ClientID == "" || c.OAuth.CloudID == "" || c.OAuth.RefreshToken == "" {
+		return fmt.Errorf("OAuth client_id, cloud_id and refresh_token are required (run 'a-cli config --oauth')")
 	}
 	return nil
 }
 
+// DataCenter reports whether the site is Data Center or Server.
+func (c Config) DataCenter() bool {
+	return c.Flavor == FlavorDataCenter
+}
+
+// RequestTimeout returns the parsed Timeout, or DefaultTimeout when unset.
+func (c Config) RequestTimeout() (time.Duration, error) {
+	if c.Timeout == "" {
+		return DefaultTimeout, nil
+	}
+	if c.Timeout == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.Timeout)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid timeout %q (use a duration like 30s or 2m, or 0 for none)", c.Timeout)
+	}
+	return d, nil
+}
+
 // Save writes the config to the given path (or default path if empty).
 func Save(cfg Config, configPath string) error {
 	if configPath == "" {
@@ -101,3 +308,25 @@ func Save(cfg Config, configPath string) error {
 
 	return nil
 }
+
+// SaveRefreshToken replaces the OAuth refresh token in the config file,
+// leaving everything else as it is. The named profile's token is replaced
+// when it has OAuth settings of its own; otherwise the top-level one is.
+// Atlassian rotates refresh tokens, so each new one must be kept.
+func SaveRefreshToken(configPath, profile, token string) error {
+	cfg, err := ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	if p, ok := cfg.Profiles[profile]; ok && p.OAuth != nil {
+		oauth := *p.OAuth
+		oauth.RefreshToken = token
+		p.OAuth = &oauth
+		cfg.Profiles[profile] = p
+	} else if cfg.OAuth != nil {
+		cfg.OAuth.RefreshToken = token
+	} else {
+		return fmt.Errorf("no OAuth settings in the config file")
+	}
+	return Save(cfg, configPath)
+}
@@ -1,10 +1,13 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestValidate_AllPresent(t *testing.T) {
@@ -35,6 +38,121 @@ func TestValidate_MissingToken(t *testing.T) {
 	}
 }
 
+func TestValidate_DataCenter(t *testing.T) {
+	cfg := Config{URL: "https://jira.example.com", Token: "pat", Flavor: FlavorDataCenter}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected Data Center without email to be valid, got: %v", err)
+	}
+
+	cfg.Flavor = "server"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "invalid flavor") {
+		t.Errorf("expected invalid flavor error, got: %v", err)
+	}
+}
+
+func TestValidate_OAuth(t *testing.T) {
+	cfg := Config{URL: "https://example.atlassian.net", OAuth: &OAuth{ClientID: "id", CloudID: "cloud", RefreshToken: "refresh"}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected OAuth without email and token to be valid, got: %v", err)
+	}
+
+	cfg.OAuth = &OAuth{ClientID: "id", CloudID: "cloud"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "config --oauth") {
+		t.Errorf("expected a missing refresh token error, got: %v", err)
+	}
+
+	cfg = Config{URL: "https://jira.example.com", Flavor: FlavorDataCenter, OAuth: &OAuth{ClientID: "id", CloudID: "cloud", RefreshToken: "refresh"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "only supported for Cloud") {
+		t.Errorf("expected OAuth to be refused on Data Center, got: %v", err)
+	}
+}
+
+func TestSaveRefreshToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test-config.yaml")
+	original := Config{
+		URL:   "https://prod.atlassian.net",
+		OAuth: &OAuth{ClientID: "id", CloudID: "prod", RefreshToken: "prod-1"},
+		Profiles: map[string]Config{
+			"sandbox": {URL: "https://sandbox.atlassian.net", OAuth: &OAuth{ClientID: "id", CloudID: "sandbox", RefreshToken: "sandbox-1"}},
+			"staging": {URL: "https://staging.atlassian.net"},
+		},
+	}
+	if err := Save(original, path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if err := SaveRefreshToken(path, "sandbox", "sandbox-2"); err != nil {
+		t.Fatal(err)
+	}
+	// A profile without OAuth settings of its own uses the top-level ones.
+	if err := SaveRefreshToken(path, "staging", "prod-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.OAuth.RefreshToken != "prod-2" || loaded.OAuth.CloudID != "prod" {
+		t.Errorf("unexpected top-level OAuth settings: %+v", loaded.OAuth)
+	}
+	if sandbox := loaded.Profiles["sandbox"].OAuth; sandbox.RefreshToken != "sandbox-2" || sandbox.CloudID != "sandbox" {
+		t.Errorf("unexpected sandbox OAuth settings: %+v", sandbox)
+	}
+	if loaded.Profiles["staging"].OAuth != nil {
+		t.Errorf("expected staging to keep inheriting, got %+v", loaded.Profiles["staging"].OAuth)
+	}
+}
+
+func TestCommandToken(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	runs := filepath.Join(t.TempDir(), "runs")
+	command := fmt.Sprintf("echo run >> %s; printf ' secret-token \\nlogin: me\\n'", runs)
+
+	for i := 0; i < 2; i++ {
+		token, err := CommandToken(command)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "secret-token" {
+			t.Errorf("expected the first line, trimmed, got %q", token)
+		}
+	}
+	if data, _ := os.ReadFile(runs); string(data) != "run\n" {
+		t.Errorf("expected the command to run once, got %q", data)
+	}
+
+	for command, want := range map[string]string{
+		"echo leaked; exit 2": "failed with exit code 2",
+		"true":                "printed no token",
+	} {
+		_, err := CommandToken(command)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected %q, got %v", command, want, err)
+		}
+		if err != nil && strings.Contains(err.Error(), "leaked") && !strings.Contains(command, "leaked") {
+			t.Errorf("%s: output echoed in %v", command, err)
+		}
+	}
+}
+
+func TestValidate_TokenCommand(t *testing.T) {
+	cfg := Config{URL: "https://example.atlassian.net", Email: "a@b.com", TokenCommand: "pass show atlassian"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected a token command to stand in for the token, got: %v", err)
+	}
+
+	// A profile's token command replaces an inherited token, and vice versa.
+	top := Config{Token: "top-token", Profiles: map[string]Config{
+		"vault": {TokenCommand: "op read op://vault/atlassian"},
+	}}
+	if vault, _ := top.Select("vault"); vault.Token != "" || vault.TokenCommand != "op read op://vault/atlassian" {
+		t.Errorf("unexpected profile token settings: %+v", vault)
+	}
+}
+
 func TestSaveAndLoad(t *testing.T) {
 	// Clear env vars so they don't override file values
 	t.Setenv("JIRA_URL", "")
@@ -81,6 +199,37 @@ func TestSaveAndLoad(t *testing.T) {
 	}
 }
 
+func TestSaveAndLoad_Fields(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test-config.yaml")
+
+	original := Config{
+		URL:   "https://example.atlassian.net",
+		Email: "user@example.com",
+		Token: "secret-token",
+		Fields: map[string]string{
+			"story_points": "customfield_10016",
+			"Team":         "Team",
+		},
+	}
+	if err := Save(original, path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if loaded.Fields["story_points"] != "customfield_10016" {
+		t.Errorf("expected story_points mapping, got %v", loaded.Fields)
+	}
+	// Viper keys are case-insensitive and come back lowercased
+	if loaded.Fields["team"] != "Team" {
+		t.Errorf("expected lowercased team mapping, got %v", loaded.Fields)
+	}
+}
+
 func TestLoad_EnvVarOverride(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "test-config.yaml")
@@ -107,6 +256,104 @@ func TestLoad_EnvVarOverride(t *testing.T) {
 	}
 }
 
+func TestLoadProfile(t *testing.T) {
+	t.Setenv("JIRA_URL", "")
+	t.Setenv("JIRA_EMAIL", "")
+	t.Setenv("JIRA_TOKEN", "")
+
+	path := filepath.Join(t.TempDir(), "test-config.yaml")
+	original := Config{
+		URL:    "https://prod.atlassian.net",
+		Email:  "user@example.com",
+		Token:  "prod-token",
+		Fields: map[string]string{"points": "customfield_10016"},
+		Profiles: map[string]Config{
+			"Sandbox": {URL: "https://sandbox.atlassian.net", Token: "sandbox-token"},
+		},
+	}
+	if err := Save(original, path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	top, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if top.URL != "https://prod.atlassian.net" || top.Profile != "" {
+		t.Errorf("expected the top-level settings, got %+v", top)
+	}
+
+	// Profile names are case-insensitive.
+	sandbox, err := LoadProfile(path, "SANDBOX")
+	if err != nil {
+		t.Fatalf("LoadProfile failed: %v", err)
+	}
+	if sandbox.URL != "https://sandbox.atlassian.net" || sandbox.Token != "sandbox-token" || sandbox.Profile != "sandbox" {
+		t.Errorf("expected the sandbox settings, got %+v", sandbox)
+	}
+	if sandbox.Email != "user@example.com" || sandbox.Fields["points"] != "customfield_10016" {
+		t.Errorf("expected unset settings to be inherited, got %+v", sandbox)
+	}
+
+	t.Setenv("JIRA_TOKEN", "env-token")
+	sandbox, err = LoadProfile(path, "sandbox")
+	if err != nil {
+		t.Fatalf("LoadProfile failed: %v", err)
+	}
+	if sandbox.Token != "env-token" {
+		t.Errorf("expected the env var to override the profile, got %s", sandbox.Token)
+	}
+
+	if _, err := LoadProfile(path, "staging"); err == nil || !strings.Contains(err.Error(), "profiles: sandbox") {
+		t.Errorf("expected an unknown profile error listing the profiles, got %v", err)
+	}
+}
+
+func TestLoadProfile_ReplacesSignIn(t *testing.T) {
+	t.Setenv("JIRA_URL", "")
+	t.Setenv("JIRA_EMAIL", "")
+	t.Setenv("JIRA_TOKEN", "")
+
+	path := filepath.Join(t.TempDir(), "test-config.yaml")
+	original := Config{
+		URL:   "https://prod.atlassian.net",
+		OAuth: &OAuth{ClientID: "client", CloudID: "prod-cloud", RefreshToken: "refresh"},
+		Profiles: map[string]Config{
+			"sandbox": {URL: "https://sandbox.atlassian.net", Email: "me@example.com", Token: "sandbox-token"},
+			"helper":  {URL: "https://helper.atlassian.net", Email: "me@example.com", TokenCommand: "pass show jira"},
+			"staging": {URL: "https://staging.atlassian.net", OAuth: &OAuth{ClientID: "client", CloudID: "staging-cloud", RefreshToken: "r2"}},
+		},
+	}
+	if err := Save(original, path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// A profile signing in with a token doesn't inherit the OAuth grant
+	for _, name := range []string{"sandbox", "helper"} {
+		cfg, err := LoadProfile(path, name)
+		if err != nil {
+			t.Fatalf("LoadProfile(%s) failed: %v", name, err)
+		}
+		if cfg.OAuth != nil {
+			t.Errorf("%s: expected the inherited OAuth grant to be dropped, got %+v", name, cfg.OAuth)
+		}
+	}
+
+	// and the reverse: a profile's own OAuth grant drops inherited tokens
+	original.OAuth = nil
+	original.Email, original.Token = "me@example.com", "prod-token"
+	if err := Save(original, path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	staging, err := LoadProfile(path, "staging")
+	if err != nil {
+		t.Fatalf("LoadProfile failed: %v", err)
+	}
+	if staging.Token != "" || staging.OAuth == nil || staging.OAuth.CloudID != "staging-cloud" {
+		t.Errorf("expected only the profile's OAuth grant, got token %q, %+v", staging.Token, staging.OAuth)
+	}
+}
+
 func TestLoad_NoFile_EnvOnly(t *testing.T) {
 	t.Setenv("JIRA_URL", "https://envonly.atlassian.net")
 	t.Setenv("JIRA_EMAIL", "envonly@example.com")
@@ -121,3 +368,28 @@ func TestLoad_NoFile_EnvOnly(t *testing.T) {
 		t.Errorf("expected env URL, got %s", loaded.URL)
 	}
 }
+
+func TestRequestTimeout(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", DefaultTimeout, false},
+		{"0", 0, false},
+		{"30s", 30 * time.Second, false},
+		{"2m", 2 * time.Minute, false},
+		{"soon", 0, true},
+		{"-5s", 0, true},
+	}
+	for _, tt := range tests {
+		cfg := Config{URL: "https://example.atlassian.net", Email: "a@b.com", Token: "tok", Timeout: tt.value}
+		got, err := cfg.RequestTimeout()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("RequestTimeout(%q) = %v, %v", tt.value, got, err)
+		}
+		if (cfg.Validate() != nil) != tt.wantErr {
+			t.Errorf("Validate with timeout %q: expected error %v", tt.value, tt.wantErr)
+		}
+	}
+}
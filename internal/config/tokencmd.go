@@ -0,0 +1,60 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// commandTokens caches the output of each token command for the life of the
+// process, so a password manager is asked once per run.
+var commandTokens = struct {
+	sync.Mutex
+	byCommand map[string]string
+}{byCommand: map[string]string{}}
+
+// CommandToken runs command with the shell and returns the first line of
+// its output as the token. The command's stderr and stdin are the
+// terminal's, so it can prompt for a passphrase. The output never appears
+// in errors.
+func CommandToken(command string) (string, error) {
+	commandTokens.Lock()
+	defer commandTokens.Unlock()
+	if token, ok := commandTokens.byCommand[command]; ok {
+		return token, nil
+	}
+
+	var stdout bytes.Buffer
+	cmd := shellCommand(command)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("token_command %q failed with exit code %d", command, exitErr.ExitCode())
+		}
+		return "", fmt.Errorf("running token_command %q: %w", command, err)
+	}
+
+	// Like pass, password managers may print more after the secret's line.
+	token, _, _ := strings.Cut(stdout.String(), "\n")
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", fmt.Errorf("token_command %q printed no token", command)
+	}
+	commandTokens.byCommand[command] = token
+	return token, nil
+}
+
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
@@ -0,0 +1,115 @@
+package dateparse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JIRA's default time tracking settings: a working day is 8 hours and a
+// working week is 5 days.
+const (
+	secondsPerMinute = 60
+	secondsPerHour   = 60 * secondsPerMinute
+	secondsPerDay    = 8 * secondsPerHour
+	secondsPerWeek   = 5 * secondsPerDay
+)
+
+var durationPartPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*(w|d|h|m)`)
+
+// ParseDuration converts a JIRA-style duration such as "2h30m", "1d 4h",
+// "90m" or "1.5h" into seconds. Days and weeks are working days (8h) and
+// working weeks (5d), as in JIRA's default time tracking settings.
+func ParseDuration(expr string) (int, error) {
+	normalized := strings.ToLower(strings.TrimSpace(expr))
+	if normalized == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	matches := durationPartPattern.FindAllStringSubmatchIndex(normalized, -1)
+	if matches == nil {
+		return 0, fmt.Errorf("invalid duration %q (use e.g. 2h30m, 1d, 45m)", expr)
+	}
+
+	total := 0.0
+	pos := 0
+	for _, m := range matches {
+		// Only whitespace may separate the parts
+		if strings.TrimSpace(normalized[pos:m[0]]) != "" {
+			return 0, fmt.Errorf("invalid duration %q (use e.g. 2h30m, 1d, 45m)", expr)
+		}
+		pos = m[1]
+
+		n, err := strconv.ParseFloat(normalized[m[2]:m[3]], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", expr, err)
+		}
+		switch normalized[m[4]:m[5]] {
+		case "w":
+			total += n * secondsPerWeek
+		case "d":
+			total += n * secondsPerDay
+		case "h":
+			total += n * secondsPerHour
+		case "m":
+			total += n * secondsPerMinute
+		}
+	}
+	if strings.TrimSpace(normalized[pos:]) != "" {
+		return 0, fmt.Errorf("invalid duration %q (use e.g. 2h30m, 1d, 45m)", expr)
+	}
+
+	seconds := int(total)
+	if seconds < secondsPerMinute {
+		return 0, fmt.Errorf("duration %q is less than a minute", expr)
+	}
+	return seconds, nil
+}
+
+// FormatDuration formats seconds as hours and minutes, e.g. "2h 30m" or "45m".
+// Hours are not rolled up into days so totals read unambiguously.
+func FormatDuration(seconds int) string {
+	minutes := seconds / secondsPerMinute
+	h, m := minutes/60, minutes%60
+	switch {
+	case h == 0:
+		return fmt.Sprintf("%dm", m)
+	case m == 0:
+		return fmt.Sprintf("%dh", h)
+	default:
+		return fmt.Sprintf("%dh %dm", h, m)
+	}
+}
+
+// ParseStartTime converts an expression for when work started into a time:
+// "now" or "" (the current time), "today" (now), "yesterday" (this time
+// yesterday), an ISO date (09:00 local time that day), "2006-01-02 15:04",
+// or RFC 3339.
+func ParseStartTime(expr string, now time.Time) (time.Time, error) {
+	normalized := strings.ToLower(strings.TrimSpace(expr))
+
+	switch normalized {
+	case "", "now", "today":
+		return now, nil
+	case "yesterday":
+		return now.AddDate(0, 0, -1), nil
+	}
+
+	if isoDatePattern.MatchString(normalized) {
+		d, err := time.ParseInLocation("2006-01-02", normalized, now.Location())
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q: %w", expr, err)
+		}
+		return d.Add(9 * time.Hour), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", strings.TrimSpace(expr), now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, strings.TrimSpace(expr)); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized start time %q (use now, yesterday, 2025-01-15 or \"2025-01-15 14:00\")", expr)
+}
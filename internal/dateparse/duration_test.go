@@ -0,0 +1,108 @@
+package dateparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		expr string
+		want int
+	}{
+		{"45m", 45 * 60},
+		{"2h", 2 * 3600},
+		{"1d", 8 * 3600},
+		{"1w", 5 * 8 * 3600},
+		{"1.5h", 90 * 60},
+		{"2h30m", 150 * 60},
+		{"1d 4h", 12 * 3600},
+		{"1w 2d 3h 30m", (5+2)*8*3600 + 3*3600 + 30*60},
+		{" 2H 30M ", 150 * 60},
+		{"1 h", 3600},
+	}
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.expr)
+		if err != nil {
+			t.Errorf("ParseDuration(%q): %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDuration(%q) = %d, want %d", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseDuration_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"   ",
+		"2",      // no unit
+		"30s",    // seconds aren't a unit
+		"h",      // no number
+		"2x",     // unknown unit
+		"2h foo", // trailing text
+		"about 2h",
+		"0m",   // zero
+		"0.5m", // less than a minute
+	} {
+		if got, err := ParseDuration(expr); err == nil {
+			t.Errorf("ParseDuration(%q) = %d, expected an error", expr, got)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    string
+	}{
+		{0, "0m"},
+		{59, "0m"}, // seconds are dropped, not rounded
+		{89, "1m"},
+		{45 * 60, "45m"},
+		{3600, "1h"},
+		{3599, "59m"},
+		{150 * 60, "2h 30m"},
+		{30 * 3600, "30h"}, // not rolled up into days
+	}
+	for _, tt := range tests {
+		if got := FormatDuration(tt.seconds); got != tt.want {
+			t.Errorf("FormatDuration(%d) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestParseStartTime(t *testing.T) {
+	loc := time.FixedZone("CET", 3600)
+	now := time.Date(2025, 1, 15, 14, 30, 0, 0, loc)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"", now},
+		{"now", now},
+		{"Today", now},
+		{"yesterday", time.Date(2025, 1, 14, 14, 30, 0, 0, loc)},
+		{"2025-01-10", time.Date(2025, 1, 10, 9, 0, 0, 0, loc)},
+		{"2025-01-10 16:45", time.Date(2025, 1, 10, 16, 45, 0, 0, loc)},
+		{"2025-01-10T16:45:00Z", time.Date(2025, 1, 10, 16, 45, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseStartTime(tt.expr, now)
+		if err != nil {
+			t.Errorf("ParseStartTime(%q): %v", tt.expr, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseStartTime(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"2025-13-01", "last week", "14:00"} {
+		if _, err := ParseStartTime(expr, now); err == nil {
+			t.Errorf("ParseStartTime(%q): expected an error", expr)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package jira
+
+import (
+	"context"
+	"io"
+)
+
+// JIRA is the set of JIRA operations commands use. *Client implements it;
+// commands take the interface so they can be exercised against a fake.
+type JIRA interface {
+	GetIssue(ctx context.Context, key string, extraFields ...string) (*Issue, error)
+	UpdateIssue(ctx context.Context, key string, payload UpdatePayload) error
+	CreateIssue(ctx context.Context, payload CreatePayload) (*CreatedIssue, error)
+	FindUsers(ctx context.Context, query string) ([]User, error)
+	Myself(ctx context.Context) (*User, error)
+	MyPermissions(ctx context.Context, projectKey string, permissions ...string) (map[string]bool, error)
+
+	AddComment(ctx context.Context, key string, body *ADFNode) (*Comment, error)
+	UpdateComment(ctx context.Context, key string, commentID string, body *ADFNode) error
+	DeleteComment(ctx context.Context, key string, commentID string) error
+
+	GetFields(ctx context.Context) ([]FieldInfo, error)
+	GetProjectComponents(ctx context.Context, projectKey string) ([]Component, error)
+	GetProjectVersions(ctx context.Context, projectKey string) ([]Version, error)
+
+	GetIssueLinkTypes(ctx context.Context) ([]IssueLinkType, error)
+	CreateIssueLink(ctx context.Context, link IssueLink) error
+	DeleteIssueLink(ctx context.Context, linkID string) error
+
+	DownloadAttachment(ctx context.Context, attachmentID string, w io.Writer) error
+	AddAttachment(ctx context.Context, key string, filename string, content io.Reader) ([]Attachment, error)
+
+	GetWorklogs(ctx context.Context, key string) ([]Worklog, error)
+	AddWorklog(ctx context.Context, key string, payload WorklogPayload) (*Worklog, error)
+	GetChangelog(ctx context.Context, key string) ([]ChangelogEntry, error)
+
+	GetProjectWorkflowScheme(ctx context.Context, projectID string) (*WorkflowScheme, error)
+	GetWorkflow(ctx context.Context, name string) (*Workflow, error)
+	GetTransitions(ctx context.Context, key string) ([]TransitionInfo, error)
+	DoTransition(ctx context.Context, key string, transitionID string, fields map[string]any) error
+
+	SearchIssues(ctx context.Context, jql string, limit int) (*SearchResult, error)
+	SearchIssuesPages(ctx context.Context, jql string, limit int, fn func(page *SearchResult) error) error
+	ApproximateIssueCount(ctx context.Context, jql string) (int, error)
+}
+
+// Confluence is the set of Confluence operations commands use. *Client
+// implements it.
+type Confluence interface {
+	GetConfluencePage(ctx context.Context, pageID string) (*ConfluencePage, error)
+	GetConfluenceSpace(ctx context.Context, spaceID string) (*ConfluenceSpace, error)
+	GetConfluenceSpaceByKey(ctx context.Context, spaceKey string) (*ConfluenceSpace, error)
+	ListConfluenceSpaces(ctx context.Context, limit int) ([]ConfluenceSpace, error)
+	GetConfluenceSpaceOperations(ctx context.Context, spaceKey string) ([]ConfluenceOperation, error)
+	CreateConfluencePage(ctx context.Context, payload ConfluenceCreatePayload) (*ConfluencePage, error)
+	UpdateConfluencePage(ctx context.Context, pageID string, payload ConfluenceUpdatePayload) error
+
+	GetConfluenceChildPages(ctx context.Context, pageID string) ([]ConfluenceChildPage, error)
+	GetConfluenceFooterComments(ctx context.Context, pageID string) ([]ConfluenceComment, error)
+	GetConfluenceInlineComments(ctx context.Context, pageID string) ([]ConfluenceComment, error)
+
+	SearchConfluence(ctx context.Context, cql string, limit int) (*ConfluenceSearchResult, error)
+	SearchConfluencePages(ctx context.Context, cql string, limit int, fn func(page *ConfluenceSearchResult) error) error
+}
+
+var (
+	_ JIRA       = (*Client)(nil)
+	_ Confluence = (*Client)(nil)
+)
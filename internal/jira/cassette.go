@@ -0,0 +1,345 @@
+package jira
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mreider/a-cli/internal/config"
+)
+
+// cassetteInfoFile holds a cassette's Cassette fields. Every exchange is
+// stored next to it in a numbered file (0001.json, 0002.json, ...).
+const cassetteInfoFile = "cassette.json"
+
+// redactedHeaders are replaced with "REDACTED" before an exchange is saved.
+var redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// A Cassette is a directory of recorded HTTP exchanges. A client recording
+// to a cassette saves every request it sends and the response it got;
+// a client replaying one answers requests from the saved responses without
+// touching the network.
+type Cassette struct {
+	// URL is the site the recording was made against.
+	URL string `json:"url"`
+
+	// Profile is the config profile the recording was made with.
+	Profile string `json:"profile,omitempty"`
+
+	// Recorded is when the recording started. Pulled files made during the
+	// recording and when replaying it use it as their synced time.
+	Recorded time.Time `json:"recorded"`
+
+	// Fields is the custom field map from the recording config.
+	Fields map[string]string `json:"fields,omitempty"`
+
+	// Flavor and ConfluenceURL are the site flavor and, on Data Center, the
+	// Confluence site the recording was made against.
+	Flavor        string `json:"flavor,omitempty"`
+	ConfluenceURL string `json:"confluence_url,omitempty"`
+
+	// CloudID is the site's cloud id when the recording was made with
+	// OAuth, whose requests go through the api.atlassian.com gateway.
+	CloudID string `json:"cloud_id,omitempty"`
+
+	dir string
+
+	mu        sync.Mutex
+	saved     int
+	exchanges []*exchange
+}
+
+// exchange is one recorded request and its response, or the error returned
+// in place of a response.
+type exchange struct {
+	Request  recordedRequest   `json:"request"`
+	Response *recordedResponse `json:"response,omitempty"`
+	Error    string            `json:"error,omitempty"`
+
+	replayed bool
+}
+
+type recordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	recordedBody
+}
+
+type recordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	recordedBody
+}
+
+// recordedBody is a message body, kept as text when it is UTF-8 so cassettes
+// can be read and edited, and as base64 otherwise.
+type recordedBody struct {
+	Body   string `json:"body,omitempty"`
+	Base64 bool   `json:"base64,omitempty"`
+}
+
+func newRecordedBody(data []byte) recordedBody {
+	if utf8.Valid(data) {
+		return recordedBody{Body: string(data)}
+	}
+	return recordedBody{Body: base64.StdEncoding.EncodeToString(data), Base64: true}
+}
+
+func (b recordedBody) bytes() ([]byte, error) {
+	if b.Base64 {
+		return base64.StdEncoding.DecodeString(b.Body)
+	}
+	return []byte(b.Body), nil
+}
+
+// NewCassette creates a cassette in dir for recording requests to the site
+// in cfg. dir is created if needed; it must not already hold a recording.
+func NewCassette(dir string, cfg config.Config, recorded time.Time) (*Cassette, error) {
+	if _, err := os.Stat(filepath.Join(dir, cassetteInfoFile)); err == nil {
+		return nil, fmt.Errorf("%s already holds a recording; record to an empty directory", dir)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating cassette directory: %w", err)
+	}
+
+	c := &Cassette{
+		URL:           cfg.URL,
+		Profile:       cfg.Profile,
+		Recorded:      recorded,
+		Fields:        cfg.Fields,
+		Flavor:        cfg.Flavor,
+		ConfluenceURL: cfg.ConfluenceURL,
+		dir:           dir,
+	}
+	if cfg.OAuth != nil {
+		c.CloudID = cfg.OAuth.CloudID
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, cassetteInfoFile), append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("writing cassette: %w", err)
+	}
+	return c, nil
+}
+
+// OpenCassette loads the cassette recorded in dir for replaying.
+func OpenCassette(dir string) (*Cassette, error) {
+	data, err := os.ReadFile(filepath.Join(dir, cassetteInfoFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s is not a recording (no %s)", dir, cassetteInfoFile)
+		}
+		return nil, fmt.Errorf("reading cassette: %w", err)
+	}
+	c := &Cassette{dir: dir}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", filepath.Join(dir, cassetteInfoFile), err)
+	}
+
+	names, err := filepath.Glob(filepath.Join(dir, "[0-9]*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("reading cassette: %w", err)
+		}
+		var e exchange
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", name, err)
+		}
+		c.exchanges = append(c.exchanges, &e)
+	}
+	return c, nil
+}
+
+// Record returns an Option that saves every exchange the client makes to
+// the cassette.
+func (c *Cassette) Record() Option {
+	return WithTransport(func(base http.RoundTripper) http.RoundTripper {
+		return &recorder{cassette: c, base: base}
+	})
+}
+
+// Replay returns an Option that answers every request from the cassette.
+// Each recorded exchange is used once, in the order it was recorded; a
+// request with no exchange left fails.
+func (c *Cassette) Replay() Option {
+	return WithTransport(func(http.RoundTripper) http.RoundTripper {
+		return replayer{c}
+	})
+}
+
+// save writes e to the next numbered file.
+func (c *Cassette) save(e *exchange) error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.saved++
+	return os.WriteFile(filepath.Join(c.dir, fmt.Sprintf("%04d.json", c.saved)), append(data, '\n'), 0644)
+}
+
+// take returns the first unreplayed exchange for the request, preferring
+// one whose body matches, and marks it replayed.
+func (c *Cassette) take(method, url string, body []byte) *exchange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var match *exchange
+	for _, e := range c.exchanges {
+		if e.replayed || e.Request.Method != method || e.Request.URL != url {
+			continue
+		}
+		if recorded, err := e.Request.bytes(); err == nil && bytes.Equal(recorded, body) {
+			match = e
+			break
+		}
+		if match == nil {
+			match = e
+		}
+	}
+	if match != nil {
+		match.replayed = true
+	}
+	return match
+}
+
+type recorder struct {
+	cassette *Cassette
+	base     http.RoundTripper
+}
+
+func (t *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &exchange{Request: recordedRequest{
+		Method:       req.Method,
+		URL:          redactQuery(req.URL),
+		Header:       redact(req.Header),
+		recordedBody: newRecordedBody(body),
+	}}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		e.Error = err.Error()
+	} else {
+		data, err := readBody(&resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		e.Response = &recordedResponse{Status: resp.StatusCode, Header: redact(resp.Header), recordedBody: newRecordedBody(redactLocation(resp.Header, data))}
+	}
+
+	if saveErr := t.cassette.save(e); saveErr != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, fmt.Errorf("recording request: %w", saveErr)
+	}
+	return resp, err
+}
+
+type replayer struct {
+	cassette *Cassette
+}
+
+func (t replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	e := t.cassette.take(req.Method, redactQuery(req.URL), body)
+	if e == nil {
+		return nil, fmt.Errorf("no recorded response for %s %s in %s", req.Method, req.URL, t.cassette.dir)
+	}
+	if e.Response == nil {
+		return nil, errors.New(e.Error)
+	}
+
+	data, err := e.Response.bytes()
+	if err != nil {
+		return nil, fmt.Errorf("replaying %s %s: %w", req.Method, req.URL, err)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Response.Status, http.StatusText(e.Response.Status)),
+		StatusCode:    e.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Response.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
+
+// readBody reads *body in full and replaces it with a reader over the same
+// bytes, so the request or response can still be sent or read.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	data, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+// redactLocation replaces the redirect location in h, which servers often
+// repeat in a redirect's body, with its redacted form.
+func redactLocation(h http.Header, body []byte) []byte {
+	loc := h.Get("Location")
+	u, err := url.Parse(loc)
+	if loc == "" || err != nil || redactQuery(u) == loc {
+		return body
+	}
+	body = bytes.ReplaceAll(body, []byte(loc), []byte(redactQuery(u)))
+	return bytes.ReplaceAll(body, []byte(html.EscapeString(loc)), []byte(html.EscapeString(redactQuery(u))))
+}
+
+// redact returns a copy of h with credentials and cookies replaced, and
+// token-like query parameters in redirect locations replaced.
+func redact(h http.Header) http.Header {
+	h = h.Clone()
+	for name, values := range h {
+		for _, secret := range redactedHeaders {
+			if strings.EqualFold(name, secret) {
+				h[name] = []string{"REDACTED"}
+			}
+		}
+		if strings.EqualFold(name, "Location") {
+			for i, v := range values {
+				if u, err := url.Parse(v); err == nil {
+					values[i] = redactQuery(u)
+				}
+			}
+		}
+	}
+	return h
+}
@@ -0,0 +1,163 @@
+package jira
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mreider/a-cli/internal/config"
+)
+
+func TestCassette_RecordAndReplay(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret-session"})
+		switch r.URL.Path {
+		case "/rest/api/3/issue/PROJ-1":
+			w.Write([]byte(`{"key":"PROJ-1","fields":{"summary":"Recorded"}}`))
+		case "/rest/api/3/attachment/content/1":
+			w.Write([]byte{0xff, 0x00, 0xfe})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errorMessages":["Issue does not exist"]}`))
+		}
+	}))
+	defer srv.Close()
+
+	dir := filepath.Join(t.TempDir(), "cassette")
+	recorded := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	cfg := config.Config{URL: srv.URL, Email: "test@example.com", Token: "test-token", Fields: map[string]string{"points": "customfield_10016"}}
+	cassette, err := NewCassette(dir, cfg, recorded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(cfg, cassette.Record())
+	ctx := context.Background()
+
+	if _, err := client.GetIssue(ctx, "PROJ-1"); err != nil {
+		t.Fatal(err)
+	}
+	var attachment strings.Builder
+	if err := client.DownloadAttachment(ctx, "1", &attachment); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetIssue(ctx, "PROJ-2"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 4 {
+		t.Fatalf("expected cassette.json and 3 exchanges, got %v", files)
+	}
+	for _, name := range files {
+		data, _ := os.ReadFile(name)
+		for _, secret := range []string{"Basic ", "secret-session"} {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("%s contains %q:\n%s", name, secret, data)
+			}
+		}
+	}
+
+	srv.Close()
+	replay, err := OpenCassette(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !replay.Recorded.Equal(recorded) || replay.URL != srv.URL || replay.Fields["points"] != "customfield_10016" {
+		t.Errorf("cassette info not kept: %+v", replay)
+	}
+	client = NewClient(cfg, replay.Replay())
+
+	issue, err := client.GetIssue(ctx, "PROJ-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Fields.Summary != "Recorded" {
+		t.Errorf("expected the recorded issue, got %+v", issue)
+	}
+	var replayed strings.Builder
+	if err := client.DownloadAttachment(ctx, "1", &replayed); err != nil {
+		t.Fatal(err)
+	}
+	if replayed.String() != attachment.String() {
+		t.Errorf("binary body not replayed: %q", replayed.String())
+	}
+	if _, err := client.GetIssue(ctx, "PROJ-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the recorded 404, got %v", err)
+	}
+	if _, err := client.GetIssue(ctx, "PROJ-1"); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("expected each exchange to replay once, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("replay reached the server: %d calls", calls)
+	}
+}
+
+func TestCassette_RedactsQueryTokens(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/rest/api/3/attachment/content/1":
+			http.Redirect(w, r, "/media/file?token=secret-download-token&name=a.txt", http.StatusFound)
+		case "/media/file":
+			w.Write([]byte("attachment"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	dir := filepath.Join(t.TempDir(), "cassette")
+	cfg := config.Config{URL: srv.URL, Email: "test@example.com", Token: "test-token"}
+	cassette, err := NewCassette(dir, cfg, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	var recorded strings.Builder
+	if err := NewClient(cfg, cassette.Record()).DownloadAttachment(ctx, "1", &recorded); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, name := range files {
+		data, _ := os.ReadFile(name)
+		if strings.Contains(string(data), "secret-download-token") {
+			t.Errorf("%s contains the download token:\n%s", name, data)
+		}
+	}
+
+	srv.Close()
+	replay, err := OpenCassette(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var replayed strings.Builder
+	if err := NewClient(cfg, replay.Replay()).DownloadAttachment(ctx, "1", &replayed); err != nil {
+		t.Fatal(err)
+	}
+	if replayed.String() != "attachment" {
+		t.Errorf("expected the recorded attachment, got %q", replayed.String())
+	}
+	if calls != 2 {
+		t.Errorf("replay reached the server: %d calls", calls)
+	}
+}
+
+func TestNewCassette_RefusesExistingRecording(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewCassette(dir, config.Config{URL: "https://example.atlassian.net"}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewCassette(dir, config.Config{URL: "https://example.atlassian.net"}, time.Now()); err == nil {
+		t.Fatal("expected recording over a recording to fail")
+	}
+}
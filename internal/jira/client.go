@@ -2,312 +2,592 @@ package jira
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/mreider/a-cli/internal/config"
+	"github.com/mreider/a-cli/internal/oauth"
 )
 
-// Client is a JIRA REST API v3 client.
+// Client is a JIRA and Confluence REST API client. It talks to Cloud through
+// JIRA API v3 and Confluence API v2, and to Data Center through JIRA API v2
+// and the Confluence v1 content API, converting rich text to and from ADF.
 type Client struct {
-	baseURL    string
+	baseURL    string // Confluence v2 next links are relative to it
+	apiURL     string // JIRA REST API root
+	wikiURL    string // Confluence root
 	authHeader string
+	dataCenter bool
+
+	// wikiAuthHeader authenticates Confluence requests, which on Data Center
+	// may go to a separate site with its own token.
+	wikiAuthHeader string
+
+	// tokens, when set, supplies OAuth access tokens used in place of
+	// authHeader and wikiAuthHeader.
+	tokens TokenSource
+
 	httpClient *http.Client
+	limiter    *rateLimiter
+	retry      retryPolicy
+	sleep      func(context.Context, time.Duration) error
 }
 
-// NewClient creates a new JIRA client from the given config.
-func NewClient(cfg config.Config) *Client {
-	creds := base64.StdEncoding.EncodeToString([]byte(cfg.Email + ":" + cfg.Token))
-	baseURL := strings.TrimRight(cfg.URL, "/")
-	return &Client{
-		baseURL:    baseURL,
-		authHeader: "Basic " + creds,
-		httpClient: &http.Client{},
+// An Option configures a Client.
+type Option func(*Client)
+
+// WithTransport wraps the transport that sends the client's requests over
+// the network. Retries and rate limiting happen above it, so the wrapper
+// sees every attempt.
+func WithTransport(wrap func(http.RoundTripper) http.RoundTripper) Option {
+	return func(c *Client) {
+		c.limiter.base = wrap(c.limiter.base)
 	}
 }
 
-// GetIssue fetches a single issue by key.
-func (c *Client) GetIssue(key string) (*Issue, error) {
-	url := fmt.Sprintf("%s/rest/api/3/issue/%s?fields=summary,status,issuetype,priority,labels,assignee,reporter,description,comment,updated", c.baseURL, key)
+// A TokenSource supplies OAuth access tokens. *oauth.TokenSource refreshes
+// them as they expire.
+type TokenSource interface {
+	AccessToken(ctx context.Context) (string, error)
+}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+// WithTokenSource sets where an OAuth client gets its access tokens, in
+// place of the one NewClient creates from the config's refresh token. It
+// lets clients share one source, so the refresh token is only rotated once.
+func WithTokenSource(ts TokenSource) Option {
+	return func(c *Client) {
+		c.tokens = ts
 	}
-	c.setHeaders(req)
+}
 
-	resp, err := c.httpClient.Do(req)
+// NewClient creates a new JIRA client from the given config. Cloud sites
+// are reached with email and API token, or with OAuth through the
+// api.atlassian.com gateway; Data Center sites with a personal access token.
+func NewClient(cfg config.Config, opts ...Option) *Client {
+	creds := base64.StdEncoding.EncodeToString([]byte(cfg.Email + ":" + cfg.Token))
+	baseURL := strings.TrimRight(cfg.URL, "/")
+	timeout, err := cfg.RequestTimeout()
 	if err != nil {
-		return nil, formatNetworkError(err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, formatAPIError(resp.StatusCode, body)
-	}
+		timeout = config.DefaultTimeout
+	}
+	limiter := &rateLimiter{base: newTransport(timeout), sleep: sleepContext}
+	c := &Client{
+		baseURL:        baseURL,
+		apiURL:         baseURL + "/rest/api/3",
+		wikiURL:        baseURL + "/wiki",
+		authHeader:     "Basic " + creds,
+		wikiAuthHeader: "Basic " + creds,
+		httpClient:     &http.Client{Transport: limiter},
+		limiter:        limiter,
+		retry:          defaultRetryPolicy,
+		sleep:          sleepContext,
+	}
+	if cfg.DataCenter() {
+		c.dataCenter = true
+		c.apiURL = baseURL + "/rest/api/2"
+		c.authHeader = "Bearer " + cfg.Token
+		c.wikiURL = baseURL
+		c.wikiAuthHeader = c.authHeader
+		if cfg.ConfluenceURL != "" {
+			c.wikiURL = strings.TrimRight(cfg.ConfluenceURL, "/")
+		}
+		if cfg.ConfluenceToken != "" {
+			c.wikiAuthHeader = "Bearer " + cfg.ConfluenceToken
+		}
+	} else if cfg.OAuth != nil {
+		gateway := strings.TrimRight(oauth.GatewayURL, "/")
+		c.apiURL = fmt.Sprintf("%s/ex/jira/%s/rest/api/3", gateway, cfg.OAuth.CloudID)
+		c.baseURL = fmt.Sprintf("%s/ex/confluence/%s", gateway, cfg.OAuth.CloudID)
+		c.wikiURL = c.baseURL + "/wiki"
+		c.tokens = oauth.NewTokenSource(oauth.Config{
+			ClientID:     cfg.OAuth.ClientID,
+			ClientSecret: cfg.OAuth.ClientSecret,
+			Endpoint:     oauth.Atlassian,
+			HTTPClient:   &http.Client{Transport: newTransport(timeout)},
+		}, cfg.OAuth.RefreshToken, nil)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
 
-	var issue Issue
-	if err := json.NewDecoder(resp.Body).Decode(&issue); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
-	}
+// newTransport returns the default transport with connecting and waiting for
+// response headers bounded by timeout (0 for no limit). Reading a response
+// body isn't bounded, so large attachment downloads aren't cut off.
+func newTransport(timeout time.Duration) http.RoundTripper {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if timeout > 0 {
+		t.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+		t.TLSHandshakeTimeout = timeout
+		t.ResponseHeaderTimeout = timeout
+	}
+	return t
+}
 
-	return &issue, nil
+// issueFields is the field list requested by GetIssue.
+var issueFields = []string{"summary", "status", "issuetype", "project", "priority", "labels", "components", "fixVersions", "versions", "assignee", "reporter", "description", "comment", "issuelinks", "subtasks", "parent", "attachment", "updated"}
+
+// GetIssue fetches a single issue by key. extraFields are requested in addition
+// to the standard set (e.g., customfield ids from the config field map).
+func (c *Client) GetIssue(ctx context.Context, key string, extraFields ...string) (*Issue, error) {
+	fields := append(append([]string{}, issueFields...), extraFields...)
+	return do[*Issue](ctx, c, request{
+		method: "GET",
+		url:    fmt.Sprintf("%s/issue/%s?fields=%s", c.apiURL, key, strings.Join(fields, ",")),
+	})
 }
 
 // UpdateIssue updates an issue's fields.
-func (c *Client) UpdateIssue(key string, payload UpdatePayload) error {
-	url := fmt.Sprintf("%s/rest/api/3/issue/%s", c.baseURL, key)
+func (c *Client) UpdateIssue(ctx context.Context, key string, payload UpdatePayload) error {
+	return c.call(ctx, request{
+		method: "PUT",
+		url:    fmt.Sprintf("%s/issue/%s", c.apiURL, key),
+		json:   payload,
+	})
+}
 
-	data, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("marshalling payload: %w", err)
-	}
+// CreateIssue creates a new issue and returns its assigned key.
+func (c *Client) CreateIssue(ctx context.Context, payload CreatePayload) (*CreatedIssue, error) {
+	return do[*CreatedIssue](ctx, c, request{
+		method: "POST",
+		url:    fmt.Sprintf("%s/issue", c.apiURL),
+		json:   payload,
+	})
+}
 
-	req, err := http.NewRequest("PUT", url, bytes.NewReader(data))
-	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
-	}
-	c.setHeaders(req)
+// FindUsers searches for users matching a query (email address or display name).
+func (c *Client) FindUsers(ctx context.Context, query string) ([]User, error) {
+	params := url.Values{}
+	if c.dataCenter {
+		params.Set("username", query)
+	} else {
+		params.Set("query", query)
+	}
+	return do[[]User](ctx, c, request{
+		method: "GET",
+		url:    fmt.Sprintf("%s/user/search?%s", c.apiURL, params.Encode()),
+	})
+}
+
+// Myself returns the user the client is authenticated as.
+func (c *Client) Myself(ctx context.Context) (*User, error) {
+	return do[*User](ctx, c, request{
+		method: "GET",
+		url:    fmt.Sprintf("%s/myself", c.apiURL),
+	})
+}
 
-	resp, err := c.httpClient.Do(req)
+// MyPermissions reports which of the given permission keys (such as
+// BROWSE_PROJECTS or EDIT_ISSUES) the user has in a project.
+func (c *Client) MyPermissions(ctx context.Context, projectKey string, permissions ...string) (map[string]bool, error) {
+	params := url.Values{}
+	params.Set("projectKey", projectKey)
+	params.Set("permissions", strings.Join(permissions, ","))
+	result, err := do[MyPermissionsResponse](ctx, c, request{
+		method: "GET",
+		url:    fmt.Sprintf("%s/mypermissions?%s", c.apiURL, params.Encode()),
+	})
 	if err != nil {
-		return formatNetworkError(err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return formatAPIError(resp.StatusCode, body)
+	have := make(map[string]bool, len(permissions))
+	for _, key := range permissions {
+		have[key] = result.Permissions[key].HavePermission
 	}
-
-	return nil
+	return have, nil
 }
 
-// GetTransitions returns available transitions for an issue.
-func (c *Client) GetTransitions(key string) ([]TransitionInfo, error) {
-	url := fmt.Sprintf("%s/rest/api/3/issue/%s/transitions", c.baseURL, key)
+// AddComment posts a new comment to an issue and returns the created comment.
+func (c *Client) AddComment(ctx context.Context, key string, body *ADFNode) (*Comment, error) {
+	return do[*Comment](ctx, c, request{
+		method: "POST",
+		url:    fmt.Sprintf("%s/issue/%s/comment", c.apiURL, key),
+		json:   CommentPayload{Body: body},
+	})
+}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
-	c.setHeaders(req)
+// UpdateComment replaces the body of an existing comment.
+func (c *Client) UpdateComment(ctx context.Context, key string, commentID string, body *ADFNode) error {
+	return c.call(ctx, request{
+		method: "PUT",
+		url:    fmt.Sprintf("%s/issue/%s/comment/%s", c.apiURL, key, commentID),
+		json:   CommentPayload{Body: body},
+	})
+}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, formatNetworkError(err)
-	}
-	defer resp.Body.Close()
+// DeleteComment deletes a comment from an issue.
+func (c *Client) DeleteComment(ctx context.Context, key string, commentID string) error {
+	return c.call(ctx, request{
+		method: "DELETE",
+		url:    fmt.Sprintf("%s/issue/%s/comment/%s", c.apiURL, key, commentID),
+	})
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, formatAPIError(resp.StatusCode, body)
-	}
+// GetFields returns all system and custom fields visible to the user.
+func (c *Client) GetFields(ctx context.Context) ([]FieldInfo, error) {
+	return do[[]FieldInfo](ctx, c, request{
+		method: "GET",
+		url:    fmt.Sprintf("%s/field", c.apiURL),
+	})
+}
 
-	var result TransitionsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
-	}
+// GetProjectComponents returns the components defined in a project.
+func (c *Client) GetProjectComponents(ctx context.Context, projectKey string) ([]Component, error) {
+	return do[[]Component](ctx, c, request{
+		method: "GET",
+		url:    fmt.Sprintf("%s/project/%s/components", c.apiURL, projectKey),
+	})
+}
 
-	return result.Transitions, nil
+// GetProjectVersions returns the versions defined in a project.
+func (c *Client) GetProjectVersions(ctx context.Context, projectKey string) ([]Version, error) {
+	return do[[]Version](ctx, c, request{
+		method: "GET",
+		url:    fmt.Sprintf("%s/project/%s/versions", c.apiURL, projectKey),
+	})
 }
 
-// DoTransition performs a status transition on an issue.
-func (c *Client) DoTransition(key string, transitionID string) error {
-	url := fmt.Sprintf("%s/rest/api/3/issue/%s/transitions", c.baseURL, key)
+// GetIssueLinkTypes returns the link types configured on the site.
+func (c *Client) GetIssueLinkTypes(ctx context.Context) ([]IssueLinkType, error) {
+	result, err := do[struct {
+		IssueLinkTypes []IssueLinkType `json:"issueLinkTypes"`
+	}](ctx, c, request{
+		method: "GET",
+		url:    fmt.Sprintf("%s/issueLinkType", c.apiURL),
+	})
+	return result.IssueLinkTypes, err
+}
 
-	payload := TransitionPayload{
-		Transition: Transition{ID: transitionID},
-	}
+// CreateIssueLink links two issues. Both InwardIssue and OutwardIssue must be set.
+func (c *Client) CreateIssueLink(ctx context.Context, link IssueLink) error {
+	return c.call(ctx, request{
+		method: "POST",
+		url:    fmt.Sprintf("%s/issueLink", c.apiURL),
+		json:   link,
+	})
+}
 
-	data, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("marshalling payload: %w", err)
-	}
+// DeleteIssueLink removes a link by id.
+func (c *Client) DeleteIssueLink(ctx context.Context, linkID string) error {
+	return c.call(ctx, request{
+		method: "DELETE",
+		url:    fmt.Sprintf("%s/issueLink/%s", c.apiURL, linkID),
+	})
+}
 
-	req, err := http.NewRequest("POST", url, bytes.NewReader(data))
+// DownloadAttachment writes the content of an attachment to w.
+func (c *Client) DownloadAttachment(ctx context.Context, attachmentID string, w io.Writer) error {
+	contentURL, err := c.attachmentContentURL(ctx, attachmentID)
 	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
+		return err
 	}
-	c.setHeaders(req)
 
-	resp, err := c.httpClient.Do(req)
+	// The content endpoint redirects to the media service; the Authorization
+	// header is not forwarded to the other host, the redirect URL carries a token.
+	resp, err := c.exec(ctx, request{
+		method: "GET",
+		url:    contentURL,
+		header: http.Header{"Accept": {"*/*"}},
+	})
 	if err != nil {
-		return formatNetworkError(err)
+		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return formatAPIError(resp.StatusCode, body)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("reading attachment: %w", err)
 	}
-
 	return nil
 }
 
-// GetConfluencePage fetches a Confluence page by ID with ADF body.
-func (c *Client) GetConfluencePage(pageID string) (*ConfluencePage, error) {
-	url := fmt.Sprintf("%s/wiki/api/v2/pages/%s?body-format=atlas_doc_format", c.baseURL, pageID)
-
-	req, err := http.NewRequest("GET", url, nil)
+// AddAttachment uploads a file to an issue and returns the created attachments.
+func (c *Client) AddAttachment(ctx context.Context, key string, filename string, content io.Reader) ([]Attachment, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, err := mw.CreateFormFile("file", filename)
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		return nil, fmt.Errorf("building upload: %w", err)
 	}
-	c.setHeaders(req)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, formatNetworkError(err)
+	if _, err := io.Copy(part, content); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", filename, err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Confluence API returned %d: %s", resp.StatusCode, string(body))
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("building upload: %w", err)
 	}
 
-	var page ConfluencePage
-	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	return do[[]Attachment](ctx, c, request{
+		method:      "POST",
+		url:         fmt.Sprintf("%s/issue/%s/attachments", c.apiURL, key),
+		raw:         &buf,
+		contentType: mw.FormDataContentType(),
+		// Required by JIRA for multipart uploads (XSRF check)
+		header: http.Header{"X-Atlassian-Token": {"no-check"}},
+	})
+}
+
+// worklogPageSize is the page size requested when listing worklogs.
+const worklogPageSize = 100
+
+// GetWorklogs returns all worklogs on an issue, oldest first, following pagination.
+func (c *Client) GetWorklogs(ctx context.Context, key string) ([]Worklog, error) {
+	var all []Worklog
+	startAt := 0
+
+	for {
+		page, err := do[WorklogPage](ctx, c, request{
+			method: "GET",
+			url:    fmt.Sprintf("%s/issue/%s/worklog?startAt=%d&maxResults=%d", c.apiURL, key, startAt, worklogPageSize),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, page.Worklogs...)
+		startAt += len(page.Worklogs)
+		if len(page.Worklogs) == 0 || startAt >= page.Total {
+			break
+		}
 	}
 
-	return &page, nil
+	return all, nil
 }
 
-// GetConfluenceSpace fetches a Confluence space by ID.
-func (c *Client) GetConfluenceSpace(spaceID string) (*ConfluenceSpace, error) {
-	url := fmt.Sprintf("%s/wiki/api/v2/spaces/%s", c.baseURL, spaceID)
+// AddWorklog logs time on an issue.
+func (c *Client) AddWorklog(ctx context.Context, key string, payload WorklogPayload) (*Worklog, error) {
+	return do[*Worklog](ctx, c, request{
+		method: "POST",
+		url:    fmt.Sprintf("%s/issue/%s/worklog", c.apiURL, key),
+		json:   payload,
+	})
+}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
-	c.setHeaders(req)
+// changelogPageSize is the page size requested when reading an issue's changelog.
+const changelogPageSize = 100
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, formatNetworkError(err)
+// GetChangelog returns an issue's full change history, oldest first, following pagination.
+func (c *Client) GetChangelog(ctx context.Context, key string) ([]ChangelogEntry, error) {
+	if c.dataCenter {
+		return c.getChangelogV2(ctx, key)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Confluence API returned %d: %s", resp.StatusCode, string(body))
-	}
+	var all []ChangelogEntry
+	startAt := 0
+
+	for {
+		page, err := do[ChangelogPage](ctx, c, request{
+			method: "GET",
+			url:    fmt.Sprintf("%s/issue/%s/changelog?startAt=%d&maxResults=%d", c.apiURL, key, startAt, changelogPageSize),
+		})
+		if err != nil {
+			return nil, err
+		}
 
-	var space ConfluenceSpace
-	if err := json.NewDecoder(resp.Body).Decode(&space); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+		all = append(all, page.Values...)
+		startAt += len(page.Values)
+		if page.IsLast || len(page.Values) == 0 || startAt >= page.Total {
+			break
+		}
 	}
 
-	return &space, nil
+	return all, nil
 }
 
-// GetConfluenceSpaceByKey fetches a Confluence space by its key (e.g., "ENG").
-func (c *Client) GetConfluenceSpaceByKey(spaceKey string) (*ConfluenceSpace, error) {
-	url := fmt.Sprintf("%s/wiki/api/v2/spaces?keys=%s", c.baseURL, spaceKey)
-
-	req, err := http.NewRequest("GET", url, nil)
+// GetProjectWorkflowScheme returns the workflow scheme used by a project.
+// Reading workflow schemes requires the Administer Jira permission.
+func (c *Client) GetProjectWorkflowScheme(ctx context.Context, projectID string) (*WorkflowScheme, error) {
+	if c.dataCenter {
+		return nil, errWorkflowsUnavailable
+	}
+
+	result, err := do[struct {
+		Values []struct {
+			WorkflowScheme WorkflowScheme `json:"workflowScheme"`
+		} `json:"values"`
+	}](ctx, c, request{
+		method: "GET",
+		url:    fmt.Sprintf("%s/workflowscheme/project?projectId=%s", c.apiURL, url.QueryEscape(projectID)),
+	})
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		return nil, err
 	}
-	c.setHeaders(req)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, formatNetworkError(err)
+	if len(result.Values) == 0 {
+		return nil, fmt.Errorf("no workflow scheme found for project %s", projectID)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Confluence API returned %d: %s", resp.StatusCode, string(body))
-	}
+	return &result.Values[0].WorkflowScheme, nil
+}
 
-	var result ConfluenceSpacesResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+// GetWorkflow returns a workflow by name with its statuses and transitions.
+// Reading workflows requires the Administer Jira permission.
+func (c *Client) GetWorkflow(ctx context.Context, name string) (*Workflow, error) {
+	if c.dataCenter {
+		return nil, errWorkflowsUnavailable
 	}
 
-	if len(result.Results) == 0 {
-		return nil, fmt.Errorf("no space found with key %q", spaceKey)
+	result, err := do[struct {
+		Values []Workflow `json:"values"`
+	}](ctx, c, request{
+		method: "GET",
+		url:    fmt.Sprintf("%s/workflow/search?workflowName=%s&expand=transitions,statuses", c.apiURL, url.QueryEscape(name)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Values) == 0 {
+		return nil, fmt.Errorf("workflow %q not found", name)
 	}
 
-	return &result.Results[0], nil
+	return &result.Values[0], nil
 }
 
-// CreateConfluencePage creates a new Confluence page and returns it.
-func (c *Client) CreateConfluencePage(payload ConfluenceCreatePayload) (*ConfluencePage, error) {
-	url := fmt.Sprintf("%s/wiki/api/v2/pages", c.baseURL)
+// GetTransitions returns available transitions for an issue, including the
+// fields on each transition screen.
+func (c *Client) GetTransitions(ctx context.Context, key string) ([]TransitionInfo, error) {
+	result, err := do[TransitionsResponse](ctx, c, request{
+		method: "GET",
+		url:    fmt.Sprintf("%s/issue/%s/transitions?expand=transitions.fields", c.apiURL, key),
+	})
+	return result.Transitions, err
+}
+
+// DoTransition performs a status transition on an issue. fields supplies
+// values for the transition screen (e.g. resolution) and may be nil.
+func (c *Client) DoTransition(ctx context.Context, key string, transitionID string, fields map[string]any) error {
+	return c.call(ctx, request{
+		method: "POST",
+		url:    fmt.Sprintf("%s/issue/%s/transitions", c.apiURL, key),
+		json: TransitionPayload{
+			Transition: Transition{ID: transitionID},
+			Fields:     fields,
+		},
+	})
+}
 
-	data, err := json.Marshal(payload)
+// GetConfluencePage fetches a Confluence page by ID with ADF body.
+func (c *Client) GetConfluencePage(ctx context.Context, pageID string) (*ConfluencePage, error) {
+	if c.dataCenter {
+		return c.getConfluencePageV1(ctx, pageID)
+	}
+	return do[*ConfluencePage](ctx, c, request{
+		method:  "GET",
+		url:     fmt.Sprintf("%s/api/v2/pages/%s?body-format=atlas_doc_format", c.wikiURL, pageID),
+		service: serviceConfluence,
+	})
+}
+
+// GetConfluenceSpace fetches a Confluence space by ID.
+func (c *Client) GetConfluenceSpace(ctx context.Context, spaceID string) (*ConfluenceSpace, error) {
+	if c.dataCenter {
+		return c.getConfluenceSpaceV1(ctx, spaceID)
+	}
+	return do[*ConfluenceSpace](ctx, c, request{
+		method:  "GET",
+		url:     fmt.Sprintf("%s/api/v2/spaces/%s", c.wikiURL, spaceID),
+		service: serviceConfluence,
+	})
+}
+
+// GetConfluenceSpaceByKey fetches a Confluence space by its key (e.g., "ENG").
+func (c *Client) GetConfluenceSpaceByKey(ctx context.Context, spaceKey string) (*ConfluenceSpace, error) {
+	if c.dataCenter {
+		return c.getConfluenceSpaceV1(ctx, spaceKey)
+	}
+	result, err := do[ConfluenceSpacesResponse](ctx, c, request{
+		method:  "GET",
+		url:     fmt.Sprintf("%s/api/v2/spaces?keys=%s", c.wikiURL, spaceKey),
+		service: serviceConfluence,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("marshalling payload: %w", err)
+		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewReader(data))
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+	if len(result.Results) == 0 {
+		return nil, fmt.Errorf("no space found with key %q", spaceKey)
 	}
-	c.setHeaders(req)
 
-	resp, err := c.httpClient.Do(req)
+	return &result.Results[0], nil
+}
+
+// ListConfluenceSpaces returns up to limit spaces the user can see.
+func (c *Client) ListConfluenceSpaces(ctx context.Context, limit int) ([]ConfluenceSpace, error) {
+	if c.dataCenter {
+		return c.listConfluenceSpacesV1(ctx, limit)
+	}
+	result, err := do[ConfluenceSpacesResponse](ctx, c, request{
+		method:  "GET",
+		url:     fmt.Sprintf("%s/api/v2/spaces?limit=%d", c.wikiURL, limit),
+		service: serviceConfluence,
+	})
 	if err != nil {
-		return nil, formatNetworkError(err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return result.Results, nil
+}
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Confluence API returned %d: %s", resp.StatusCode, string(body))
+// GetConfluenceSpaceOperations returns what the user may do in the space
+// with the given key, such as read the space or create pages in it.
+func (c *Client) GetConfluenceSpaceOperations(ctx context.Context, spaceKey string) ([]ConfluenceOperation, error) {
+	if c.dataCenter {
+		return c.getConfluenceSpaceOperationsV1(ctx, spaceKey)
 	}
-
-	var page ConfluencePage
-	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	space, err := c.GetConfluenceSpaceByKey(ctx, spaceKey)
+	if err != nil {
+		return nil, err
+	}
+	result, err := do[struct {
+		Operations struct {
+			Results []ConfluenceOperation `json:"results"`
+		} `json:"operations"`
+	}](ctx, c, request{
+		method:  "GET",
+		url:     fmt.Sprintf("%s/api/v2/spaces/%s?include-operations=true", c.wikiURL, space.ID),
+		service: serviceConfluence,
+	})
+	if err != nil {
+		return nil, err
 	}
+	return result.Operations.Results, nil
+}
 
-	return &page, nil
+// CreateConfluencePage creates a new Confluence page and returns it.
+func (c *Client) CreateConfluencePage(ctx context.Context, payload ConfluenceCreatePayload) (*ConfluencePage, error) {
+	if c.dataCenter {
+		return c.createConfluencePageV1(ctx, payload)
+	}
+	return do[*ConfluencePage](ctx, c, request{
+		method:  "POST",
+		url:     fmt.Sprintf("%s/api/v2/pages", c.wikiURL),
+		json:    payload,
+		service: serviceConfluence,
+	})
 }
 
 // GetConfluenceChildPages fetches all direct child pages of a given page ID.
 // It handles pagination internally and returns the complete list.
-func (c *Client) GetConfluenceChildPages(pageID string) ([]ConfluenceChildPage, error) {
+func (c *Client) GetConfluenceChildPages(ctx context.Context, pageID string) ([]ConfluenceChildPage, error) {
+	if c.dataCenter {
+		return c.getConfluenceChildPagesV1(ctx, pageID)
+	}
+
 	var all []ConfluenceChildPage
-	apiURL := fmt.Sprintf("%s/wiki/api/v2/pages/%s/children?limit=50", c.baseURL, pageID)
+	apiURL := fmt.Sprintf("%s/api/v2/pages/%s/children?limit=50", c.wikiURL, pageID)
 
 	for apiURL != "" {
-		req, err := http.NewRequest("GET", apiURL, nil)
+		result, err := do[ConfluenceChildrenResponse](ctx, c, request{method: "GET", url: apiURL, service: serviceConfluence})
 		if err != nil {
-			return nil, fmt.Errorf("creating request: %w", err)
+			return nil, err
 		}
-		c.setHeaders(req)
-
-		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			return nil, formatNetworkError(err)
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			resp.Body.Close()
-			return nil, fmt.Errorf("Confluence API returned %d: %s", resp.StatusCode, string(body))
-		}
-
-		var result ConfluenceChildrenResponse
-		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-			resp.Body.Close()
-			return nil, fmt.Errorf("decoding response: %w", err)
-		}
-		resp.Body.Close()
 
 		all = append(all, result.Results...)
 
@@ -322,83 +602,38 @@ func (c *Client) GetConfluenceChildPages(pageID string) ([]ConfluenceChildPage,
 }
 
 // GetConfluenceFooterComments fetches footer (page-level) comments for a Confluence page.
-func (c *Client) GetConfluenceFooterComments(pageID string) ([]ConfluenceComment, error) {
-	var all []ConfluenceComment
-	apiURL := fmt.Sprintf("%s/wiki/api/v2/pages/%s/footer-comments?body-format=storage", c.baseURL, pageID)
-
-	for apiURL != "" {
-		req, err := http.NewRequest("GET", apiURL, nil)
-		if err != nil {
-			return nil, fmt.Errorf("creating request: %w", err)
-		}
-		c.setHeaders(req)
-
-		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			return nil, formatNetworkError(err)
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			resp.Body.Close()
-			return nil, fmt.Errorf("Confluence API returned %d: %s", resp.StatusCode, string(body))
-		}
-
-		var result ConfluenceCommentsResponse
-		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-			resp.Body.Close()
-			return nil, fmt.Errorf("decoding response: %w", err)
-		}
-		resp.Body.Close()
-
-		all = append(all, result.Results...)
-
-		if result.Links.Next != "" {
-			apiURL = c.baseURL + result.Links.Next
-		} else {
-			apiURL = ""
-		}
+func (c *Client) GetConfluenceFooterComments(ctx context.Context, pageID string) ([]ConfluenceComment, error) {
+	if c.dataCenter {
+		return c.getConfluenceCommentsV1(ctx, pageID, "footer")
 	}
-
-	return all, nil
+	return c.getConfluenceComments(ctx, fmt.Sprintf("%s/api/v2/pages/%s/footer-comments?body-format=storage", c.wikiURL, pageID))
 }
 
 // GetConfluenceInlineComments fetches inline comments for a Confluence page.
 // Returns nil (not error) on 404, which is a known Confluence bug on pages with resolved comments.
-func (c *Client) GetConfluenceInlineComments(pageID string) ([]ConfluenceComment, error) {
+func (c *Client) GetConfluenceInlineComments(ctx context.Context, pageID string) ([]ConfluenceComment, error) {
+	var comments []ConfluenceComment
+	var err error
+	if c.dataCenter {
+		comments, err = c.getConfluenceCommentsV1(ctx, pageID, "inline")
+	} else {
+		comments, err = c.getConfluenceComments(ctx, fmt.Sprintf("%s/api/v2/pages/%s/inline-comments?body-format=storage", c.wikiURL, pageID))
+	}
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	return comments, err
+}
+
+// getConfluenceComments reads every page of a comments listing.
+func (c *Client) getConfluenceComments(ctx context.Context, apiURL string) ([]ConfluenceComment, error) {
 	var all []ConfluenceComment
-	apiURL := fmt.Sprintf("%s/wiki/api/v2/pages/%s/inline-comments?body-format=storage", c.baseURL, pageID)
 
 	for apiURL != "" {
-		req, err := http.NewRequest("GET", apiURL, nil)
-		if err != nil {
-			return nil, fmt.Errorf("creating request: %w", err)
-		}
-		c.setHeaders(req)
-
-		resp, err := c.httpClient.Do(req)
+		result, err := do[ConfluenceCommentsResponse](ctx, c, request{method: "GET", url: apiURL, service: serviceConfluence})
 		if err != nil {
-			return nil, formatNetworkError(err)
-		}
-
-		// Known Confluence bug: returns 404 on pages with resolved inline comments
-		if resp.StatusCode == http.StatusNotFound {
-			resp.Body.Close()
-			return nil, nil
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			resp.Body.Close()
-			return nil, fmt.Errorf("Confluence API returned %d: %s", resp.StatusCode, string(body))
-		}
-
-		var result ConfluenceCommentsResponse
-		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-			resp.Body.Close()
-			return nil, fmt.Errorf("decoding response: %w", err)
+			return nil, err
 		}
-		resp.Body.Close()
 
 		all = append(all, result.Results...)
 
@@ -433,157 +668,167 @@ type ConfluenceUpdateVersion struct {
 	Message string `json:"message,omitempty"`
 }
 
-// UpdateConfluencePage updates a Confluence page body (ADF format).
-func (c *Client) UpdateConfluencePage(pageID string, payload ConfluenceUpdatePayload) error {
-	url := fmt.Sprintf("%s/wiki/api/v2/pages/%s", c.baseURL, pageID)
-
-	data, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("marshalling payload: %w", err)
-	}
-
-	req, err := http.NewRequest("PUT", url, bytes.NewReader(data))
-	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
-	}
-	c.setHeaders(req)
+// UpdateConfluencePage updates a Confluence page body (ADF format). A version
+// number someone else's edit has already taken fails with ErrConflict.
+func (c *Client) UpdateConfluencePage(ctx context.Context, pageID string, payload ConfluenceUpdatePayload) error {
+	if c.dataCenter {
+		return c.updateConfluencePageV1(ctx, pageID, payload)
+	}
+	return c.call(ctx, request{
+		method:  "PUT",
+		url:     fmt.Sprintf("%s/api/v2/pages/%s", c.wikiURL, pageID),
+		json:    payload,
+		service: serviceConfluence,
+	})
+}
 
-	resp, err := c.httpClient.Do(req)
+// searchPageSize is the most issues requested per page of a JQL search.
+const searchPageSize = 100
+
+// searchFields is the field list requested for each search result.
+var searchFields = []string{"summary", "status", "issuetype", "priority", "labels", "assignee", "reporter", "updated"}
+
+// SearchIssues searches for issues using JQL, following pages until limit
+// issues have been read or the matches run out. A limit of 0 or less reads
+// every match. IsLast on the result reports whether every match was read.
+func (c *Client) SearchIssues(ctx context.Context, jql string, limit int) (*SearchResult, error) {
+	result := &SearchResult{IsLast: true}
+	err := c.SearchIssuesPages(ctx, jql, limit, func(page *SearchResult) error {
+		result.Issues = append(result.Issues, page.Issues...)
+		result.NextPageToken = page.NextPageToken
+		result.IsLast = page.IsLast || page.NextPageToken == ""
+		return nil
+	})
 	if err != nil {
-		return formatNetworkError(err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("Confluence API returned %d: %s", resp.StatusCode, string(body))
-	}
-
-	return nil
+	return result, nil
 }
 
-// SearchIssues searches for issues using JQL.
-func (c *Client) SearchIssues(jql string, maxResults int, startAt int) (*SearchResult, error) {
-	apiURL := fmt.Sprintf("%s/rest/api/3/search/jql", c.baseURL)
-
-	payload := SearchPayload{
-		JQL:        jql,
-		MaxResults: maxResults,
-		StartAt:    startAt,
-		Fields:     []string{"summary", "status", "issuetype", "priority", "labels", "assignee", "reporter", "updated"},
+// SearchIssuesPages runs a JQL search and calls fn with each page of results
+// as it arrives, stopping after limit issues (every match when limit <= 0) or
+// when fn returns an error.
+func (c *Client) SearchIssuesPages(ctx context.Context, jql string, limit int, fn func(page *SearchResult) error) error {
+	if c.dataCenter {
+		return c.searchIssuesPagesV2(ctx, jql, limit, fn)
 	}
 
-	data, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("marshalling search payload: %w", err)
-	}
+	apiURL := fmt.Sprintf("%s/search/jql", c.apiURL)
+	read := 0
+	token := ""
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewReader(data))
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
-	c.setHeaders(req)
+	for {
+		size := searchPageSize
+		if limit > 0 && limit-read < size {
+			size = limit - read
+		}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, formatNetworkError(err)
-	}
-	defer resp.Body.Close()
+		page, err := do[SearchResult](ctx, c, request{
+			method: "POST",
+			url:    apiURL,
+			json: SearchPayload{
+				JQL:           jql,
+				MaxResults:    size,
+				NextPageToken: token,
+				Fields:        searchFields,
+			},
+			// Search only reads, so it is retried like a GET.
+			readOnly: true,
+		})
+		if err != nil {
+			return err
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, formatAPIError(resp.StatusCode, body)
-	}
+		read += len(page.Issues)
+		if err := fn(&page); err != nil {
+			return err
+		}
 
-	var result SearchResult
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+		if page.IsLast || page.NextPageToken == "" || len(page.Issues) == 0 || (limit > 0 && read >= limit) {
+			return nil
+		}
+		token = page.NextPageToken
 	}
-
-	return &result, nil
 }
 
-// SearchConfluence searches Confluence content using CQL.
-func (c *Client) SearchConfluence(cql string, limit int, start int) (*ConfluenceSearchResult, error) {
-	params := url.Values{}
-	params.Set("cql", cql)
-	params.Set("limit", fmt.Sprintf("%d", limit))
-	params.Set("start", fmt.Sprintf("%d", start))
-
-	apiURL := fmt.Sprintf("%s/wiki/rest/api/content/search?%s", c.baseURL, params.Encode())
-
-	req, err := http.NewRequest("GET", apiURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
-	c.setHeaders(req)
+// ApproximateIssueCount returns an estimate of how many issues match a JQL
+// query. The search endpoint doesn't report a total, so this is used for
+// progress output.
+func (c *Client) ApproximateIssueCount(ctx context.Context, jql string) (int, error) {
+	if c.dataCenter {
+		return c.approximateIssueCountV2(ctx, jql)
+	}
+
+	result, err := do[struct {
+		Count int `json:"count"`
+	}](ctx, c, request{
+		method:   "POST",
+		url:      fmt.Sprintf("%s/search/approximate-count", c.apiURL),
+		json:     map[string]string{"jql": jql},
+		readOnly: true,
+	})
+	return result.Count, err
+}
 
-	resp, err := c.httpClient.Do(req)
+// confluenceSearchPageSize is the most results requested per page of a CQL search.
+const confluenceSearchPageSize = 50
+
+// SearchConfluence searches Confluence content using CQL, following pages
+// until limit results have been read or the matches run out. A limit of 0 or
+// less reads every match.
+func (c *Client) SearchConfluence(ctx context.Context, cql string, limit int) (*ConfluenceSearchResult, error) {
+	result := &ConfluenceSearchResult{}
+	err := c.SearchConfluencePages(ctx, cql, limit, func(page *ConfluenceSearchResult) error {
+		result.Results = append(result.Results, page.Results...)
+		result.TotalSize = page.TotalSize
+		result.Links = page.Links
+		return nil
+	})
 	if err != nil {
-		return nil, formatNetworkError(err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Confluence search API returned %d: %s", resp.StatusCode, string(body))
+		return nil, err
 	}
+	result.Size = len(result.Results)
+	return result, nil
+}
 
-	var result ConfluenceSearchResult
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+// SearchConfluencePages runs a CQL search and calls fn with each page of
+// results as it arrives, following the _links.next cursor. It stops after
+// limit results (every match when limit <= 0) or when fn returns an error.
+func (c *Client) SearchConfluencePages(ctx context.Context, cql string, limit int, fn func(page *ConfluenceSearchResult) error) error {
+	size := confluenceSearchPageSize
+	if limit > 0 && limit < size {
+		size = limit
 	}
 
-	return &result, nil
-}
+	params := url.Values{}
+	params.Set("cql", cql)
+	params.Set("limit", fmt.Sprintf("%d", size))
+	params.Set("start", "0")
 
-// JiraErrors represents the structured error response from the JIRA API.
-type JiraErrors struct {
-	Errors        map[string]string `json:"errors"`
-	ErrorMessages []string          `json:"errorMessages"`
-}
+	apiURL := fmt.Sprintf("%s/rest/api/content/search?%s", c.wikiURL, params.Encode())
+	read := 0
 
-func formatAPIError(statusCode int, body []byte) error {
-	var je JiraErrors
-	if json.Unmarshal(body, &je) == nil && (len(je.ErrorMessages) > 0 || len(je.Errors) > 0) {
-		var parts []string
-		for _, msg := range je.ErrorMessages {
-			parts = append(parts, msg)
+	for apiURL != "" {
+		page, err := do[ConfluenceSearchResult](ctx, c, request{method: "GET", url: apiURL, service: serviceConfluence})
+		if err != nil {
+			return err
 		}
-		for field, msg := range je.Errors {
-			parts = append(parts, field+": "+msg)
+
+		if limit > 0 && read+len(page.Results) > limit {
+			page.Results = page.Results[:limit-read]
 		}
-		msg := strings.Join(parts, "; ")
-		// Always append the raw body so field-level details aren't lost
-		raw := strings.TrimSpace(string(body))
-		if raw != "" && raw != msg {
-			return fmt.Errorf("JIRA API %d: %s\nResponse body: %s", statusCode, msg, raw)
+		read += len(page.Results)
+		if err := fn(&page); err != nil {
+			return err
 		}
-		return fmt.Errorf("JIRA API %d: %s", statusCode, msg)
-	}
-	return fmt.Errorf("JIRA API %d: %s", statusCode, string(body))
-}
 
-func formatNetworkError(err error) error {
-	var netErr net.Error
-	if errors.As(err, &netErr) && netErr.Timeout() {
-		return fmt.Errorf("connection timed out — check your network connection or VPN, then try again")
-	}
-	var opErr *net.OpError
-	if errors.As(err, &opErr) {
-		if opErr.Op == "dial" {
-			return fmt.Errorf("could not connect to server — check your network connection or VPN, then try again")
+		// Links in search results are relative to the Confluence context path.
+		if page.Links.Next != "" && len(page.Results) > 0 && (limit <= 0 || read < limit) {
+			apiURL = c.wikiURL + page.Links.Next
+		} else {
+			apiURL = ""
 		}
 	}
-	var dnsErr *net.DNSError
-	if errors.As(err, &dnsErr) {
-		return fmt.Errorf("DNS lookup failed for %s — check the URL in your config", dnsErr.Name)
-	}
-	return fmt.Errorf("network error: %w", err)
-}
 
-func (c *Client) setHeaders(req *http.Request) {
-	req.Header.Set("Authorization", c.authHeader)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+	return nil
 }
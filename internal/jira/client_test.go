@@ -1,14 +1,20 @@
 package jira
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mreider/a-cli/internal/config"
+	"github.com/mreider/a-cli/internal/oauth"
 )
 
 func testClient(baseURL string) *Client {
@@ -34,13 +40,13 @@ func TestSearchIssues_Endpoint(t *testing.T) {
 
 		json.NewEncoder(w).Encode(SearchResult{
 			Issues: []Issue{{Key: "TEST-1", Fields: Fields{Summary: "test issue"}}},
-			Total:  1,
+			IsLast: true,
 		})
 	}))
 	defer srv.Close()
 
 	client := testClient(srv.URL)
-	result, err := client.SearchIssues("project = TEST", 25, 0)
+	result, err := client.SearchIssues(context.Background(), "project = TEST", 25)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -62,6 +68,72 @@ func TestSearchIssues_Endpoint(t *testing.T) {
 	}
 }
 
+func TestSearchIssues_Pagination(t *testing.T) {
+	var tokens []string
+	var sizes []int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload SearchPayload
+		json.NewDecoder(r.Body).Decode(&payload)
+		tokens = append(tokens, payload.NextPageToken)
+		sizes = append(sizes, payload.MaxResults)
+
+		switch payload.NextPageToken {
+		case "":
+			w.Write([]byte(`{"issues":[{"key":"TEST-1"},{"key":"TEST-2"}],"nextPageToken":"p2"}`))
+		case "p2":
+			w.Write([]byte(`{"issues":[{"key":"TEST-3"},{"key":"TEST-4"}],"nextPageToken":"p3"}`))
+		default:
+			w.Write([]byte(`{"issues":[{"key":"TEST-5"}],"isLast":true}`))
+		}
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	result, err := client.SearchIssues(context.Background(), "project = TEST", 0)
+	if err != nil {
+		t.Fatalf("SearchIssues: %v", err)
+	}
+	if len(result.Issues) != 5 || result.Issues[4].Key != "TEST-5" || !result.IsLast {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if strings.Join(tokens, ",") != ",p2,p3" {
+		t.Errorf("unexpected page tokens: %q", tokens)
+	}
+
+	// A limit stops paging early and trims the last page request.
+	tokens, sizes = nil, nil
+	result, err = client.SearchIssues(context.Background(), "project = TEST", 3)
+	if err != nil {
+		t.Fatalf("SearchIssues: %v", err)
+	}
+	if len(result.Issues) != 4 || result.IsLast {
+		t.Errorf("expected 4 issues and more remaining, got %d (isLast %v)", len(result.Issues), result.IsLast)
+	}
+	if len(sizes) != 2 || sizes[0] != 3 || sizes[1] != 1 {
+		t.Errorf("unexpected page sizes: %v", sizes)
+	}
+}
+
+func TestApproximateIssueCount_Endpoint(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/rest/api/3/search/approximate-count" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+		w.Write([]byte(`{"count":1234}`))
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	n, err := client.ApproximateIssueCount(context.Background(), "project = TEST")
+	if err != nil {
+		t.Fatalf("ApproximateIssueCount: %v", err)
+	}
+	if n != 1234 {
+		t.Errorf("expected 1234, got %d", n)
+	}
+}
+
 func TestGetIssue_Endpoint(t *testing.T) {
 	var gotPath, gotMethod string
 
@@ -73,7 +145,7 @@ func TestGetIssue_Endpoint(t *testing.T) {
 	defer srv.Close()
 
 	client := testClient(srv.URL)
-	issue, err := client.GetIssue("PROJ-123")
+	issue, err := client.GetIssue(context.Background(), "PROJ-123")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -89,6 +161,701 @@ func TestGetIssue_Endpoint(t *testing.T) {
 	}
 }
 
+func TestCreateIssue_Endpoint(t *testing.T) {
+	var gotPath, gotMethod string
+	var gotPayload CreatePayload
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotMethod = r.Method
+
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &gotPayload)
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(CreatedIssue{ID: "10001", Key: "PROJ-42"})
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	created, err := client.CreateIssue(context.Background(), CreatePayload{
+		Fields: CreateFields{
+			Project:   ProjectRef{Key: "PROJ"},
+			Summary:   "New issue",
+			IssueType: IssueType{Name: "Bug"},
+			Assignee:  &AccountRef{AccountID: "abc123"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotPath != "/rest/api/3/issue" {
+		t.Errorf("expected path /rest/api/3/issue, got %s", gotPath)
+	}
+	if gotMethod != "POST" {
+		t.Errorf("expected POST, got %s", gotMethod)
+	}
+	if gotPayload.Fields.Project.Key != "PROJ" || gotPayload.Fields.IssueType.Name != "Bug" {
+		t.Errorf("unexpected payload: %+v", gotPayload)
+	}
+	if gotPayload.Fields.Assignee == nil || gotPayload.Fields.Assignee.AccountID != "abc123" {
+		t.Errorf("expected assignee accountId abc123, got %+v", gotPayload.Fields.Assignee)
+	}
+	if created.Key != "PROJ-42" {
+		t.Errorf("expected key PROJ-42, got %s", created.Key)
+	}
+}
+
+func TestFindUsers_Endpoint(t *testing.T) {
+	var gotPath, gotQuery string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotQuery = r.URL.Query().Get("query")
+		json.NewEncoder(w).Encode([]User{{AccountID: "abc123", EmailAddress: "dev@example.com"}})
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	users, err := client.FindUsers(context.Background(), "dev@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotPath != "/rest/api/3/user/search" {
+		t.Errorf("expected path /rest/api/3/user/search, got %s", gotPath)
+	}
+	if gotQuery != "dev@example.com" {
+		t.Errorf("expected query dev@example.com, got %q", gotQuery)
+	}
+	if len(users) != 1 || users[0].AccountID != "abc123" {
+		t.Errorf("unexpected users: %+v", users)
+	}
+}
+
+func TestCommentEndpoints(t *testing.T) {
+	var calls []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch r.Method {
+		case "POST":
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(Comment{ID: "10100"})
+		case "PUT":
+			json.NewEncoder(w).Encode(Comment{ID: "10001"})
+		case "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	body := &ADFNode{Type: "doc"}
+
+	created, err := client.AddComment(context.Background(), "PROJ-1", body)
+	if err != nil {
+		t.Fatalf("AddComment: unexpected error: %v", err)
+	}
+	if created.ID != "10100" {
+		t.Errorf("expected created comment id 10100, got %s", created.ID)
+	}
+	if err := client.UpdateComment(context.Background(), "PROJ-1", "10001", body); err != nil {
+		t.Fatalf("UpdateComment: unexpected error: %v", err)
+	}
+	if err := client.DeleteComment(context.Background(), "PROJ-1", "10002"); err != nil {
+		t.Fatalf("DeleteComment: unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"POST /rest/api/3/issue/PROJ-1/comment",
+		"PUT /rest/api/3/issue/PROJ-1/comment/10001",
+		"DELETE /rest/api/3/issue/PROJ-1/comment/10002",
+	}
+	if len(calls) != len(expected) {
+		t.Fatalf("expected %d calls, got %v", len(expected), calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("call %d: expected %q, got %q", i, expected[i], calls[i])
+		}
+	}
+}
+
+func TestGetIssue_CustomFields(t *testing.T) {
+	var gotFields string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotFields = r.URL.Query().Get("fields")
+		w.Write([]byte(`{"key":"PROJ-1","fields":{"summary":"s","customfield_10016":5,"customfield_10020":null}}`))
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	issue, err := client.GetIssue(context.Background(), "PROJ-1", "customfield_10016", "customfield_10020")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasSuffix(gotFields, ",customfield_10016,customfield_10020") {
+		t.Errorf("expected custom fields to be requested, got %q", gotFields)
+	}
+	if issue.Fields.Summary != "s" {
+		t.Errorf("expected summary 's', got %q", issue.Fields.Summary)
+	}
+	if string(issue.Fields.Custom["customfield_10016"]) != "5" {
+		t.Errorf("expected customfield_10016 = 5, got %q", issue.Fields.Custom["customfield_10016"])
+	}
+	if _, ok := issue.Fields.Custom["customfield_10020"]; ok {
+		t.Error("expected null custom field to be omitted")
+	}
+}
+
+func TestUpdateFields_MarshalCustom(t *testing.T) {
+	data, err := json.Marshal(UpdateFields{
+		Summary: "s",
+		Custom: map[string]any{
+			"customfield_10016": 3.0,
+			"customfield_10030": map[string]string{"value": "High"},
+			"customfield_10040": nil,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{"customfield_10016":3,"customfield_10030":{"value":"High"},"customfield_10040":null,"summary":"s"}`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+}
+
+func TestUpdateFields_MarshalVersionLists(t *testing.T) {
+	// nil lists are left out; empty lists are sent so they clear the field
+	data, err := json.Marshal(UpdateFields{
+		Summary:     "s",
+		FixVersions: []Version{},
+		Components:  []Component{{Name: "API"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{"components":[{"name":"API"}],"fixVersions":[],"summary":"s"}`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+}
+
+func TestProjectComponentsAndVersions_Endpoints(t *testing.T) {
+	var paths []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/components") {
+			json.NewEncoder(w).Encode([]Component{{ID: "1", Name: "API"}})
+			return
+		}
+		json.NewEncoder(w).Encode([]Version{{ID: "2", Name: "1.0", Released: true}})
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	components, err := client.GetProjectComponents(context.Background(), "PRODUCT")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	versions, err := client.GetProjectVersions(context.Background(), "PRODUCT")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"/rest/api/3/project/PRODUCT/components", "/rest/api/3/project/PRODUCT/versions"}
+	if strings.Join(paths, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected paths %v, got %v", expected, paths)
+	}
+	if len(components) != 1 || components[0].Name != "API" {
+		t.Errorf("unexpected components: %+v", components)
+	}
+	if len(versions) != 1 || versions[0].Name != "1.0" || !versions[0].Released {
+		t.Errorf("unexpected versions: %+v", versions)
+	}
+}
+
+func TestIssueLinkEndpoints(t *testing.T) {
+	var calls []string
+	var gotLink IssueLink
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch r.Method {
+		case "GET":
+			w.Write([]byte(`{"issueLinkTypes":[{"id":"1","name":"Blocks","inward":"is blocked by","outward":"blocks"}]}`))
+		case "POST":
+			json.NewDecoder(r.Body).Decode(&gotLink)
+			w.WriteHeader(http.StatusCreated)
+		case "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	types, err := client.GetIssueLinkTypes(context.Background())
+	if err != nil {
+		t.Fatalf("GetIssueLinkTypes: %v", err)
+	}
+	if len(types) != 1 || types[0].Outward != "blocks" {
+		t.Errorf("unexpected link types: %+v", types)
+	}
+
+	link := IssueLink{
+		Type:         IssueLinkType{Name: "Blocks"},
+		InwardIssue:  &LinkedIssue{Key: "PRODUCT-1"},
+		OutwardIssue: &LinkedIssue{Key: "PRODUCT-2"},
+	}
+	if err := client.CreateIssueLink(context.Background(), link); err != nil {
+		t.Fatalf("CreateIssueLink: %v", err)
+	}
+	if err := client.DeleteIssueLink(context.Background(), "100"); err != nil {
+		t.Fatalf("DeleteIssueLink: %v", err)
+	}
+
+	expected := []string{
+		"GET /rest/api/3/issueLinkType",
+		"POST /rest/api/3/issueLink",
+		"DELETE /rest/api/3/issueLink/100",
+	}
+	if strings.Join(calls, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("expected calls %v, got %v", expected, calls)
+	}
+	if gotLink.Type.Name != "Blocks" || gotLink.InwardIssue.Key != "PRODUCT-1" || gotLink.OutwardIssue.Key != "PRODUCT-2" {
+		t.Errorf("unexpected link payload: %+v", gotLink)
+	}
+}
+
+func TestAttachmentEndpoints(t *testing.T) {
+	var gotToken, gotFilename, gotContent string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/rest/api/3/attachment/content/10":
+			w.Write([]byte("PNGDATA"))
+		case r.Method == "POST" && r.URL.Path == "/rest/api/3/issue/PRODUCT-1/attachments":
+			gotToken = r.Header.Get("X-Atlassian-Token")
+			f, header, err := r.FormFile("file")
+			if err != nil {
+				t.Errorf("reading multipart file: %v", err)
+				return
+			}
+			data, _ := io.ReadAll(f)
+			gotFilename, gotContent = header.Filename, string(data)
+			json.NewEncoder(w).Encode([]Attachment{{ID: "11", Filename: header.Filename, Size: int64(len(data))}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+
+	var buf bytes.Buffer
+	if err := client.DownloadAttachment(context.Background(), "10", &buf); err != nil {
+		t.Fatalf("DownloadAttachment: %v", err)
+	}
+	if buf.String() != "PNGDATA" {
+		t.Errorf("expected downloaded content PNGDATA, got %q", buf.String())
+	}
+
+	attachments, err := client.AddAttachment(context.Background(), "PRODUCT-1", "notes.txt", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("AddAttachment: %v", err)
+	}
+	if gotToken != "no-check" {
+		t.Errorf("expected X-Atlassian-Token no-check, got %q", gotToken)
+	}
+	if gotFilename != "notes.txt" || gotContent != "hello" {
+		t.Errorf("unexpected upload %q: %q", gotFilename, gotContent)
+	}
+	if len(attachments) != 1 || attachments[0].ID != "11" || attachments[0].Size != 5 {
+		t.Errorf("unexpected attachments: %+v", attachments)
+	}
+}
+
+func TestGetWorklogs_Pagination(t *testing.T) {
+	var starts []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/3/issue/PROJ-1/worklog" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		startAt := r.URL.Query().Get("startAt")
+		starts = append(starts, startAt)
+		if startAt == "0" {
+			json.NewEncoder(w).Encode(WorklogPage{Total: 3, Worklogs: []Worklog{{ID: "1"}, {ID: "2"}}})
+		} else {
+			json.NewEncoder(w).Encode(WorklogPage{StartAt: 2, Total: 3, Worklogs: []Worklog{{ID: "3"}}})
+		}
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	worklogs, err := client.GetWorklogs(context.Background(), "PROJ-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(worklogs) != 3 || worklogs[2].ID != "3" {
+		t.Errorf("expected 3 worklogs, got %+v", worklogs)
+	}
+	if strings.Join(starts, ",") != "0,2" {
+		t.Errorf("expected pages at startAt 0,2, got %v", starts)
+	}
+}
+
+func TestAddWorklog_Endpoint(t *testing.T) {
+	var gotPath, gotMethod string
+	var gotPayload WorklogPayload
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotMethod = r.Method
+		json.NewDecoder(r.Body).Decode(&gotPayload)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(Worklog{ID: "100", TimeSpentSeconds: gotPayload.TimeSpentSeconds})
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	worklog, err := client.AddWorklog(context.Background(), "PROJ-1", WorklogPayload{
+		TimeSpentSeconds: 9000,
+		Started:          "2025-01-15T09:00:00.000+0000",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotPath != "/rest/api/3/issue/PROJ-1/worklog" || gotMethod != "POST" {
+		t.Errorf("expected POST /rest/api/3/issue/PROJ-1/worklog, got %s %s", gotMethod, gotPath)
+	}
+	if gotPayload.TimeSpentSeconds != 9000 || gotPayload.Started != "2025-01-15T09:00:00.000+0000" {
+		t.Errorf("unexpected payload: %+v", gotPayload)
+	}
+	if worklog.ID != "100" {
+		t.Errorf("expected worklog id 100, got %s", worklog.ID)
+	}
+}
+
+func TestGetChangelog_Pagination(t *testing.T) {
+	var starts []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/3/issue/PROJ-1/changelog" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		startAt := r.URL.Query().Get("startAt")
+		starts = append(starts, startAt)
+		if startAt == "0" {
+			w.Write([]byte(`{"startAt":0,"total":2,"isLast":false,"values":[{"id":"1","created":"2024-01-15T10:00:00.000+0000","items":[{"field":"status","fromString":"To Do","toString":"In Progress"}]}]}`))
+		} else {
+			w.Write([]byte(`{"startAt":1,"total":2,"isLast":true,"values":[{"id":"2","items":[{"field":"assignee","to":"abc"}]}]}`))
+		}
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	entries, err := client.GetChangelog(context.Background(), "PROJ-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Join(starts, ",") != "0,1" {
+		t.Errorf("expected pages at startAt 0,1, got %v", starts)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if item := entries[0].Items[0]; item.Field != "status" || item.FromString != "To Do" || item.ToString != "In Progress" {
+		t.Errorf("unexpected first change: %+v", item)
+	}
+}
+
+func TestTransitionEndpoints(t *testing.T) {
+	var gotExpand string
+	var gotPayload map[string]any
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/3/issue/PROJ-1/transitions" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Method == "GET" {
+			gotExpand = r.URL.Query().Get("expand")
+			w.Write([]byte(`{"transitions":[{"id":"31","name":"Close","to":{"name":"Done"},"fields":{"resolution":{"required":true,"name":"Resolution","schema":{"type":"resolution"},"allowedValues":[{"id":"1","name":"Fixed"}]}}}]}`))
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&gotPayload)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	transitions, err := client.GetTransitions(context.Background(), "PROJ-1")
+	if err != nil {
+		t.Fatalf("GetTransitions: %v", err)
+	}
+	if gotExpand != "transitions.fields" {
+		t.Errorf("expected expand=transitions.fields, got %q", gotExpand)
+	}
+	f, ok := transitions[0].Fields["resolution"]
+	if !ok || !f.Required || len(f.AllowedValues) != 1 || f.AllowedValues[0].Name != "Fixed" {
+		t.Errorf("unexpected transition fields: %+v", transitions[0].Fields)
+	}
+
+	if err := client.DoTransition(context.Background(), "PROJ-1", "31", map[string]any{"resolution": map[string]string{"id": "1"}}); err != nil {
+		t.Fatalf("DoTransition: %v", err)
+	}
+	data, _ := json.Marshal(gotPayload)
+	expected := `{"fields":{"resolution":{"id":"1"}},"transition":{"id":"31"}}`
+	if string(data) != expected {
+		t.Errorf("expected payload %s, got %s", expected, data)
+	}
+}
+
+func TestWorkflowEndpoints(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/3/workflowscheme/project":
+			if r.URL.Query().Get("projectId") != "10000" {
+				t.Errorf("unexpected projectId: %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"values":[{"projectIds":["10000"],"workflowScheme":{"name":"PROJ scheme","defaultWorkflow":"jira","issueTypeMappings":{"10001":"PROJ bug flow"}}}]}`))
+		case "/rest/api/3/workflow/search":
+			if r.URL.Query().Get("workflowName") != "PROJ bug flow" || r.URL.Query().Get("expand") != "transitions,statuses" {
+				t.Errorf("unexpected query: %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"values":[{"id":{"name":"PROJ bug flow"},"statuses":[{"id":"1","name":"To Do"},{"id":"3","name":"In Progress"}],"transitions":[{"id":"11","name":"Start","from":["1"],"to":"3","type":"directed"},{"id":"21","name":"Reset","from":[],"to":"1","type":"global"}]}]}`))
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	scheme, err := client.GetProjectWorkflowScheme(context.Background(), "10000")
+	if err != nil {
+		t.Fatalf("GetProjectWorkflowScheme: %v", err)
+	}
+	if scheme.DefaultWorkflow != "jira" || scheme.IssueTypeMappings["10001"] != "PROJ bug flow" {
+		t.Errorf("unexpected scheme: %+v", scheme)
+	}
+
+	wf, err := client.GetWorkflow(context.Background(), "PROJ bug flow")
+	if err != nil {
+		t.Fatalf("GetWorkflow: %v", err)
+	}
+	if len(wf.Statuses) != 2 || len(wf.Transitions) != 2 {
+		t.Fatalf("unexpected workflow: %+v", wf)
+	}
+	if tr := wf.Transitions[0]; tr.ID != "11" || tr.To != "3" || len(tr.From) != 1 || tr.From[0] != "1" {
+		t.Errorf("unexpected transition: %+v", tr)
+	}
+	if tr := wf.Transitions[1]; tr.Type != "global" || len(tr.From) != 0 {
+		t.Errorf("expected global transition, got %+v", tr)
+	}
+}
+
+// noSleep makes the client record retry waits instead of sleeping.
+func noSleep(client *Client) *[]time.Duration {
+	var waits []time.Duration
+	client.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	client.httpClient.Transport.(*rateLimiter).sleep = func(context.Context, time.Duration) error { return nil }
+	return &waits
+}
+
+func TestSend_RetriesRateLimitAndServerErrors(t *testing.T) {
+	var calls int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{"key":"PROJ-1"}`))
+		}
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	waits := noSleep(client)
+
+	issue, err := client.GetIssue(context.Background(), "PROJ-1")
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if issue.Key != "PROJ-1" || calls != 3 {
+		t.Fatalf("expected success on the third attempt, got %q after %d calls", issue.Key, calls)
+	}
+	if len(*waits) != 2 || (*waits)[0] != 3*time.Second {
+		t.Fatalf("expected Retry-After wait then a backoff, got %v", *waits)
+	}
+	if b := (*waits)[1]; b < defaultRetryPolicy.baseDelay<<1/2 || b > defaultRetryPolicy.baseDelay<<1 {
+		t.Errorf("backoff %v outside jitter range", b)
+	}
+}
+
+func TestSend_RetriesSearchWithBody(t *testing.T) {
+	var bodies []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"issues":[{"key":"TEST-1"}],"isLast":true}`))
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	noSleep(client)
+
+	result, err := client.SearchIssues(context.Background(), "project = TEST", 10)
+	if err != nil {
+		t.Fatalf("SearchIssues: %v", err)
+	}
+	if len(result.Issues) != 1 || len(bodies) != 2 || bodies[0] != bodies[1] || bodies[1] == "" {
+		t.Errorf("expected the search body to be resent, got %q", bodies)
+	}
+}
+
+func TestSend_DoesNotRetryCreate(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	noSleep(client)
+
+	if _, err := client.CreateIssue(context.Background(), CreatePayload{}); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Errorf("expected POST to be sent once, got %d calls", calls)
+	}
+}
+
+func TestSend_CapsTotalWait(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "40")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	waits := noSleep(client)
+
+	_, err := client.GetIssue(context.Background(), "PROJ-1")
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Fatalf("expected the 429 to be returned, got %v", err)
+	}
+	// A second 40s wait would exceed the 60s cap.
+	if calls != 2 || len(*waits) != 1 {
+		t.Errorf("expected 2 calls and 1 wait, got %d calls and waits %v", calls, *waits)
+	}
+}
+
+func TestSend_GivesUpAfterMaxRetries(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusGatewayTimeout)
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	noSleep(client)
+
+	if _, err := client.GetIssue(context.Background(), "PROJ-1"); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != defaultRetryPolicy.maxRetries+1 {
+		t.Errorf("expected %d calls, got %d", defaultRetryPolicy.maxRetries+1, calls)
+	}
+}
+
+func TestSend_StopsWaitingWhenCancelled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := client.GetIssue(ctx, "PROJ-1")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancellation took %v", elapsed)
+	}
+}
+
+func TestClient_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	client := NewClient(config.Config{URL: srv.URL, Email: "test@example.com", Token: "test-token", Timeout: "100ms"})
+	noSleep(client)
+
+	_, err := client.GetIssue(context.Background(), "PROJ-1")
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"3", 3 * time.Second, true},
+		{"3600", maxRateLimitPause, true},
+		{"-1", 0, true},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+		{"", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := retryAfter(tt.header); got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %v, %v; want %v, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
 func TestGetConfluencePage_Endpoint(t *testing.T) {
 	var gotPath, gotMethod string
 
@@ -100,7 +867,7 @@ func TestGetConfluencePage_Endpoint(t *testing.T) {
 	defer srv.Close()
 
 	client := testClient(srv.URL)
-	page, err := client.GetConfluencePage("12345")
+	page, err := client.GetConfluencePage(context.Background(), "12345")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -128,7 +895,7 @@ func TestSearchConfluence_Endpoint(t *testing.T) {
 	defer srv.Close()
 
 	client := testClient(srv.URL)
-	_, err := client.SearchConfluence("type = page", 10, 0)
+	_, err := client.SearchConfluence(context.Background(), "type = page", 10)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -144,6 +911,45 @@ func TestSearchConfluence_Endpoint(t *testing.T) {
 	}
 }
 
+func TestSearchConfluence_Pagination(t *testing.T) {
+	var requests []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/wiki/rest/api/content/search" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		requests = append(requests, r.URL.RawQuery)
+		if r.URL.Query().Get("cursor") == "" {
+			w.Write([]byte(`{"results":[{"content":{"id":"1"}},{"content":{"id":"2"}}],"_links":{"next":"/rest/api/content/search?cql=type%3Dpage&cursor=abc&limit=2"}}`))
+			return
+		}
+		w.Write([]byte(`{"results":[{"content":{"id":"3"}}],"_links":{}}`))
+	}))
+	defer srv.Close()
+
+	client := testClient(srv.URL)
+	result, err := client.SearchConfluence(context.Background(), "type=page", 0)
+	if err != nil {
+		t.Fatalf("SearchConfluence: %v", err)
+	}
+	if len(result.Results) != 3 || result.Results[2].Content.ID != "3" {
+		t.Errorf("unexpected results: %+v", result.Results)
+	}
+	if len(requests) != 2 || !strings.Contains(requests[1], "cursor=abc") {
+		t.Errorf("expected the next link to be followed, got %q", requests)
+	}
+
+	// A limit inside the first page stops without following the next link.
+	requests = nil
+	result, err = client.SearchConfluence(context.Background(), "type=page", 1)
+	if err != nil {
+		t.Fatalf("SearchConfluence: %v", err)
+	}
+	if len(result.Results) != 1 || len(requests) != 1 {
+		t.Errorf("expected 1 result from 1 request, got %d from %d", len(result.Results), len(requests))
+	}
+}
+
 func TestSetHeaders(t *testing.T) {
 	var gotAuth, gotContentType, gotAccept string
 
@@ -156,7 +962,7 @@ func TestSetHeaders(t *testing.T) {
 	defer srv.Close()
 
 	client := testClient(srv.URL)
-	client.GetIssue("X-1")
+	client.GetIssue(context.Background(), "X-1")
 
 	if gotAuth == "" {
 		t.Error("expected Authorization header to be set")
@@ -179,10 +985,48 @@ func TestAPIError_NonOK(t *testing.T) {
 	defer srv.Close()
 
 	client := testClient(srv.URL)
-	_, err := client.GetIssue("NOPE-999")
+	_, err := client.GetIssue(context.Background(), "NOPE-999")
 	if err == nil {
 		t.Fatal("expected error for 404 response")
 	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || len(apiErr.Details.ErrorMessages) != 1 || apiErr.Details.ErrorMessages[0] != "Issue does not exist" {
+		t.Errorf("expected parsed JIRA errors, got %#v", apiErr)
+	}
+	if !strings.Contains(err.Error(), "JIRA API 404: Issue does not exist") {
+		t.Errorf("unexpected message: %v", err)
+	}
+}
+
+func TestAPIError_Is(t *testing.T) {
+	tests := []struct {
+		status int
+		target error
+	}{
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrUnauthorized},
+		{http.StatusConflict, ErrConflict},
+		{http.StatusTooManyRequests, ErrRateLimited},
+	}
+	targets := []error{ErrNotFound, ErrUnauthorized, ErrConflict, ErrRateLimited}
+
+	for _, tt := range tests {
+		err := error(newAPIError(serviceConfluence, tt.status, []byte(`{"errors":[{"title":"nope"}]}`)))
+		for _, target := range targets {
+			if got := errors.Is(err, target); got != (target == tt.target) {
+				t.Errorf("status %d: errors.Is(%v) = %v", tt.status, target, got)
+			}
+		}
+	}
+
+	err := newAPIError(serviceConfluence, http.StatusConflict, []byte(`{"errors":[{"title":"Version must be incremented"}]}`))
+	if got := err.Error(); got != `Confluence API 409: {"errors":[{"title":"Version must be incremented"}]}` {
+		t.Errorf("unexpected message: %s", got)
+	}
 }
 
 func TestGetConfluenceChildPages_Endpoint(t *testing.T) {
@@ -209,7 +1053,7 @@ func TestGetConfluenceChildPages_Endpoint(t *testing.T) {
 	defer srv.Close()
 
 	client := testClient(srv.URL)
-	children, err := client.GetConfluenceChildPages("12345")
+	children, err := client.GetConfluenceChildPages(context.Background(), "12345")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -255,7 +1099,7 @@ func TestGetConfluenceFooterComments_Endpoint(t *testing.T) {
 	defer srv.Close()
 
 	client := testClient(srv.URL)
-	comments, err := client.GetConfluenceFooterComments("12345")
+	comments, err := client.GetConfluenceFooterComments(context.Background(), "12345")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -308,7 +1152,7 @@ func TestGetConfluenceInlineComments_Endpoint(t *testing.T) {
 	defer srv.Close()
 
 	client := testClient(srv.URL)
-	comments, err := client.GetConfluenceInlineComments("12345")
+	comments, err := client.GetConfluenceInlineComments(context.Background(), "12345")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -339,7 +1183,7 @@ func TestGetConfluenceInlineComments_404_ReturnsNil(t *testing.T) {
 	defer srv.Close()
 
 	client := testClient(srv.URL)
-	comments, err := client.GetConfluenceInlineComments("12345")
+	comments, err := client.GetConfluenceInlineComments(context.Background(), "12345")
 	if err != nil {
 		t.Fatalf("expected nil error on 404, got: %v", err)
 	}
@@ -368,7 +1212,7 @@ func TestIntegration_GetIssue(t *testing.T) {
 		t.Skip("skipping: TEST_JIRA_ISSUE_KEY not set")
 	}
 
-	issue, err := client.GetIssue(issueKey)
+	issue, err := client.GetIssue(context.Background(), issueKey)
 	if err != nil {
 		t.Fatalf("GetIssue(%s) failed: %v", issueKey, err)
 	}
@@ -387,12 +1231,12 @@ func TestIntegration_SearchIssues(t *testing.T) {
 		t.Skip("skipping: TEST_JIRA_ISSUE_KEY not set")
 	}
 
-	result, err := client.SearchIssues("key = "+issueKey, 5, 0)
+	result, err := client.SearchIssues(context.Background(), "key = "+issueKey, 5)
 	if err != nil {
 		t.Fatalf("SearchIssues failed: %v", err)
 	}
-	if result.Total < 1 {
-		t.Errorf("expected at least 1 result, got %d", result.Total)
+	if len(result.Issues) < 1 {
+		t.Errorf("expected at least 1 result, got %d", len(result.Issues))
 	}
 	found := false
 	for _, issue := range result.Issues {
@@ -413,7 +1257,7 @@ func TestIntegration_GetConfluencePage(t *testing.T) {
 		t.Skip("skipping: TEST_CONFLUENCE_PAGE_ID not set")
 	}
 
-	page, err := client.GetConfluencePage(pageID)
+	page, err := client.GetConfluencePage(context.Background(), pageID)
 	if err != nil {
 		t.Fatalf("GetConfluencePage(%s) failed: %v", pageID, err)
 	}
@@ -424,3 +1268,72 @@ func TestIntegration_GetConfluencePage(t *testing.T) {
 		t.Error("expected non-empty title")
 	}
 }
+
+func TestOAuthRoutesThroughGateway(t *testing.T) {
+	var paths, auths []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		auths = append(auths, r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/ex/jira/cloud-1/rest/api/3/issue/PROJ-1":
+			w.Write([]byte(`{"key":"PROJ-1","fields":{"summary":"Gateway"}}`))
+		case "/ex/confluence/cloud-1/wiki/api/v2/pages/42/children":
+			if r.URL.Query().Get("cursor") == "" {
+				w.Write([]byte(`{"results":[{"id":"1"}],"_links":{"next":"/wiki/api/v2/pages/42/children?cursor=abc"}}`))
+				return
+			}
+			w.Write([]byte(`{"results":[{"id":"2"}],"_links":{}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	gateway := oauth.GatewayURL
+	oauth.GatewayURL = srv.URL
+	defer func() { oauth.GatewayURL = gateway }()
+
+	client := NewClient(config.Config{
+		URL:   "https://example.atlassian.net",
+		OAuth: &config.OAuth{ClientID: "client", CloudID: "cloud-1", RefreshToken: "refresh"},
+	}, WithTokenSource(oauth.StaticToken("access")))
+	ctx := context.Background()
+
+	issue, err := client.GetIssue(ctx, "PROJ-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Fields.Summary != "Gateway" {
+		t.Errorf("unexpected issue: %+v", issue)
+	}
+	children, err := client.GetConfluenceChildPages(ctx, "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(children) != 2 {
+		t.Errorf("expected both pages of children through the gateway, got %+v (requests %v)", children, paths)
+	}
+	for _, auth := range auths {
+		if auth != "Bearer access" {
+			t.Errorf("expected the OAuth access token, got %q", auth)
+		}
+	}
+}
+
+func TestOAuthTokenError(t *testing.T) {
+	client := NewClient(config.Config{
+		URL:   "https://example.atlassian.net",
+		OAuth: &config.OAuth{ClientID: "client", CloudID: "cloud-1", RefreshToken: "refresh"},
+	}, WithTokenSource(failingTokens{}))
+
+	if _, err := client.GetIssue(context.Background(), "PROJ-1"); err == nil || !strings.Contains(err.Error(), "refresh token expired") {
+		t.Errorf("expected the token error, got %v", err)
+	}
+}
+
+type failingTokens struct{}
+
+func (failingTokens) AccessToken(context.Context) (string, error) {
+	return "", errors.New("refresh token expired")
+}
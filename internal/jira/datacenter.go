@@ -0,0 +1,407 @@
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// Data Center speaks JIRA API v2 and the Confluence v1 content API. Most
+// JIRA endpoints only differ in the version in their path; the ones that
+// differ in shape, and every Confluence call, go through the methods here.
+
+// errWorkflowsUnavailable is returned for the workflow reads, whose
+// endpoints only exist on Cloud. Callers fall back to exploring transitions.
+var errWorkflowsUnavailable = errors.New("reading workflows isn't supported on Data Center")
+
+// wikiPayload rewrites every ADF document in a JIRA request body as wiki
+// markup, which is how API v2 takes descriptions, comments and rich text
+// custom fields.
+func wikiPayload(data []byte) ([]byte, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(adfToWikiValue(v))
+}
+
+func adfToWikiValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		if t["type"] == "doc" {
+			data, err := json.Marshal(t)
+			var doc ADFNode
+			if err == nil && json.Unmarshal(data, &doc) == nil {
+				return ADFToWiki(&doc)
+			}
+		}
+		for k, e := range t {
+			t[k] = adfToWikiValue(e)
+		}
+	case []any:
+		for i, e := range t {
+			t[i] = adfToWikiValue(e)
+		}
+	}
+	return v
+}
+
+// searchV2 is the response from POST /rest/api/2/search.
+type searchV2 struct {
+	StartAt int     `json:"startAt"`
+	Total   int     `json:"total"`
+	Issues  []Issue `json:"issues"`
+}
+
+// searchV2Payload is the body for POST /rest/api/2/search.
+type searchV2Payload struct {
+	JQL        string   `json:"jql"`
+	StartAt    int      `json:"startAt"`
+	MaxResults int      `json:"maxResults"`
+	Fields     []string `json:"fields,omitempty"`
+}
+
+// searchIssuesPagesV2 is SearchIssuesPages for Data Center, which pages by
+// offset. The offset of the next page is passed on as NextPageToken.
+func (c *Client) searchIssuesPagesV2(ctx context.Context, jql string, limit int, fn func(page *SearchResult) error) error {
+	read := 0
+	for {
+		size := searchPageSize
+		if limit > 0 && limit-read < size {
+			size = limit - read
+		}
+
+		result, err := do[searchV2](ctx, c, request{
+			method:   "POST",
+			url:      fmt.Sprintf("%s/search", c.apiURL),
+			json:     searchV2Payload{JQL: jql, StartAt: read, MaxResults: size, Fields: searchFields},
+			readOnly: true,
+		})
+		if err != nil {
+			return err
+		}
+
+		read += len(result.Issues)
+		page := SearchResult{Issues: result.Issues, IsLast: read >= result.Total || len(result.Issues) == 0}
+		if !page.IsLast {
+			page.NextPageToken = strconv.Itoa(read)
+		}
+		if err := fn(&page); err != nil {
+			return err
+		}
+
+		if page.IsLast || (limit > 0 && read >= limit) {
+			return nil
+		}
+	}
+}
+
+// approximateIssueCountV2 reads the total of an empty page of results.
+func (c *Client) approximateIssueCountV2(ctx context.Context, jql string) (int, error) {
+	result, err := do[searchV2](ctx, c, request{
+		method:   "POST",
+		url:      fmt.Sprintf("%s/search", c.apiURL),
+		json:     searchV2Payload{JQL: jql, MaxResults: 0, Fields: []string{"key"}},
+		readOnly: true,
+	})
+	return result.Total, err
+}
+
+// getChangelogV2 reads the changelog expanded on the issue, since Data
+// Center has no changelog endpoint.
+func (c *Client) getChangelogV2(ctx context.Context, key string) ([]ChangelogEntry, error) {
+	result, err := do[struct {
+		Changelog struct {
+			Histories []ChangelogEntry `json:"histories"`
+		} `json:"changelog"`
+	}](ctx, c, request{
+		method: "GET",
+		url:    fmt.Sprintf("%s/issue/%s?fields=created&expand=changelog", c.apiURL, key),
+	})
+	return result.Changelog.Histories, err
+}
+
+// attachmentContentURL returns where to download an attachment from. Data
+// Center has no content endpoint; the attachment's metadata links to it.
+func (c *Client) attachmentContentURL(ctx context.Context, attachmentID string) (string, error) {
+	if !c.dataCenter {
+		return fmt.Sprintf("%s/attachment/content/%s", c.apiURL, attachmentID), nil
+	}
+	meta, err := do[struct {
+		Content string `json:"content"`
+	}](ctx, c, request{
+		method: "GET",
+		url:    fmt.Sprintf("%s/attachment/%s", c.apiURL, attachmentID),
+	})
+	if err != nil {
+		return "", err
+	}
+	if meta.Content == "" {
+		return "", fmt.Errorf("attachment %s has no content link", attachmentID)
+	}
+	return meta.Content, nil
+}
+
+// contentV1 is a page or comment from the Confluence v1 content API.
+type contentV1 struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Title  string `json:"title"`
+	Space  struct {
+		Key  string `json:"key"`
+		Name string `json:"name"`
+	} `json:"space"`
+	Version struct {
+		Number int    `json:"number"`
+		When   string `json:"when"`
+		By     struct {
+			UserKey string `json:"userKey"`
+		} `json:"by"`
+	} `json:"version"`
+	Body struct {
+		Storage PageBodyFormat `json:"storage"`
+	} `json:"body"`
+	Extensions struct {
+		Resolution *struct {
+			Status string `json:"status"`
+		} `json:"resolution,omitempty"`
+		InlineProperties map[string]any `json:"inlineProperties,omitempty"`
+	} `json:"extensions"`
+	Links PageLinks `json:"_links"`
+}
+
+// contentV1List is a page of results from a v1 content listing.
+type contentV1List struct {
+	Results []contentV1     `json:"results"`
+	Links   PaginationLinks `json:"_links"`
+}
+
+// spaceV1 is a space from the v1 API. Spaces are addressed by key there.
+type spaceV1 struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+// contentV1Expand is what the v1 API is asked to include with a page.
+const contentV1Expand = "body.storage,version,space"
+
+// pageV1 converts a v1 page, with its storage body converted to ADF. On Data
+// Center SpaceID holds the space key.
+func (c *Client) pageV1(content contentV1) (*ConfluencePage, error) {
+	page := &ConfluencePage{
+		ID:      content.ID,
+		Title:   content.Title,
+		Status:  content.Status,
+		SpaceID: content.Space.Key,
+		Version: PageVersion{Number: content.Version.Number, CreatedAt: content.Version.When, AuthorID: content.Version.By.UserKey},
+		Links:   content.Links,
+	}
+	if page.Links.Base == "" {
+		page.Links.Base = c.wikiURL
+	}
+
+	doc, err := StorageToADF(content.Body.Storage.Value)
+	if err != nil {
+		return nil, fmt.Errorf("page %s: %w", content.ID, err)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	page.Body.AtlasDocFormat = &PageBodyFormat{Value: string(data), Representation: "atlas_doc_format"}
+	return page, nil
+}
+
+func commentV1(content contentV1) ConfluenceComment {
+	comment := ConfluenceComment{
+		ID:      content.ID,
+		Status:  content.Status,
+		Title:   content.Title,
+		Version: ConfluenceCommentVersion{CreatedAt: content.Version.When, Number: content.Version.Number, AuthorID: content.Version.By.UserKey},
+		Body:    CommentBody{Storage: &PageBodyFormat{Value: content.Body.Storage.Value, Representation: "storage"}},
+	}
+	if r := content.Extensions.Resolution; r != nil {
+		comment.ResolutionStatus = r.Status
+		if r.Status == "reopened" {
+			comment.ResolutionStatus = "open"
+		}
+	}
+	if sel, ok := content.Extensions.InlineProperties["originalSelection"]; ok {
+		comment.Properties = map[string]interface{}{"inline-original-selection": sel}
+	}
+	return comment
+}
+
+// storageBody returns the storage format value of a page body given in
+// either storage or ADF representation.
+func storageBody(body ConfluenceUpdateBody) (map[string]any, error) {
+	value := body.Value
+	if body.Representation != "storage" {
+		var doc ADFNode
+		if err := json.Unmarshal([]byte(body.Value), &doc); err != nil {
+			return nil, fmt.Errorf("reading ADF body: %w", err)
+		}
+		value = ADFToStorage(&doc)
+	}
+	return map[string]any{"storage": map[string]string{"value": value, "representation": "storage"}}, nil
+}
+
+func (c *Client) getConfluencePageV1(ctx context.Context, pageID string) (*ConfluencePage, error) {
+	content, err := do[contentV1](ctx, c, request{
+		method:  "GET",
+		url:     fmt.Sprintf("%s/rest/api/content/%s?expand=%s", c.wikiURL, pageID, contentV1Expand),
+		service: serviceConfluence,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.pageV1(content)
+}
+
+func (c *Client) getConfluenceSpaceV1(ctx context.Context, spaceKey string) (*ConfluenceSpace, error) {
+	space, err := do[spaceV1](ctx, c, request{
+		method:  "GET",
+		url:     fmt.Sprintf("%s/rest/api/space/%s", c.wikiURL, url.PathEscape(spaceKey)),
+		service: serviceConfluence,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ConfluenceSpace{ID: space.Key, Key: space.Key, Name: space.Name}, nil
+}
+
+func (c *Client) listConfluenceSpacesV1(ctx context.Context, limit int) ([]ConfluenceSpace, error) {
+	result, err := do[struct {
+		Results []spaceV1 `json:"results"`
+	}](ctx, c, request{
+		method:  "GET",
+		url:     fmt.Sprintf("%s/rest/api/space?limit=%d", c.wikiURL, limit),
+		service: serviceConfluence,
+	})
+	if err != nil {
+		return nil, err
+	}
+	spaces := make([]ConfluenceSpace, len(result.Results))
+	for i, space := range result.Results {
+		spaces[i] = ConfluenceSpace{ID: space.Key, Key: space.Key, Name: space.Name}
+	}
+	return spaces, nil
+}
+
+func (c *Client) getConfluenceSpaceOperationsV1(ctx context.Context, spaceKey string) ([]ConfluenceOperation, error) {
+	space, err := do[struct {
+		Operations []ConfluenceOperation `json:"operations"`
+	}](ctx, c, request{
+		method:  "GET",
+		url:     fmt.Sprintf("%s/rest/api/space/%s?expand=operations", c.wikiURL, url.PathEscape(spaceKey)),
+		service: serviceConfluence,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return space.Operations, nil
+}
+
+func (c *Client) createConfluencePageV1(ctx context.Context, payload ConfluenceCreatePayload) (*ConfluencePage, error) {
+	body, err := storageBody(payload.Body)
+	if err != nil {
+		return nil, err
+	}
+	create := map[string]any{
+		"type":  "page",
+		"title": payload.Title,
+		"space": map[string]string{"key": payload.SpaceID},
+		"body":  body,
+	}
+	if payload.ParentID != "" {
+		create["ancestors"] = []map[string]string{{"id": payload.ParentID}}
+	}
+
+	content, err := do[contentV1](ctx, c, request{
+		method:  "POST",
+		url:     fmt.Sprintf("%s/rest/api/content?expand=%s", c.wikiURL, contentV1Expand),
+		json:    create,
+		service: serviceConfluence,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.pageV1(content)
+}
+
+func (c *Client) updateConfluencePageV1(ctx context.Context, pageID string, payload ConfluenceUpdatePayload) error {
+	body, err := storageBody(payload.Body)
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, request{
+		method: "PUT",
+		url:    fmt.Sprintf("%s/rest/api/content/%s", c.wikiURL, pageID),
+		json: map[string]any{
+			"id":      payload.ID,
+			"type":    "page",
+			"status":  payload.Status,
+			"title":   payload.Title,
+			"body":    body,
+			"version": payload.Version,
+		},
+		service: serviceConfluence,
+	})
+}
+
+// listContentV1 reads every page of a v1 content listing. Next links are
+// relative to the Confluence context path.
+func (c *Client) listContentV1(ctx context.Context, apiURL string) ([]contentV1, error) {
+	var all []contentV1
+	for apiURL != "" {
+		result, err := do[contentV1List](ctx, c, request{method: "GET", url: apiURL, service: serviceConfluence})
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, result.Results...)
+
+		if result.Links.Next != "" && len(result.Results) > 0 {
+			apiURL = c.wikiURL + result.Links.Next
+		} else {
+			apiURL = ""
+		}
+	}
+	return all, nil
+}
+
+func (c *Client) getConfluenceChildPagesV1(ctx context.Context, pageID string) ([]ConfluenceChildPage, error) {
+	contents, err := c.listContentV1(ctx, fmt.Sprintf("%s/rest/api/content/%s/child/page?limit=50", c.wikiURL, pageID))
+	if err != nil {
+		return nil, err
+	}
+	children := make([]ConfluenceChildPage, 0, len(contents))
+	for _, content := range contents {
+		children = append(children, ConfluenceChildPage{ID: content.ID, Title: content.Title, Status: content.Status})
+	}
+	return children, nil
+}
+
+// getConfluenceCommentsV1 reads the footer or inline comments on a page,
+// replies included.
+func (c *Client) getConfluenceCommentsV1(ctx context.Context, pageID, location string) ([]ConfluenceComment, error) {
+	expand := "body.storage,version"
+	if location == "inline" {
+		expand += ",extensions.inlineProperties,extensions.resolution"
+	}
+	contents, err := c.listContentV1(ctx, fmt.Sprintf("%s/rest/api/content/%s/child/comment?location=%s&depth=all&limit=50&expand=%s", c.wikiURL, pageID, location, expand))
+	if err != nil {
+		return nil, err
+	}
+	comments := make([]ConfluenceComment, 0, len(contents))
+	for _, content := range contents {
+		comments = append(comments, commentV1(content))
+	}
+	return comments, nil
+}
@@ -0,0 +1,288 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mreider/a-cli/internal/config"
+)
+
+func dataCenterClient(baseURL string) *Client {
+	return NewClient(config.Config{
+		URL:    baseURL,
+		Token:  "dc-pat",
+		Flavor: config.FlavorDataCenter,
+	})
+}
+
+func TestDataCenter_GetIssue(t *testing.T) {
+	var gotPath, gotAuth string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		w.Write([]byte(`{"key":"OPS-1","fields":{"summary":"Disk full","description":"h2. Steps\n\nRun *df* on [the host|https://host.example]","comment":{"comments":[{"id":"7","body":"Seen on {{db1}}"}]}}}`))
+	}))
+	defer srv.Close()
+
+	issue, err := dataCenterClient(srv.URL).GetIssue(context.Background(), "OPS-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gotPath != "/rest/api/2/issue/OPS-1" {
+		t.Errorf("expected path /rest/api/2/issue/OPS-1, got %s", gotPath)
+	}
+	if gotAuth != "Bearer dc-pat" {
+		t.Errorf("expected bearer auth, got %q", gotAuth)
+	}
+
+	desc := issue.Fields.Description
+	if desc == nil || desc.Type != "doc" || len(desc.Content) != 2 || desc.Content[0].Type != "heading" {
+		t.Fatalf("expected description converted to ADF, got %+v", desc)
+	}
+	para := desc.Content[1].Content
+	if len(para) != 4 || para[1].Text != "df" || para[1].Marks[0].Type != "strong" || para[3].Marks[0].Attrs["href"] != "https://host.example" {
+		t.Errorf("unexpected paragraph: %+v", para)
+	}
+	body := issue.Fields.Comment.Comments[0].Body
+	if body == nil || body.Content[0].Content[1].Marks[0].Type != "code" {
+		t.Errorf("expected comment converted to ADF, got %+v", body)
+	}
+}
+
+func TestDataCenter_UpdateIssueSendsWikiMarkup(t *testing.T) {
+	var got map[string]map[string]any
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/issue/OPS-1" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	desc := WikiToADF("Restart *nginx*\n\n* first\n* second")
+	err := dataCenterClient(srv.URL).UpdateIssue(context.Background(), "OPS-1", UpdatePayload{
+		Fields: UpdateFields{Summary: "Disk full", Description: desc, Assignee: &AccountRef{Name: "jdoe"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got["fields"]["description"] != "Restart *nginx*\n\n* first\n* second" {
+		t.Errorf("expected wiki markup description, got %#v", got["fields"]["description"])
+	}
+	if assignee, _ := got["fields"]["assignee"].(map[string]any); assignee["name"] != "jdoe" || assignee["accountId"] != nil {
+		t.Errorf("expected assignee by name, got %#v", got["fields"]["assignee"])
+	}
+}
+
+func TestDataCenter_SearchIssuesPagesByOffset(t *testing.T) {
+	var starts []int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/search" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var payload searchV2Payload
+		json.NewDecoder(r.Body).Decode(&payload)
+		starts = append(starts, payload.StartAt)
+
+		result := searchV2{StartAt: payload.StartAt, Total: 150}
+		for i := payload.StartAt; i < 150 && i < payload.StartAt+payload.MaxResults; i++ {
+			result.Issues = append(result.Issues, Issue{Key: "OPS"})
+		}
+		json.NewEncoder(w).Encode(result)
+	}))
+	defer srv.Close()
+
+	result, err := dataCenterClient(srv.URL).SearchIssues(context.Background(), "project = OPS", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Issues) != 150 || !result.IsLast {
+		t.Errorf("expected all 150 issues, got %d (last %v)", len(result.Issues), result.IsLast)
+	}
+	if len(starts) != 2 || starts[0] != 0 || starts[1] != 100 {
+		t.Errorf("expected pages at 0 and 100, got %v", starts)
+	}
+}
+
+func TestDataCenter_ConfluencePage(t *testing.T) {
+	var updated map[string]any
+	var gotAuth string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/confluence/rest/api/content/42":
+			if r.URL.Query().Get("expand") != contentV1Expand {
+				t.Errorf("unexpected expand %q", r.URL.Query().Get("expand"))
+			}
+			w.Write([]byte(`{"id":"42","title":"Runbook","status":"current","space":{"id":98,"key":"OPS","name":"Operations"},
+				"version":{"number":3,"when":"2025-01-15T09:00:00.000Z"},
+				"body":{"storage":{"value":"<p>Hello <strong>ops</strong></p><ac:structured-macro ac:name=\"info\"><ac:rich-text-body><p>Note</p></ac:rich-text-body></ac:structured-macro>","representation":"storage"}},
+				"_links":{"webui":"/display/OPS/Runbook","base":"https://wiki.example/confluence"}}`))
+		case r.Method == "PUT" && r.URL.Path == "/confluence/rest/api/content/42":
+			json.NewDecoder(r.Body).Decode(&updated)
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewClient(config.Config{
+		URL:             "https://jira.example",
+		Token:           "jira-pat",
+		Flavor:          config.FlavorDataCenter,
+		ConfluenceURL:   srv.URL + "/confluence/",
+		ConfluenceToken: "wiki-pat",
+	})
+	ctx := context.Background()
+
+	page, err := client.GetConfluencePage(ctx, "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotAuth != "Bearer wiki-pat" {
+		t.Errorf("expected the Confluence token, got %q", gotAuth)
+	}
+	if page.SpaceID != "OPS" || page.Version.Number != 3 || page.Links.Base+page.Links.WebUI != "https://wiki.example/confluence/display/OPS/Runbook" {
+		t.Errorf("unexpected page: %+v", page)
+	}
+
+	var doc ADFNode
+	if err := json.Unmarshal([]byte(page.Body.AtlasDocFormat.Value), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Content) != 2 || doc.Content[0].Type != "paragraph" || doc.Content[1].Type != "extension" {
+		t.Fatalf("unexpected ADF: %s", page.Body.AtlasDocFormat.Value)
+	}
+
+	err = client.UpdateConfluencePage(ctx, "42", ConfluenceUpdatePayload{
+		ID:      "42",
+		Status:  "current",
+		Title:   "Runbook",
+		Body:    ConfluenceUpdateBody{Representation: "atlas_doc_format", Value: page.Body.AtlasDocFormat.Value},
+		Version: ConfluenceUpdateVersion{Number: 4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage := updated["body"].(map[string]any)["storage"].(map[string]any)
+	want := `<p>Hello <strong>ops</strong></p><ac:structured-macro ac:name="info"><ac:rich-text-body><p>Note</p></ac:rich-text-body></ac:structured-macro>`
+	if storage["value"] != want || storage["representation"] != "storage" || updated["type"] != "page" {
+		t.Errorf("unexpected update: %#v", updated)
+	}
+}
+
+func TestDataCenter_ConfluenceInlineComments(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/content/42/child/comment" || r.URL.Query().Get("location") != "inline" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if r.URL.Query().Get("start") == "" {
+			io.WriteString(w, `{"results":[{"id":"1","body":{"storage":{"value":"<p>typo</p>"}},"extensions":{"resolution":{"status":"reopened"},"inlineProperties":{"originalSelection":"teh"}}}],
+				"_links":{"next":"/rest/api/content/42/child/comment?location=inline&start=1"}}`)
+			return
+		}
+		io.WriteString(w, `{"results":[{"id":"2","body":{"storage":{"value":"<p>done</p>"}},"extensions":{"resolution":{"status":"resolved"}}}],"_links":{}}`)
+	}))
+	defer srv.Close()
+
+	comments, err := dataCenterClient(srv.URL).GetConfluenceInlineComments(context.Background(), "42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 2 {
+		t.Fatalf("expected 2 comments, got %d", len(comments))
+	}
+	if comments[0].ResolutionStatus != "open" || comments[0].Properties["inline-original-selection"] != "teh" || comments[0].Body.Storage.Value != "<p>typo</p>" {
+		t.Errorf("unexpected comment: %+v", comments[0])
+	}
+	if comments[1].ResolutionStatus != "resolved" {
+		t.Errorf("unexpected comment: %+v", comments[1])
+	}
+}
+
+func TestDataCenter_MyselfAndSpaceOperations(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/2/myself":
+			io.WriteString(w, `{"name":"jdoe","displayName":"Jane Doe","emailAddress":"jane@example.com"}`)
+		case "/rest/api/space/OPS":
+			if r.URL.Query().Get("expand") != "operations" {
+				t.Errorf("unexpected expand %q", r.URL.Query().Get("expand"))
+			}
+			io.WriteString(w, `{"key":"OPS","operations":[{"operation":"read","targetType":"space"},{"operation":"create","targetType":"page"}]}`)
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	client := dataCenterClient(srv.URL)
+	ctx := context.Background()
+
+	me, err := client.Myself(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.Name != "jdoe" || me.DisplayName != "Jane Doe" {
+		t.Errorf("unexpected user: %+v", me)
+	}
+
+	ops, err := client.GetConfluenceSpaceOperations(ctx, "OPS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 2 || ops[1] != (ConfluenceOperation{Operation: "create", TargetType: "page"}) {
+		t.Errorf("unexpected operations: %+v", ops)
+	}
+}
+
+func TestWikiRoundTrip(t *testing.T) {
+	wiki := strings.Join([]string{
+		"h2. Title",
+		"Some *bold*, _em_ and -gone- text with {{code}}, [a link|https://x.example] and [~jdoe].\nA second line.",
+		"* one\n** nested\n* two",
+		"# first\n# second",
+		"{code:go}\nfmt.Println(1)\n{code}",
+		"||Name||Value||\n|a|b|",
+		"{quote}\nquoted\n{quote}",
+		"----",
+		"2025-01-15 costs 5 \\* 3 \\[not a link\\]",
+	}, "\n\n")
+
+	if got := ADFToWiki(WikiToADF(wiki)); got != wiki {
+		t.Errorf("round trip changed the markup:\n%s\n---\n%s", wiki, got)
+	}
+}
+
+func TestStorageRoundTrip(t *testing.T) {
+	storage := `<h1>Title</h1><p>Hello <strong>world</strong> &amp; <a href="https://x.example">friends</a><br />next</p>` +
+		`<ul><li>one<ul><li>two</li></ul></li></ul>` +
+		`<ac:structured-macro ac:name="code"><ac:parameter ac:name="language">go</ac:parameter><ac:plain-text-body><![CDATA[if a && b {}]]></ac:plain-text-body></ac:structured-macro>` +
+		`<p>See <ac:link><ri:page ri:content-title="Other" /></ac:link> too</p>` +
+		`<table><tbody><tr><th><p>A</p></th><td><p>b</p></td></tr></tbody></table><hr />`
+
+	doc, err := StorageToADF(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Content[3].Type != "codeBlock" || doc.Content[3].Attrs["language"] != "go" || doc.Content[3].Content[0].Text != "if a && b {}" {
+		t.Errorf("expected a go code block, got %+v", doc.Content[3])
+	}
+	if got := ADFToStorage(doc); got != storage {
+		t.Errorf("round trip changed the storage format:\n%s\n---\n%s", storage, got)
+	}
+}
@@ -0,0 +1,137 @@
+package jira
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// debugBodyLimit is how much of each request and response body Debug logs.
+const debugBodyLimit = 2000
+
+// Debug returns an Option that logs every request the client sends to w:
+// method, URL, status, latency and the start of each body. The
+// Authorization header is never logged; secrets (such as the API token) and
+// query parameters named like tokens are replaced with REDACTED wherever
+// they appear.
+func Debug(w io.Writer, secrets ...string) Option {
+	var nonEmpty []string
+	for _, s := range secrets {
+		if s != "" {
+			nonEmpty = append(nonEmpty, s)
+		}
+	}
+	return WithTransport(func(base http.RoundTripper) http.RoundTripper {
+		return &debugTransport{base: base, w: w, secrets: nonEmpty}
+	})
+}
+
+type debugTransport struct {
+	base    http.RoundTripper
+	secrets []string
+
+	mu sync.Mutex // serializes writes to w
+	w  io.Writer
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, reqMore := peekBody(&req.Body, debugBodyLimit)
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	var b strings.Builder
+	target := req.Method + " " + t.redact(redactQuery(req.URL))
+	if err != nil {
+		fmt.Fprintf(&b, "[debug] %s -> error: %s (%s)\n", target, t.redact(err.Error()), elapsed)
+	} else {
+		fmt.Fprintf(&b, "[debug] %s -> %s (%s)\n", target, resp.Status, elapsed)
+	}
+	if len(reqBody) > 0 {
+		fmt.Fprintf(&b, "[debug]   request: %s\n", t.formatBody(reqBody, reqMore))
+	}
+	if resp != nil {
+		if respBody, respMore := peekBody(&resp.Body, debugBodyLimit); len(respBody) > 0 {
+			fmt.Fprintf(&b, "[debug]   response: %s\n", t.formatBody(respBody, respMore))
+		}
+	}
+
+	t.mu.Lock()
+	io.WriteString(t.w, b.String())
+	t.mu.Unlock()
+	return resp, err
+}
+
+// formatBody renders the start of a body on one line, noting how it was cut.
+func (t *debugTransport) formatBody(data []byte, more bool) string {
+	if more {
+		// The cut may have split a character.
+		for i := 0; i < utf8.UTFMax && len(data) > 0 && !utf8.Valid(data); i++ {
+			data = data[:len(data)-1]
+		}
+	}
+	if !utf8.Valid(data) {
+		return "(binary data)"
+	}
+	text := strings.ReplaceAll(t.redact(string(data)), "\n", `\n`)
+	if more {
+		text += " ...(truncated)"
+	}
+	return text
+}
+
+func (t *debugTransport) redact(s string) string {
+	for _, secret := range t.secrets {
+		s = strings.ReplaceAll(s, secret, "REDACTED")
+	}
+	return s
+}
+
+// redactQuery returns u with the values of token-like query parameters,
+// such as the one in attachment download redirects, replaced.
+func redactQuery(u *url.URL) string {
+	q := u.Query()
+	changed := false
+	for name := range q {
+		lower := strings.ToLower(name)
+		if strings.Contains(lower, "token") || lower == "jwt" || lower == "signature" {
+			q.Set(name, "REDACTED")
+			changed = true
+		}
+	}
+	if !changed {
+		return u.String()
+	}
+	c := *u
+	c.RawQuery = q.Encode()
+	return c.String()
+}
+
+// peekBody reads up to limit bytes of *body and puts them back in front of
+// the rest, so the body can still be sent or read in full. more reports
+// whether the body goes on past limit.
+func peekBody(body *io.ReadCloser, limit int) (data []byte, more bool) {
+	if *body == nil || *body == http.NoBody {
+		return nil, false
+	}
+	buf := make([]byte, limit+1)
+	n, err := io.ReadFull(*body, buf)
+	rest := *body
+	*body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(buf[:n]), rest), rest}
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, false
+	}
+	if n > limit {
+		return buf[:limit], true
+	}
+	return buf[:n], false
+}
@@ -0,0 +1,75 @@
+package jira
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mreider/a-cli/internal/config"
+)
+
+func TestDebug_LogsRequestsWithoutSecrets(t *testing.T) {
+	long := strings.Repeat("x", 3*debugBodyLimit)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/3/issue/PROJ-1":
+			w.Write([]byte(`{"key":"PROJ-1","fields":{"summary":"` + long + `"}}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errorMessages":["echoed test-token back"]}`))
+		}
+	}))
+	defer srv.Close()
+
+	var log bytes.Buffer
+	cfg := config.Config{URL: srv.URL, Email: "test@example.com", Token: "test-token"}
+	client := NewClient(cfg, Debug(&log, cfg.Token))
+	ctx := context.Background()
+
+	issue, err := client.GetIssue(ctx, "PROJ-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Fields.Summary != long {
+		t.Errorf("logging cut the response body short: %d bytes", len(issue.Fields.Summary))
+	}
+	_, err = client.CreateIssue(ctx, CreatePayload{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a 400, got %v", err)
+	}
+
+	out := log.String()
+	for _, want := range []string{
+		"[debug] GET " + srv.URL + "/rest/api/3/issue/PROJ-1?fields=",
+		"-> 200 OK (",
+		"...(truncated)",
+		"[debug] POST " + srv.URL + "/rest/api/3/issue -> 400 Bad Request (",
+		`[debug]   request: {"fields":`,
+		`[debug]   response: {"errorMessages":["echoed REDACTED back"]}`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log is missing %q:\n%s", want, out)
+		}
+	}
+	for _, secret := range []string{"test-token", "Basic ", "Authorization"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("log contains %q:\n%s", secret, out)
+		}
+	}
+	if strings.Contains(out, long) {
+		t.Error("log holds the whole response body")
+	}
+}
+
+func TestRedactQuery(t *testing.T) {
+	req := httptest.NewRequest("GET", "https://api.media.atlassian.com/file/1/binary?token=abc.def&client=xyz", nil)
+	got := redactQuery(req.URL)
+	if strings.Contains(got, "abc.def") || !strings.Contains(got, "client=xyz") {
+		t.Errorf("unexpected URL %s", got)
+	}
+}
@@ -0,0 +1,107 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Errors an *APIError matches with errors.Is, by status code.
+var (
+	// ErrNotFound is a 404: the issue, page or other resource doesn't exist
+	// or isn't visible to the user.
+	ErrNotFound = errors.New("not found")
+
+	// ErrUnauthorized is a 401 or 403: the credentials were rejected or
+	// don't grant access.
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrConflict is a 409, such as a Confluence page version that was
+	// already taken by someone else's edit.
+	ErrConflict = errors.New("conflict")
+
+	// ErrRateLimited is a 429. Idempotent requests return it only once their
+	// retries are used up; other requests, such as POSTs, are not retried and
+	// return it on the first 429.
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// JiraErrors represents the structured error response from the JIRA API.
+type JiraErrors struct {
+	Errors        map[string]string `json:"errors"`
+	ErrorMessages []string          `json:"errorMessages"`
+}
+
+// APIError is an unsuccessful response from JIRA or Confluence.
+type APIError struct {
+	Service    string // "JIRA" or "Confluence"
+	StatusCode int
+	Details    JiraErrors // parsed from Body when it has the JIRA error shape
+	Body       string
+}
+
+func newAPIError(service string, statusCode int, body []byte) *APIError {
+	e := &APIError{Service: service, StatusCode: statusCode, Body: strings.TrimSpace(string(body))}
+	if json.Unmarshal(body, &e.Details) != nil {
+		e.Details = JiraErrors{}
+	}
+	return e
+}
+
+func (e *APIError) Error() string {
+	var parts []string
+	parts = append(parts, e.Details.ErrorMessages...)
+	for field, msg := range e.Details.Errors {
+		parts = append(parts, field+": "+msg)
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("%s API %d: %s", e.Service, e.StatusCode, e.Body)
+	}
+
+	msg := strings.Join(parts, "; ")
+	// Always append the raw body so field-level details aren't lost
+	if e.Body != "" && e.Body != msg {
+		return fmt.Sprintf("%s API %d: %s\nResponse body: %s", e.Service, e.StatusCode, msg, e.Body)
+	}
+	return fmt.Sprintf("%s API %d: %s", e.Service, e.StatusCode, msg)
+}
+
+// Is matches the Err* values for the error's status code.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+func formatNetworkError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("request cancelled: %w", context.Canceled)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("connection timed out — check your network connection or VPN, then try again")
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		if opErr.Op == "dial" {
+			return fmt.Errorf("could not connect to server — check your network connection or VPN, then try again")
+		}
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return fmt.Errorf("DNS lookup failed for %s — check the URL in your config", dnsErr.Name)
+	}
+	return fmt.Errorf("network error: %w", err)
+}
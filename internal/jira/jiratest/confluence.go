@@ -0,0 +1,235 @@
+package jiratest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/mreider/a-cli/internal/jira"
+)
+
+func (s *Server) serveConfluence(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case route(r, parts, "POST", "api", "v2", "pages"):
+		s.createPage(w, r)
+	case route(r, parts, "GET", "api", "v2", "pages", "*"):
+		if page := s.findPage(w, r, parts[3]); page != nil {
+			writeJSON(w, http.StatusOK, page)
+		}
+	case route(r, parts, "PUT", "api", "v2", "pages", "*"):
+		if page := s.findPage(w, r, parts[3]); page != nil {
+			s.updatePage(w, r, page)
+		}
+	case route(r, parts, "GET", "api", "v2", "pages", "*", "children"):
+		if page := s.findPage(w, r, parts[3]); page != nil {
+			s.listChildren(w, r, page)
+		}
+	case route(r, parts, "GET", "api", "v2", "pages", "*", "footer-comments"):
+		if page := s.findPage(w, r, parts[3]); page != nil {
+			writeJSON(w, http.StatusOK, jira.ConfluenceCommentsResponse{Results: append([]jira.ConfluenceComment{}, s.footer[page.ID]...)})
+		}
+	case route(r, parts, "GET", "api", "v2", "pages", "*", "inline-comments"):
+		if page := s.findPage(w, r, parts[3]); page != nil {
+			writeJSON(w, http.StatusOK, jira.ConfluenceCommentsResponse{Results: append([]jira.ConfluenceComment{}, s.inline[page.ID]...)})
+		}
+	case route(r, parts, "GET", "api", "v2", "spaces"):
+		keys := strings.Split(r.URL.Query().Get("keys"), ",")
+		spaces := []jira.ConfluenceSpace{}
+		for _, space := range s.spaces {
+			if _, ok := findByName(keys, space.Key, func(k string) string { return k }); ok || r.URL.Query().Get("keys") == "" {
+				spaces = append(spaces, space)
+			}
+		}
+		writeJSON(w, http.StatusOK, jira.ConfluenceSpacesResponse{Results: spaces})
+	case route(r, parts, "GET", "api", "v2", "spaces", "*"):
+		space := s.space(parts[3])
+		switch {
+		case space == nil:
+			apiError(w, r, http.StatusNotFound, "Space not found")
+		case r.URL.Query().Get("include-operations") == "true":
+			writeJSON(w, http.StatusOK, map[string]any{
+				"id": space.ID, "key": space.Key, "name": space.Name,
+				"operations": map[string]any{"results": s.spaceOperations(space.Key)},
+			})
+		default:
+			writeJSON(w, http.StatusOK, space)
+		}
+	case route(r, parts, "GET", "rest", "api", "content", "search"):
+		s.searchContent(w, r)
+	default:
+		noRoute(w, r)
+	}
+}
+
+func (s *Server) findPage(w http.ResponseWriter, r *http.Request, id string) *jira.ConfluencePage {
+	page, ok := s.pages[id]
+	if !ok {
+		apiError(w, r, http.StatusNotFound, "Page not found")
+		return nil
+	}
+	return page
+}
+
+func (s *Server) space(id string) *jira.ConfluenceSpace {
+	for i := range s.spaces {
+		if s.spaces[i].ID == id {
+			space := s.spaces[i]
+			return &space
+		}
+	}
+	return nil
+}
+
+// spaceOperations returns what Me may do in a space.
+func (s *Server) spaceOperations(key string) []jira.ConfluenceOperation {
+	ops := []jira.ConfluenceOperation{{Operation: "read", TargetType: "space"}}
+	if !s.readOnly[key] {
+		ops = append(ops, jira.ConfluenceOperation{Operation: "create", TargetType: "page"}, jira.ConfluenceOperation{Operation: "update", TargetType: "page"})
+	}
+	return ops
+}
+
+func (s *Server) createPage(w http.ResponseWriter, r *http.Request) {
+	var payload jira.ConfluenceCreatePayload
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+	if payload.ParentID != "" {
+		if _, ok := s.pages[payload.ParentID]; !ok {
+			apiError(w, r, http.StatusBadRequest, "Parent page not found")
+			return
+		}
+	}
+
+	page := &jira.ConfluencePage{
+		ID:      s.id(),
+		Title:   payload.Title,
+		Status:  payload.Status,
+		SpaceID: payload.SpaceID,
+		Version: jira.PageVersion{Number: 1, CreatedAt: s.tick(), AuthorID: Me.AccountID},
+		Body:    jira.PageBody{AtlasDocFormat: &jira.PageBodyFormat{Value: payload.Body.Value, Representation: "atlas_doc_format"}},
+	}
+	s.pages[page.ID] = page
+	s.pageOrder = append(s.pageOrder, page.ID)
+	if payload.ParentID != "" {
+		s.children[payload.ParentID] = append(s.children[payload.ParentID], page.ID)
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// updatePage saves a new version of a page. Like Confluence, it rejects a
+// version number other than the current one plus one with 409 Conflict.
+func (s *Server) updatePage(w http.ResponseWriter, r *http.Request, page *jira.ConfluencePage) {
+	var payload jira.ConfluenceUpdatePayload
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+	if payload.Version.Number != page.Version.Number+1 {
+		apiError(w, r, http.StatusConflict, fmt.Sprintf("Version must be incremented on update. Current version is: %d", page.Version.Number))
+		return
+	}
+
+	page.Title = payload.Title
+	page.Body.AtlasDocFormat = &jira.PageBodyFormat{Value: payload.Body.Value, Representation: payload.Body.Representation}
+	page.Version = jira.PageVersion{Number: payload.Version.Number, CreatedAt: s.tick(), AuthorID: Me.AccountID}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// listChildren pages through a page's children with a cursor, as the v2 API does.
+func (s *Server) listChildren(w http.ResponseWriter, r *http.Request, page *jira.ConfluencePage) {
+	var children []jira.ConfluenceChildPage
+	for _, id := range s.children[page.ID] {
+		child := s.pages[id]
+		children = append(children, jira.ConfluenceChildPage{ID: child.ID, Title: child.Title, Status: child.Status})
+	}
+
+	limit := intParam(r, "limit", 25)
+	results, next := paginate(children, intParam(r, "cursor", 0), limit)
+	resp := jira.ConfluenceChildrenResponse{Results: append([]jira.ConfluenceChildPage{}, results...)}
+	if next >= 0 {
+		resp.Links.Next = fmt.Sprintf("/wiki/api/v2/pages/%s/children?limit=%d&cursor=%d", page.ID, limit, next)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// searchContent runs a CQL search over pages. The next link is relative to
+// /wiki, as in Confluence.
+func (s *Server) searchContent(w http.ResponseWriter, r *http.Request) {
+	cql := r.URL.Query().Get("cql")
+	clauses, err := parseQuery(cql)
+	if err != nil {
+		apiError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var entries []jira.ConfluenceSearchEntry
+	for _, id := range s.pageOrder {
+		page := s.pages[id]
+		var space jira.ConfluenceSpace
+		if sp := s.space(page.SpaceID); sp != nil {
+			space = *sp
+		}
+		ok, err := matchPage(page, space, clauses)
+		if err != nil {
+			apiError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if !ok {
+			continue
+		}
+		entries = append(entries, jira.ConfluenceSearchEntry{
+			Content:      jira.ConfluenceSearchContent{ID: page.ID, Type: "page", Title: page.Title, Space: space, Links: page.Links},
+			Title:        page.Title,
+			URL:          page.Links.WebUI,
+			LastModified: page.Version.CreatedAt,
+		})
+	}
+
+	start := intParam(r, "start", 0)
+	limit := intParam(r, "limit", 25)
+	results, next := paginate(entries, start, limit)
+	resp := jira.ConfluenceSearchResult{
+		Results:   append([]jira.ConfluenceSearchEntry{}, results...),
+		Start:     start,
+		Limit:     limit,
+		Size:      len(results),
+		TotalSize: len(entries),
+	}
+	if next >= 0 {
+		params := url.Values{}
+		params.Set("cql", cql)
+		params.Set("limit", strconv.Itoa(limit))
+		params.Set("start", strconv.Itoa(next))
+		resp.Links.Next = "/rest/api/content/search?" + params.Encode()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func matchPage(page *jira.ConfluencePage, space jira.ConfluenceSpace, clauses []clause) (bool, error) {
+	for _, c := range clauses {
+		var have []string
+		switch c.field {
+		case "type":
+			have = []string{"page"}
+		case "space":
+			have = []string{space.Key}
+		case "id":
+			have = []string{page.ID}
+		case "title":
+			have = []string{page.Title}
+		case "text":
+			have = []string{page.Title}
+			if page.Body.AtlasDocFormat != nil {
+				have = append(have, page.Body.AtlasDocFormat.Value)
+			}
+		default:
+			return false, fmt.Errorf("jiratest: unsupported CQL field %q", c.field)
+		}
+		if !c.test(have) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,628 @@
+package jiratest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mreider/a-cli/internal/jira"
+)
+
+// route reports whether the request has the method and its path segments
+// match pattern, where "*" matches any one segment.
+func route(r *http.Request, parts []string, method string, pattern ...string) bool {
+	if r.Method != method || len(parts) != len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		if p != "*" && p != parts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) serveJIRA(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case route(r, parts, "GET", "myself"):
+		writeJSON(w, http.StatusOK, Me)
+	case route(r, parts, "POST", "issue"):
+		s.createIssue(w, r)
+	case route(r, parts, "GET", "issue", "*"):
+		if issue := s.findIssue(w, r, parts[1]); issue != nil {
+			writeIssue(w, issue)
+		}
+	case route(r, parts, "PUT", "issue", "*"):
+		if issue := s.findIssue(w, r, parts[1]); issue != nil {
+			s.updateIssue(w, r, issue)
+		}
+	case route(r, parts, "GET", "issue", "*", "transitions"):
+		if issue := s.findIssue(w, r, parts[1]); issue != nil {
+			writeJSON(w, http.StatusOK, jira.TransitionsResponse{Transitions: s.transitionsFrom(issue.Fields.Status.Name)})
+		}
+	case route(r, parts, "POST", "issue", "*", "transitions"):
+		if issue := s.findIssue(w, r, parts[1]); issue != nil {
+			s.doTransition(w, r, issue)
+		}
+	case route(r, parts, "POST", "issue", "*", "comment"):
+		if issue := s.findIssue(w, r, parts[1]); issue != nil {
+			s.addComment(w, r, issue)
+		}
+	case route(r, parts, "PUT", "issue", "*", "comment", "*"), route(r, parts, "DELETE", "issue", "*", "comment", "*"):
+		if issue := s.findIssue(w, r, parts[1]); issue != nil {
+			s.changeComment(w, r, issue, parts[3])
+		}
+	case route(r, parts, "GET", "mypermissions"):
+		s.myPermissions(w, r)
+	case route(r, parts, "GET", "user", "search"):
+		s.searchUsers(w, r)
+	case route(r, parts, "GET", "field"):
+		writeJSON(w, http.StatusOK, append([]jira.FieldInfo{}, s.fields...))
+	case route(r, parts, "GET", "project", "*", "components"):
+		writeJSON(w, http.StatusOK, append([]jira.Component{}, s.components[parts[1]]...))
+	case route(r, parts, "GET", "project", "*", "versions"):
+		writeJSON(w, http.StatusOK, append([]jira.Version{}, s.versions[parts[1]]...))
+	case route(r, parts, "GET", "issueLinkType"):
+		writeJSON(w, http.StatusOK, map[string]any{"issueLinkTypes": s.linkTypes})
+	case route(r, parts, "POST", "issueLink"):
+		s.createLink(w, r)
+	case route(r, parts, "DELETE", "issueLink", "*"):
+		s.deleteLink(w, r, parts[1])
+	case route(r, parts, "GET", "workflowscheme", "project"):
+		writeJSON(w, http.StatusOK, map[string]any{
+			"values": []map[string]any{{"workflowScheme": jira.WorkflowScheme{Name: "jiratest scheme", DefaultWorkflow: WorkflowName}}},
+		})
+	case route(r, parts, "GET", "workflow", "search"):
+		s.searchWorkflows(w, r)
+	case route(r, parts, "POST", "search", "jql"):
+		s.searchIssues(w, r)
+	case route(r, parts, "POST", "search", "approximate-count"):
+		s.countIssues(w, r)
+	default:
+		noRoute(w, r)
+	}
+}
+
+func (s *Server) myPermissions(w http.ResponseWriter, r *http.Request) {
+	project := r.URL.Query().Get("projectKey")
+	if _, ok := s.projects[project]; !ok {
+		apiError(w, r, http.StatusNotFound, fmt.Sprintf("No project could be found with key '%s'.", project))
+		return
+	}
+	permissions := map[string]jira.Permission{}
+	for _, key := range strings.Split(r.URL.Query().Get("permissions"), ",") {
+		_, denied := findByName(s.denied[project], key, func(k string) string { return k })
+		permissions[key] = jira.Permission{Key: key, Name: key, HavePermission: !denied}
+	}
+	writeJSON(w, http.StatusOK, jira.MyPermissionsResponse{Permissions: permissions})
+}
+
+func (s *Server) findIssue(w http.ResponseWriter, r *http.Request, key string) *jira.Issue {
+	issue, ok := s.issues[key]
+	if !ok {
+		apiError(w, r, http.StatusNotFound, "Issue does not exist or you do not have permission to see it.")
+		return nil
+	}
+	return issue
+}
+
+// marshalIssue encodes an issue with its custom fields merged into fields,
+// as the API returns them.
+func marshalIssue(issue *jira.Issue) []byte {
+	fields, err := json.Marshal(issue.Fields)
+	if err != nil {
+		panic(err)
+	}
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal(fields, &merged); err != nil {
+		panic(err)
+	}
+	for k, v := range issue.Fields.Custom {
+		merged[k] = v
+	}
+	data, err := json.Marshal(map[string]any{"key": issue.Key, "fields": merged})
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// cloneIssue deep-copies an issue, keeping its custom fields.
+func cloneIssue(issue jira.Issue) jira.Issue {
+	var out jira.Issue
+	if err := json.Unmarshal(marshalIssue(&issue), &out); err != nil {
+		panic(err)
+	}
+	return out
+}
+
+func writeIssue(w http.ResponseWriter, issue *jira.Issue) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(marshalIssue(issue))
+}
+
+func (s *Server) createIssue(w http.ResponseWriter, r *http.Request) {
+	var payload jira.CreatePayload
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+	f := payload.Fields
+	if f.Summary == "" {
+		writeJSON(w, http.StatusBadRequest, jira.JiraErrors{Errors: map[string]string{"summary": "You must specify a summary of the issue."}})
+		return
+	}
+
+	n := 1
+	for key := range s.issues {
+		if p, k := splitKey(key); p == f.Project.Key && k >= n {
+			n = k + 1
+		}
+	}
+	issue := jira.Issue{
+		Key: fmt.Sprintf("%s-%d", f.Project.Key, n),
+		Fields: jira.Fields{
+			Summary:     f.Summary,
+			Project:     &jira.ProjectRef{ID: s.projectID(f.Project.Key), Key: f.Project.Key},
+			IssueType:   jira.IssueType{ID: issueTypeID(f.IssueType.Name), Name: f.IssueType.Name},
+			Status:      s.status(s.statuses[0]),
+			Labels:      f.Labels,
+			Description: f.Description,
+			Reporter:    s.user(Me.AccountID),
+			Updated:     s.tick(),
+		},
+	}
+	if f.Priority != nil {
+		issue.Fields.Priority = *f.Priority
+	}
+	if f.Assignee != nil {
+		issue.Fields.Assignee = s.user(f.Assignee.AccountID)
+	}
+	s.issues[issue.Key] = &issue
+
+	writeJSON(w, http.StatusCreated, jira.CreatedIssue{ID: s.id(), Key: issue.Key, Self: s.URL + "/rest/api/3/issue/" + issue.Key})
+}
+
+// updateIssue applies the fields of an edit. Fields the fake doesn't know are
+// rejected the way JIRA rejects fields missing from the edit screen.
+func (s *Server) updateIssue(w http.ResponseWriter, r *http.Request, issue *jira.Issue) {
+	var payload struct {
+		Fields map[string]json.RawMessage `json:"fields"`
+	}
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+
+	updated := cloneIssue(*issue)
+	f := &updated.Fields
+	project := f.Project.Key
+	problems := map[string]string{}
+	for name, raw := range payload.Fields {
+		var err error
+		switch name {
+		case "summary":
+			err = json.Unmarshal(raw, &f.Summary)
+		case "description":
+			err = json.Unmarshal(raw, &f.Description)
+		case "labels":
+			err = json.Unmarshal(raw, &f.Labels)
+		case "priority":
+			err = json.Unmarshal(raw, &f.Priority)
+		case "assignee", "reporter":
+			var ref jira.AccountRef
+			if err = json.Unmarshal(raw, &ref); err == nil {
+				user := s.user(ref.AccountID)
+				if user == nil {
+					problems[name] = fmt.Sprintf("User '%s' does not exist.", ref.AccountID)
+				} else if name == "assignee" {
+					f.Assignee = user
+				} else {
+					f.Reporter = user
+				}
+			}
+		case "components":
+			var refs []jira.Component
+			if err = json.Unmarshal(raw, &refs); err == nil {
+				f.Components = nil
+				for _, ref := range refs {
+					c, ok := findByName(s.components[project], ref.Name, func(c jira.Component) string { return c.Name })
+					if !ok {
+						problems[name] = fmt.Sprintf("Component name '%s' is not valid", ref.Name)
+					}
+					f.Components = append(f.Components, c)
+				}
+			}
+		case "fixVersions", "versions":
+			var refs []jira.Version
+			if err = json.Unmarshal(raw, &refs); err == nil {
+				var versions []jira.Version
+				for _, ref := range refs {
+					v, ok := findByName(s.versions[project], ref.Name, func(v jira.Version) string { return v.Name })
+					if !ok {
+						problems[name] = fmt.Sprintf("Version name '%s' is not valid", ref.Name)
+					}
+					versions = append(versions, v)
+				}
+				if name == "fixVersions" {
+					f.FixVersions = versions
+				} else {
+					f.Versions = versions
+				}
+			}
+		default:
+			if !strings.HasPrefix(name, "customfield_") {
+				problems[name] = fmt.Sprintf("Field '%s' cannot be set. It is not on the appropriate screen, or unknown.", name)
+				continue
+			}
+			if f.Custom == nil {
+				f.Custom = make(map[string]json.RawMessage)
+			}
+			f.Custom[name] = raw
+		}
+		if err != nil {
+			problems[name] = err.Error()
+		}
+	}
+	if len(problems) > 0 {
+		writeJSON(w, http.StatusBadRequest, jira.JiraErrors{Errors: problems})
+		return
+	}
+
+	f.Updated = s.tick()
+	*issue = updated
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func findByName[T any](items []T, name string, nameOf func(T) string) (T, bool) {
+	for _, item := range items {
+		if strings.EqualFold(nameOf(item), name) {
+			return item, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+func (s *Server) user(accountID string) *jira.User {
+	for i, u := range s.users {
+		if u.AccountID == accountID {
+			user := s.users[i]
+			return &user
+		}
+	}
+	return nil
+}
+
+func (s *Server) searchUsers(w http.ResponseWriter, r *http.Request) {
+	query := strings.ToLower(r.URL.Query().Get("query"))
+	users := []jira.User{}
+	for _, u := range s.users {
+		if strings.Contains(strings.ToLower(u.EmailAddress), query) || strings.Contains(strings.ToLower(u.DisplayName), query) {
+			users = append(users, u)
+		}
+	}
+	writeJSON(w, http.StatusOK, users)
+}
+
+// transitionsFrom lists the transitions the workflow offers from a status.
+func (s *Server) transitionsFrom(status string) []jira.TransitionInfo {
+	out := []jira.TransitionInfo{}
+	for _, t := range s.transitions {
+		if len(t.From) > 0 {
+			if _, ok := findByName(t.From, status, func(s string) string { return s }); !ok {
+				continue
+			}
+		}
+		out = append(out, jira.TransitionInfo{ID: t.ID, Name: t.Name, To: s.status(t.To)})
+	}
+	return out
+}
+
+func (s *Server) doTransition(w http.ResponseWriter, r *http.Request, issue *jira.Issue) {
+	var payload jira.TransitionPayload
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+	for _, t := range s.transitionsFrom(issue.Fields.Status.Name) {
+		if t.ID == payload.Transition.ID {
+			issue.Fields.Status = t.To
+			issue.Fields.Updated = s.tick()
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	apiError(w, r, http.StatusBadRequest, fmt.Sprintf("Transition id '%s' is not valid for this issue.", payload.Transition.ID))
+}
+
+func (s *Server) searchWorkflows(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("workflowName") != WorkflowName {
+		writeJSON(w, http.StatusOK, map[string]any{"values": []jira.Workflow{}})
+		return
+	}
+
+	workflow := jira.Workflow{ID: jira.WorkflowID{Name: WorkflowName}}
+	for _, name := range s.statuses {
+		workflow.Statuses = append(workflow.Statuses, jira.WorkflowStatus{ID: s.statusID(name), Name: name})
+	}
+	workflow.Transitions = append(workflow.Transitions, jira.WorkflowTransition{ID: "1", Name: "Create", To: s.statusID(s.statuses[0]), Type: "initial"})
+	for _, t := range s.transitions {
+		wt := jira.WorkflowTransition{ID: t.ID, Name: t.Name, From: []string{}, To: s.statusID(t.To), Type: "global"}
+		for _, from := range t.From {
+			wt.From = append(wt.From, s.statusID(from))
+			wt.Type = "directed"
+		}
+		workflow.Transitions = append(workflow.Transitions, wt)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"values": []jira.Workflow{workflow}})
+}
+
+func (s *Server) addComment(w http.ResponseWriter, r *http.Request, issue *jira.Issue) {
+	var payload jira.CommentPayload
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+	comment := jira.Comment{ID: s.id(), Author: Me, Body: payload.Body, Created: s.tick()}
+	if issue.Fields.Comment == nil {
+		issue.Fields.Comment = &jira.Comments{}
+	}
+	issue.Fields.Comment.Comments = append(issue.Fields.Comment.Comments, comment)
+	issue.Fields.Updated = comment.Created
+	writeJSON(w, http.StatusCreated, comment)
+}
+
+func (s *Server) changeComment(w http.ResponseWriter, r *http.Request, issue *jira.Issue, id string) {
+	if issue.Fields.Comment != nil {
+		comments := issue.Fields.Comment.Comments
+		for i := range comments {
+			if comments[i].ID != id {
+				continue
+			}
+			if r.Method == "DELETE" {
+				issue.Fields.Comment.Comments = append(comments[:i], comments[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			var payload jira.CommentPayload
+			if !decodeBody(w, r, &payload) {
+				return
+			}
+			comments[i].Body = payload.Body
+			writeJSON(w, http.StatusOK, comments[i])
+			return
+		}
+	}
+	apiError(w, r, http.StatusNotFound, "Can not find a comment for the id: "+id+".")
+}
+
+func (s *Server) createLink(w http.ResponseWriter, r *http.Request) {
+	var link jira.IssueLink
+	if !decodeBody(w, r, &link) {
+		return
+	}
+	if link.InwardIssue == nil || link.OutwardIssue == nil {
+		apiError(w, r, http.StatusBadRequest, "Both inward and outward issues are required.")
+		return
+	}
+	linkType, ok := findByName(s.linkTypes, link.Type.Name, func(t jira.IssueLinkType) string { return t.Name })
+	if !ok {
+		apiError(w, r, http.StatusNotFound, fmt.Sprintf("No issue link type with name '%s' found.", link.Type.Name))
+		return
+	}
+	inward, ok := s.issues[link.InwardIssue.Key]
+	if !ok {
+		apiError(w, r, http.StatusNotFound, "Issue does not exist or you do not have permission to see it.")
+		return
+	}
+	outward, ok := s.issues[link.OutwardIssue.Key]
+	if !ok {
+		apiError(w, r, http.StatusNotFound, "Issue does not exist or you do not have permission to see it.")
+		return
+	}
+
+	// The inward issue reads "<inward issue> blocks <outward issue>", so on
+	// it the other side is the outward issue, and the other way round.
+	id := s.id()
+	inward.Fields.IssueLinks = append(inward.Fields.IssueLinks, jira.IssueLink{ID: id, Type: linkType, OutwardIssue: linkedIssue(outward)})
+	outward.Fields.IssueLinks = append(outward.Fields.IssueLinks, jira.IssueLink{ID: id, Type: linkType, InwardIssue: linkedIssue(inward)})
+	w.WriteHeader(http.StatusCreated)
+}
+
+func linkedIssue(issue *jira.Issue) *jira.LinkedIssue {
+	return &jira.LinkedIssue{Key: issue.Key, Fields: &jira.LinkedIssueFields{Summary: issue.Fields.Summary, Status: issue.Fields.Status}}
+}
+
+func (s *Server) deleteLink(w http.ResponseWriter, r *http.Request, id string) {
+	found := false
+	for _, issue := range s.issues {
+		links := issue.Fields.IssueLinks[:0]
+		for _, l := range issue.Fields.IssueLinks {
+			if l.ID == id {
+				found = true
+				continue
+			}
+			links = append(links, l)
+		}
+		issue.Fields.IssueLinks = links
+	}
+	if !found {
+		apiError(w, r, http.StatusNotFound, "No issue link with id '"+id+"' exists.")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// matchingIssues returns the issues matching a JQL query, in key order.
+func (s *Server) matchingIssues(jql string) ([]jira.Issue, error) {
+	clauses, err := parseQuery(jql)
+	if err != nil {
+		return nil, err
+	}
+	var out []jira.Issue
+	for _, key := range sortedKeys(s.issues) {
+		issue := s.issues[key]
+		ok, err := matchIssue(issue, clauses)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, cloneIssue(*issue))
+		}
+	}
+	return out, nil
+}
+
+func (s *Server) searchIssues(w http.ResponseWriter, r *http.Request) {
+	var payload jira.SearchPayload
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+	issues, err := s.matchingIssues(payload.JQL)
+	if err != nil {
+		apiError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	start, _ := strconv.Atoi(payload.NextPageToken)
+	limit := payload.MaxResults
+	if limit <= 0 {
+		limit = 50
+	}
+	results, next := paginate(issues, start, limit)
+	result := jira.SearchResult{Issues: results, IsLast: next < 0}
+	if next >= 0 {
+		result.NextPageToken = strconv.Itoa(next)
+	}
+	if result.Issues == nil {
+		result.Issues = []jira.Issue{}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) countIssues(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		JQL string `json:"jql"`
+	}
+	if !decodeBody(w, r, &payload) {
+		return
+	}
+	issues, err := s.matchingIssues(payload.JQL)
+	if err != nil {
+		apiError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"count": len(issues)})
+}
+
+// clause is one condition of a JQL or CQL query: field op value(s).
+type clause struct {
+	field  string
+	op     string
+	values []string
+}
+
+var (
+	orderBy   = regexp.MustCompile(`(?i)\s+order\s+by\s+.*$`)
+	andSep    = regexp.MustCompile(`(?i)\s+and\s+`)
+	clauseExp = regexp.MustCompile(`^(\w+)\s*(!=|=|~|(?i:not\s+in|in))\s*(.+)$`)
+)
+
+// parseQuery parses the small subset of JQL and CQL the fake understands:
+// clauses joined with AND, each comparing a field with =, !=, ~, in or
+// not in. ORDER BY is ignored.
+func parseQuery(query string) ([]clause, error) {
+	query = strings.TrimSpace(orderBy.ReplaceAllString(query, ""))
+	if query == "" {
+		return nil, nil
+	}
+	var clauses []clause
+	for _, part := range andSep.Split(query, -1) {
+		m := clauseExp.FindStringSubmatch(strings.TrimSpace(part))
+		if m == nil {
+			return nil, fmt.Errorf("jiratest: unsupported query clause %q", part)
+		}
+		c := clause{field: strings.ToLower(m[1]), op: strings.ToLower(strings.Join(strings.Fields(m[2]), " "))}
+		value := strings.TrimSpace(m[3])
+		if c.op == "in" || c.op == "not in" {
+			value = strings.TrimSuffix(strings.TrimPrefix(value, "("), ")")
+			for _, v := range strings.Split(value, ",") {
+				c.values = append(c.values, unquote(v))
+			}
+		} else {
+			c.values = []string{unquote(value)}
+		}
+		clauses = append(clauses, c)
+	}
+	return clauses, nil
+}
+
+func unquote(v string) string {
+	v = strings.TrimSpace(v)
+	if u, err := strconv.Unquote(v); err == nil {
+		return u
+	}
+	return strings.Trim(v, `'`)
+}
+
+// test reports whether any of have satisfies the clause.
+func (c clause) test(have []string) bool {
+	if c.op == "~" {
+		for _, h := range have {
+			if strings.Contains(strings.ToLower(h), strings.ToLower(c.values[0])) {
+				return true
+			}
+		}
+		return false
+	}
+	found := false
+	for _, v := range c.values {
+		for _, h := range have {
+			if strings.EqualFold(h, v) {
+				found = true
+			}
+		}
+	}
+	if c.op == "!=" || c.op == "not in" {
+		return !found
+	}
+	return found
+}
+
+func matchIssue(issue *jira.Issue, clauses []clause) (bool, error) {
+	f := issue.Fields
+	for _, c := range clauses {
+		var have []string
+		switch c.field {
+		case "project":
+			have = []string{f.Project.Key}
+		case "key", "issuekey":
+			have = []string{issue.Key}
+		case "status":
+			have = []string{f.Status.Name}
+		case "issuetype", "type":
+			have = []string{f.IssueType.Name}
+		case "labels":
+			have = f.Labels
+		case "summary", "text":
+			have = []string{f.Summary}
+		case "assignee", "reporter":
+			user := f.Assignee
+			if c.field == "reporter" {
+				user = f.Reporter
+			}
+			if user != nil {
+				have = []string{user.AccountID, user.EmailAddress, user.DisplayName}
+				if user.AccountID == Me.AccountID {
+					have = append(have, "currentUser()")
+				}
+			}
+		default:
+			return false, fmt.Errorf("jiratest: unsupported JQL field %q", c.field)
+		}
+		if !c.test(have) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,483 @@
+// Package jiratest provides an in-memory JIRA and Confluence site for tests.
+//
+// A Server holds issues, a workflow, project components and versions, users,
+// and Confluence spaces, pages and comments, and serves the REST endpoints
+// jira.Client calls against them. Changes made through the API are kept, so
+// a test can run a command and then inspect the resulting state:
+//
+//	srv := jiratest.NewServer(t)
+//	srv.AddIssue(jira.Issue{Key: "PROJ-1", Fields: jira.Fields{Summary: "Fix login"}})
+//	client := jira.NewClient(config.Config{URL: srv.URL, Email: "me@example.com", Token: "x"})
+//
+// Requests to endpoints the fake doesn't implement fail with 404 and a
+// message naming the route.
+package jiratest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mreider/a-cli/internal/jira"
+)
+
+// WorkflowName is the name of the single workflow every issue uses.
+const WorkflowName = "jiratest workflow"
+
+// Transition is a workflow transition from any of From (any status when From
+// is empty) to To.
+type Transition struct {
+	ID   string
+	Name string
+	From []string
+	To   string
+}
+
+// DefaultStatuses and DefaultTransitions make up the workflow a new Server
+// starts with: To Do -> In Progress -> In Review -> Done, and back to To Do
+// from Done.
+var (
+	DefaultStatuses    = []string{"To Do", "In Progress", "In Review", "Done"}
+	DefaultTransitions = []Transition{
+		{ID: "11", Name: "Start", From: []string{"To Do"}, To: "In Progress"},
+		{ID: "21", Name: "Review", From: []string{"In Progress"}, To: "In Review"},
+		{ID: "31", Name: "Finish", From: []string{"In Review"}, To: "Done"},
+		{ID: "41", Name: "Reopen", From: []string{"Done"}, To: "To Do"},
+	}
+)
+
+// Me is the user the fake authenticates every request as. Comments and
+// pages it creates are authored by this user.
+var Me = jira.User{AccountID: "me-0001", EmailAddress: "me@example.com", DisplayName: "Test User"}
+
+// Server is a fake Atlassian site. Its methods are safe for concurrent use.
+type Server struct {
+	// URL is the base URL of the running server, set by NewServer.
+	URL string
+
+	mu          sync.Mutex
+	now         time.Time
+	nextID      int
+	statuses    []string
+	transitions []Transition
+	issues      map[string]*jira.Issue
+	projects    map[string]string   // key -> id
+	denied      map[string][]string // project key -> permissions Me lacks
+	components  map[string][]jira.Component
+	versions    map[string][]jira.Version
+	users       []jira.User
+	fields      []jira.FieldInfo
+	linkTypes   []jira.IssueLinkType
+	spaces      []jira.ConfluenceSpace
+	readOnly    map[string]bool // space keys Me can't write to
+	pages       map[string]*jira.ConfluencePage
+	pageOrder   []string
+	children    map[string][]string
+	footer      map[string][]jira.ConfluenceComment
+	inline      map[string][]jira.ConfluenceComment
+	failures    []failure
+	requests    []string
+}
+
+type failure struct {
+	method, path string
+	status       int
+}
+
+// New returns a Server that isn't listening; serve it with httptest or use
+// NewServer.
+func New() *Server {
+	return &Server{
+		now:         time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC),
+		nextID:      10000,
+		statuses:    DefaultStatuses,
+		transitions: DefaultTransitions,
+		issues:      make(map[string]*jira.Issue),
+		projects:    make(map[string]string),
+		denied:      make(map[string][]string),
+		components:  make(map[string][]jira.Component),
+		versions:    make(map[string][]jira.Version),
+		users:       []jira.User{Me},
+		linkTypes: []jira.IssueLinkType{
+			{ID: "1000", Name: "Blocks", Inward: "is blocked by", Outward: "blocks"},
+			{ID: "1001", Name: "Relates", Inward: "relates to", Outward: "relates to"},
+		},
+		readOnly: make(map[string]bool),
+		pages:    make(map[string]*jira.ConfluencePage),
+		children: make(map[string][]string),
+		footer:   make(map[string][]jira.ConfluenceComment),
+		inline:   make(map[string][]jira.ConfluenceComment),
+	}
+}
+
+// NewServer starts a Server on a local httptest server that is closed when
+// the test ends.
+func NewServer(t testing.TB) *Server {
+	s := New()
+	hs := httptest.NewServer(s)
+	t.Cleanup(hs.Close)
+	s.URL = hs.URL
+	return s
+}
+
+// SetWorkflow replaces the workflow. The first status is where new issues start.
+func (s *Server) SetWorkflow(statuses []string, transitions []Transition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses = statuses
+	s.transitions = transitions
+}
+
+// AddIssue stores an issue. Key is required; the project, issue type
+// (Task), status (the workflow's first) and updated time are filled in when
+// missing.
+func (s *Server) AddIssue(issue jira.Issue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	issue = cloneIssue(issue)
+	f := &issue.Fields
+	if f.Project == nil {
+		f.Project = &jira.ProjectRef{Key: projectKey(issue.Key)}
+	}
+	f.Project.ID = s.projectID(f.Project.Key)
+	if f.IssueType.Name == "" {
+		f.IssueType.Name = "Task"
+	}
+	if f.IssueType.ID == "" {
+		f.IssueType.ID = issueTypeID(f.IssueType.Name)
+	}
+	if f.Status.Name == "" {
+		f.Status.Name = s.statuses[0]
+	}
+	f.Status = s.status(f.Status.Name)
+	if f.Updated == "" {
+		f.Updated = s.tick()
+	}
+	s.issues[issue.Key] = &issue
+}
+
+// Issue returns a copy of an issue, or nil if there is none with that key.
+func (s *Server) Issue(key string) *jira.Issue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	issue, ok := s.issues[key]
+	if !ok {
+		return nil
+	}
+	c := cloneIssue(*issue)
+	return &c
+}
+
+// AddComponents defines components in a project.
+func (s *Server) AddComponents(project string, names ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range names {
+		s.components[project] = append(s.components[project], jira.Component{ID: s.id(), Name: name})
+	}
+}
+
+// AddVersions defines versions in a project.
+func (s *Server) AddVersions(project string, names ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, name := range names {
+		s.versions[project] = append(s.versions[project], jira.Version{ID: s.id(), Name: name})
+	}
+}
+
+// AddUser adds a user for user search and assignee lookups.
+func (s *Server) AddUser(user jira.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = append(s.users, user)
+}
+
+// DenyPermissions takes project permissions (such as EDIT_ISSUES) away from
+// Me. Me has every permission in a project by default.
+func (s *Server) DenyPermissions(project string, keys ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.projectID(project)
+	s.denied[project] = append(s.denied[project], keys...)
+}
+
+// AddField adds a field to the field list.
+func (s *Server) AddField(field jira.FieldInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fields = append(s.fields, field)
+}
+
+// AddSpace adds a Confluence space.
+func (s *Server) AddSpace(space jira.ConfluenceSpace) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.spaces = append(s.spaces, space)
+}
+
+// SetSpaceReadOnly lets Me read the space with the given key but not create
+// or edit pages in it.
+func (s *Server) SetSpaceReadOnly(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readOnly[key] = true
+}
+
+// AddPage stores a Confluence page as a child of parentID ("" for a top-level
+// page) and returns its ID. body is the page's ADF as JSON. ID, status and
+// version are filled in when missing.
+func (s *Server) AddPage(page jira.ConfluencePage, parentID, body string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	page = clone(page)
+	if page.ID == "" {
+		page.ID = s.id()
+	}
+	if page.Status == "" {
+		page.Status = "current"
+	}
+	if page.Version.Number == 0 {
+		page.Version.Number = 1
+	}
+	if page.Version.CreatedAt == "" {
+		page.Version.CreatedAt = s.tick()
+	}
+	page.Body.AtlasDocFormat = &jira.PageBodyFormat{Value: body, Representation: "atlas_doc_format"}
+	s.pages[page.ID] = &page
+	s.pageOrder = append(s.pageOrder, page.ID)
+	if parentID != "" {
+		s.children[parentID] = append(s.children[parentID], page.ID)
+	}
+	return page.ID
+}
+
+// Page returns a copy of a Confluence page, or nil if there is none with that ID.
+func (s *Server) Page(id string) *jira.ConfluencePage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	page, ok := s.pages[id]
+	if !ok {
+		return nil
+	}
+	c := clone(*page)
+	return &c
+}
+
+// AddPageComment adds a footer comment, or an inline comment when inline is set.
+func (s *Server) AddPageComment(pageID string, comment jira.ConfluenceComment, inline bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if comment.ID == "" {
+		comment.ID = s.id()
+	}
+	if inline {
+		s.inline[pageID] = append(s.inline[pageID], comment)
+	} else {
+		s.footer[pageID] = append(s.footer[pageID], comment)
+	}
+}
+
+// FailNext makes the next request with this method and path (without the
+// query) fail with status before it is handled.
+func (s *Server) FailNext(method, path string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{method, path, status})
+}
+
+// Requests returns every request served so far as "METHOD /path".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	for i, f := range s.failures {
+		if f.method == r.Method && f.path == r.URL.Path {
+			s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			apiError(w, r, f.status, "jiratest: injected failure")
+			return
+		}
+	}
+
+	if _, token, ok := r.BasicAuth(); !ok || token == "" {
+		apiError(w, r, http.StatusUnauthorized, "Client must be authenticated to access this resource.")
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/rest/api/3/"):
+		s.serveJIRA(w, r, strings.Split(strings.TrimPrefix(r.URL.Path, "/rest/api/3/"), "/"))
+	case strings.HasPrefix(r.URL.Path, "/wiki/"):
+		s.serveConfluence(w, r, strings.Split(strings.TrimPrefix(r.URL.Path, "/wiki/"), "/"))
+	default:
+		noRoute(w, r)
+	}
+}
+
+// id returns a new numeric id.
+func (s *Server) id() string {
+	s.nextID++
+	return strconv.Itoa(s.nextID)
+}
+
+// tick advances the fake clock by a minute and returns the new time in the
+// format JIRA uses.
+func (s *Server) tick() string {
+	s.now = s.now.Add(time.Minute)
+	return s.now.Format("2006-01-02T15:04:05.000-0700")
+}
+
+func (s *Server) projectID(key string) string {
+	id, ok := s.projects[key]
+	if !ok {
+		id = s.id()
+		s.projects[key] = id
+	}
+	return id
+}
+
+// status returns the named status with its category.
+func (s *Server) status(name string) jira.Status {
+	category := &jira.StatusCategory{Key: "indeterminate", Name: "In Progress"}
+	switch {
+	case strings.EqualFold(name, s.statuses[0]):
+		category = &jira.StatusCategory{Key: "new", Name: "To Do"}
+	case strings.EqualFold(name, s.statuses[len(s.statuses)-1]):
+		category = &jira.StatusCategory{Key: "done", Name: "Done"}
+	}
+	for _, st := range s.statuses {
+		if strings.EqualFold(st, name) {
+			name = st
+		}
+	}
+	return jira.Status{Name: name, StatusCategory: category}
+}
+
+func (s *Server) statusID(name string) string {
+	for i, st := range s.statuses {
+		if strings.EqualFold(st, name) {
+			return strconv.Itoa(i + 1)
+		}
+	}
+	return ""
+}
+
+func projectKey(issueKey string) string {
+	if i := strings.LastIndex(issueKey, "-"); i > 0 {
+		return issueKey[:i]
+	}
+	return issueKey
+}
+
+func issueTypeID(name string) string {
+	switch strings.ToLower(name) {
+	case "bug":
+		return "10002"
+	case "story":
+		return "10003"
+	case "epic":
+		return "10004"
+	}
+	return "10001"
+}
+
+// clone deep-copies v through JSON so stored state can't be changed by callers.
+func clone[T any](v T) T {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	var out T
+	if err := json.Unmarshal(data, &out); err != nil {
+		panic(err)
+	}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// apiError writes an error in the shape of the API the request was for.
+func apiError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	if strings.HasPrefix(r.URL.Path, "/wiki/") {
+		writeJSON(w, status, map[string]any{
+			"errors": []map[string]any{{"status": status, "title": msg}},
+		})
+		return
+	}
+	writeJSON(w, status, jira.JiraErrors{ErrorMessages: []string{msg}})
+}
+
+func noRoute(w http.ResponseWriter, r *http.Request) {
+	apiError(w, r, http.StatusNotFound, fmt.Sprintf("jiratest: no route for %s %s", r.Method, r.URL.Path))
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		apiError(w, r, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// paginate slices items for offset pagination and returns the offset of the next
+// page, or -1 when there is none.
+func paginate[T any](items []T, start, limit int) ([]T, int) {
+	if start > len(items) {
+		start = len(items)
+	}
+	end := len(items)
+	if limit > 0 && start+limit < end {
+		end = start + limit
+	}
+	if end < len(items) {
+		return items[start:end], end
+	}
+	return items[start:end], -1
+}
+
+func intParam(r *http.Request, name string, def int) int {
+	if n, err := strconv.Atoi(r.URL.Query().Get(name)); err == nil {
+		return n
+	}
+	return def
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		pi, ni := splitKey(keys[i])
+		pj, nj := splitKey(keys[j])
+		if pi != pj {
+			return pi < pj
+		}
+		return ni < nj
+	})
+	return keys
+}
+
+func splitKey(key string) (string, int) {
+	n, _ := strconv.Atoi(key[strings.LastIndex(key, "-")+1:])
+	return projectKey(key), n
+}
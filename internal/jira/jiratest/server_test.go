@@ -0,0 +1,68 @@
+package jiratest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mreider/a-cli/internal/config"
+	"github.com/mreider/a-cli/internal/jira"
+	"github.com/mreider/a-cli/internal/jira/jiratest"
+)
+
+func newClient(srv *jiratest.Server) *jira.Client {
+	return jira.NewClient(config.Config{URL: srv.URL, Email: "me@example.com", Token: "test-token"})
+}
+
+func TestServer_SearchPages(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	for i := 1; i <= 150; i++ {
+		srv.AddIssue(jira.Issue{Key: fmt.Sprintf("PROJ-%d", i), Fields: jira.Fields{Summary: "Issue"}})
+	}
+	srv.AddIssue(jira.Issue{Key: "OTHER-1", Fields: jira.Fields{Summary: "Issue"}})
+
+	result, err := newClient(srv).SearchIssues(context.Background(), "project = PROJ ORDER BY key", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Issues) != 150 || result.Issues[0].Key != "PROJ-1" || result.Issues[149].Key != "PROJ-150" {
+		t.Errorf("expected PROJ-1..PROJ-150, got %d issues", len(result.Issues))
+	}
+	searches := 0
+	for _, req := range srv.Requests() {
+		if req == "POST /rest/api/3/search/jql" {
+			searches++
+		}
+	}
+	if searches != 2 {
+		t.Errorf("expected 2 pages of results, got %d", searches)
+	}
+}
+
+func TestServer_PageVersionConflict(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	srv.AddSpace(jira.ConfluenceSpace{ID: "100", Key: "ENG"})
+	id := srv.AddPage(jira.ConfluencePage{Title: "Notes", SpaceID: "100"}, "", `{"type":"doc","version":1,"content":[]}`)
+	client := newClient(srv)
+
+	update := jira.ConfluenceUpdatePayload{ID: id, Status: "current", Title: "Notes"}
+	update.Body.Representation = "atlas_doc_format"
+	update.Body.Value = `{"type":"doc","version":1,"content":[]}`
+	update.Version.Number = 2
+	if err := client.UpdateConfluencePage(context.Background(), id, update); err != nil {
+		t.Fatal(err)
+	}
+	err := client.UpdateConfluencePage(context.Background(), id, update)
+	if !errors.Is(err, jira.ErrConflict) {
+		t.Fatalf("expected ErrConflict for a reused version, got %v", err)
+	}
+}
+
+func TestServer_RequiresAuth(t *testing.T) {
+	srv := jiratest.NewServer(t)
+	client := jira.NewClient(config.Config{URL: srv.URL})
+	if _, err := client.GetIssue(context.Background(), "PROJ-1"); !errors.Is(err, jira.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package jira
+
+import "context"
+
+// DefaultConcurrency is the number of parallel requests bulk pulls make when
+// no --concurrency is given.
+const DefaultConcurrency = 4
+
+// Ordered runs work for items 0..n-1 on up to concurrency goroutines and calls
+// emit once per item, in index order, on the calling goroutine. Each item is
+// emitted as soon as it and every item before it have finished, so output is
+// the same whatever order the work completes in. Once ctx is cancelled, items
+// that haven't started are emitted with the context's error instead of run.
+func Ordered[T any](ctx context.Context, n, concurrency int, work func(i int) (T, error), emit func(i int, result T, err error)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	type outcome struct {
+		result T
+		err    error
+	}
+	done := make([]chan outcome, n)
+	for i := range done {
+		done[i] = make(chan outcome, 1)
+	}
+
+	next := make(chan int)
+	go func() {
+		for i := 0; i < n; i++ {
+			next <- i
+		}
+		close(next)
+	}()
+
+	for w := 0; w < concurrency && w < n; w++ {
+		go func() {
+			for i := range next {
+				if err := ctx.Err(); err != nil {
+					done[i] <- outcome{err: err}
+					continue
+				}
+				result, err := work(i)
+				done[i] <- outcome{result, err}
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		o := <-done[i]
+		emit(i, o.result, o.err)
+	}
+}
@@ -0,0 +1,76 @@
+package jira
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOrdered_EmitsInInputOrder(t *testing.T) {
+	var running, peak int32
+	var order []int
+
+	Ordered(context.Background(), 20, 4, func(i int) (int, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		// Later items finish first.
+		time.Sleep(time.Duration(20-i) * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		if i == 7 {
+			return 0, errors.New("boom")
+		}
+		return i * i, nil
+	}, func(i int, result int, err error) {
+		order = append(order, i)
+		if i == 7 {
+			if err == nil {
+				t.Error("expected error for item 7")
+			}
+			return
+		}
+		if err != nil || result != i*i {
+			t.Errorf("item %d: got %d, %v", i, result, err)
+		}
+	})
+
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("expected items in order, got %v", order)
+		}
+	}
+	if len(order) != 20 {
+		t.Errorf("expected 20 items, got %d", len(order))
+	}
+	if peak > 4 {
+		t.Errorf("expected at most 4 concurrent workers, saw %d", peak)
+	}
+}
+
+func TestOrdered_StopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var ran int32
+	var cancelled int
+
+	Ordered(ctx, 50, 2, func(i int) (int, error) {
+		atomic.AddInt32(&ran, 1)
+		if i == 3 {
+			cancel()
+		}
+		return i, nil
+	}, func(i int, result int, err error) {
+		if errors.Is(err, context.Canceled) {
+			cancelled++
+		}
+	})
+
+	if ran >= 50 || cancelled == 0 || int(ran)+cancelled != 50 {
+		t.Errorf("expected remaining items to be skipped, ran %d and cancelled %d", ran, cancelled)
+	}
+}
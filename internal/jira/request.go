@@ -0,0 +1,133 @@
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Services named in API errors.
+const (
+	serviceJIRA       = "JIRA"
+	serviceConfluence = "Confluence"
+)
+
+// request describes one API call. Any 2xx status is a success; anything else
+// is returned as an *APIError.
+type request struct {
+	method  string
+	url     string
+	service string // serviceJIRA or serviceConfluence
+
+	// json is encoded as the request body when set. raw is sent as is, with
+	// contentType, for uploads.
+	json        any
+	raw         io.Reader
+	contentType string
+
+	header http.Header // added to the default headers
+
+	// readOnly marks a POST that only reads, such as a search, so it is
+	// retried like a GET.
+	readOnly bool
+}
+
+// do performs r and decodes the JSON response into a T.
+func do[T any](ctx context.Context, c *Client, r request) (T, error) {
+	var result T
+	resp, err := c.exec(ctx, r)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return result, fmt.Errorf("decoding response: %w", err)
+	}
+	return result, nil
+}
+
+// call performs r and discards the response body.
+func (c *Client) call(ctx context.Context, r request) error {
+	resp, err := c.exec(ctx, r)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return nil
+}
+
+// exec performs r and returns the response when its status is 2xx. The
+// caller closes the body.
+func (c *Client) exec(ctx context.Context, r request) (*http.Response, error) {
+	service := r.service
+	if service == "" {
+		service = serviceJIRA
+	}
+
+	body := r.raw
+	if r.json != nil {
+		data, err := json.Marshal(r.json)
+		if err != nil {
+			return nil, fmt.Errorf("marshalling payload: %w", err)
+		}
+		if c.dataCenter && service == serviceJIRA {
+			if data, err = wikiPayload(data); err != nil {
+				return nil, fmt.Errorf("marshalling payload: %w", err)
+			}
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.method, r.url, body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	if err := c.setHeaders(req, service); err != nil {
+		return nil, err
+	}
+	if r.raw != nil && r.contentType != "" {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	for name, values := range r.header {
+		req.Header[name] = values
+	}
+
+	send := c.send
+	if r.readOnly {
+		send = c.sendIdempotent
+	}
+	resp, err := send(req)
+	if err != nil {
+		return nil, formatNetworkError(err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, newAPIError(service, resp.StatusCode, data)
+	}
+	return resp, nil
+}
+
+func (c *Client) setHeaders(req *http.Request, service string) error {
+	auth := c.authHeader
+	if service == serviceConfluence {
+		auth = c.wikiAuthHeader
+	}
+	if c.tokens != nil {
+		token, err := c.tokens.AccessToken(req.Context())
+		if err != nil {
+			return err
+		}
+		auth = "Bearer " + token
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	return nil
+}
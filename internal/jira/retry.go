@@ -0,0 +1,208 @@
+package jira
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// retryPolicy controls how send retries failed requests.
+type retryPolicy struct {
+	maxRetries int           // retries after the first attempt
+	baseDelay  time.Duration // first backoff, doubled on each retry
+	maxDelay   time.Duration // cap on a single backoff
+	maxWait    time.Duration // cap on the total time spent waiting between attempts
+}
+
+var defaultRetryPolicy = retryPolicy{
+	maxRetries: 4,
+	baseDelay:  500 * time.Millisecond,
+	maxDelay:   10 * time.Second,
+	maxWait:    60 * time.Second,
+}
+
+// maxRateLimitPause caps how long a Retry-After header can hold up requests.
+const maxRateLimitPause = 60 * time.Second
+
+// idempotentMethods are retried automatically; other methods only through
+// sendIdempotent.
+var idempotentMethods = map[string]bool{
+	"GET":     true,
+	"HEAD":    true,
+	"OPTIONS": true,
+	"PUT":     true,
+	"DELETE":  true,
+}
+
+// send performs a request, retrying idempotent methods on rate limiting,
+// transient server errors and dropped connections.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if !idempotentMethods[req.Method] {
+		return c.httpClient.Do(req)
+	}
+	return c.sendIdempotent(req)
+}
+
+// sendIdempotent performs a request with retries. It is used directly for
+// POST endpoints that only read, such as JQL search.
+//
+// Failed attempts are retried with exponential backoff and jitter, or after
+// the server's Retry-After interval when it gives one. Once the next wait
+// would take the total past the policy's cap, the last response or error is
+// returned as it is.
+func (c *Client) sendIdempotent(req *http.Request) (*http.Response, error) {
+	policy := c.retry
+	var waited time.Duration
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if attempt >= policy.maxRetries || !shouldRetry(resp, err) || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+
+		delay := backoff(policy, attempt)
+		if resp != nil {
+			if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = d
+			}
+		}
+		if waited+delay > policy.maxWait {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := c.sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+		waited += delay
+	}
+}
+
+// shouldRetry reports whether a response or error is worth another attempt.
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return isTransient(err) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isTransient reports whether a network error may go away on its own.
+// DNS failures and other configuration problems are not retried.
+func isTransient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// sleepContext waits for d, returning early with the context's error if it
+// is cancelled first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// backoff returns the wait before retry number attempt+1: the base delay
+// doubled per attempt, capped, with the upper half randomised.
+func backoff(policy retryPolicy, attempt int) time.Duration {
+	d := policy.baseDelay << attempt
+	if d <= 0 || d > policy.maxDelay {
+		d = policy.maxDelay
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+// It reports false when the header is missing or malformed.
+func retryAfter(header string) (time.Duration, bool) {
+	var d time.Duration
+	if secs, err := strconv.Atoi(header); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if at, err := http.ParseTime(header); err == nil {
+		d = time.Until(at)
+	} else {
+		return 0, false
+	}
+	if d < 0 {
+		d = 0
+	}
+	if d > maxRateLimitPause {
+		d = maxRateLimitPause
+	}
+	return d, true
+}
+
+// rateLimiter is the client's transport. When the server asks it to back
+// off with Retry-After, it holds back every request made through the client
+// until the interval has passed, so concurrent workers slow down together
+// rather than each running into the limit.
+type rateLimiter struct {
+	base  http.RoundTripper
+	sleep func(context.Context, time.Duration) error
+
+	mu    sync.Mutex
+	until time.Time
+}
+
+func (t *rateLimiter) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && shouldRetry(resp, nil) {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			t.pause(d)
+		}
+	}
+	return resp, err
+}
+
+// wait blocks until any pause set by a rate-limited response has passed.
+func (t *rateLimiter) wait(ctx context.Context) error {
+	t.mu.Lock()
+	d := time.Until(t.until)
+	t.mu.Unlock()
+	if d > 0 {
+		return t.sleep(ctx, d)
+	}
+	return nil
+}
+
+func (t *rateLimiter) pause(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until := time.Now().Add(d); until.After(t.until) {
+		t.until = until
+	}
+}
@@ -0,0 +1,656 @@
+package jira
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Confluence Data Center has no ADF; pages are XHTML in its storage format.
+// StorageToADF and ADFToStorage convert between the two for the elements the
+// markdown converter understands. Anything else (macros, images, links to
+// other pages) becomes an extension node carrying the original storage XML,
+// so it survives a pull and push unchanged.
+
+// storageExtensionType marks extension nodes that hold raw storage XML in
+// their "storage" parameter.
+const storageExtensionType = "com.atlassian.confluence.storage"
+
+// storageBlocks are the elements converted to ADF block nodes.
+var storageBlocks = map[string]bool{
+	"p": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "pre": true, "blockquote": true, "hr": true, "table": true,
+}
+
+// storageInlineMarks are the elements converted to ADF marks.
+var storageInlineMarks = map[string]ADFMark{
+	"strong": {Type: "strong"},
+	"b":      {Type: "strong"},
+	"em":     {Type: "em"},
+	"i":      {Type: "em"},
+	"code":   {Type: "code"},
+	"s":      {Type: "strike"},
+	"del":    {Type: "strike"},
+	"u":      {Type: "underline"},
+	"sub":    {Type: "subsup", Attrs: map[string]any{"type": "sub"}},
+	"sup":    {Type: "subsup", Attrs: map[string]any{"type": "sup"}},
+}
+
+// storageInlineElements are Confluence elements that sit inside a paragraph.
+// They're kept as inline extensions rather than ending the paragraph.
+var storageInlineElements = map[string]bool{
+	"link": true, "emoticon": true, "image": true, "placeholder": true,
+}
+
+// storageAutoClose are the void elements storage format may leave unclosed.
+// xml.HTMLAutoClose isn't used since it includes "link", which would match
+// <ac:link>.
+var storageAutoClose = []string{"br", "hr", "img", "col"}
+
+// StorageToADF converts a page body in Confluence storage format to an ADF
+// document.
+func StorageToADF(storage string) (*ADFNode, error) {
+	src := []byte("<storage>" + storage + "</storage>")
+	d := xml.NewDecoder(bytes.NewReader(src))
+	d.Strict = false
+	d.AutoClose = storageAutoClose
+	d.Entity = xml.HTMLEntity
+
+	// Skip to the wrapper element.
+	if _, err := d.Token(); err != nil {
+		return nil, fmt.Errorf("parsing storage format: %w", err)
+	}
+	p := &storageParser{d: d, src: src}
+	content, err := p.blocks()
+	if err != nil {
+		return nil, fmt.Errorf("parsing storage format: %w", err)
+	}
+
+	version := 1
+	return &ADFNode{Type: "doc", Version: &version, Content: content}, nil
+}
+
+type storageParser struct {
+	d   *xml.Decoder
+	src []byte
+}
+
+// next returns the next token and the offset it starts at.
+func (p *storageParser) next() (xml.Token, int64, error) {
+	start := p.d.InputOffset()
+	tok, err := p.d.Token()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return xml.CopyToken(tok), start, err
+}
+
+// raw skips the rest of the element that started at offset and returns its
+// source.
+func (p *storageParser) raw(start int64) (string, error) {
+	if err := p.d.Skip(); err != nil {
+		return "", err
+	}
+	return string(p.src[start:p.d.InputOffset()]), nil
+}
+
+// blocks reads block content up to the end of the current element. Inline
+// content between blocks is collected into paragraphs.
+func (p *storageParser) blocks() ([]ADFNode, error) {
+	var blocks, inline []ADFNode
+
+	flush := func() {
+		if hasVisibleContent(inline) {
+			blocks = append(blocks, ADFNode{Type: "paragraph", Content: inline})
+		}
+		inline = nil
+	}
+
+	for {
+		tok, start, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.EndElement:
+			flush()
+			return blocks, nil
+
+		case xml.CharData:
+			inline = append(inline, storageText(string(t), nil)...)
+
+		case xml.StartElement:
+			if name := t.Name.Local; t.Name.Space == "" && storageBlocks[name] {
+				flush()
+				node, err := p.block(t)
+				if err != nil {
+					return nil, err
+				}
+				blocks = append(blocks, node)
+				continue
+			}
+			if isCodeMacro(t) {
+				flush()
+				node, err := p.codeMacro(t, start)
+				if err != nil {
+					return nil, err
+				}
+				blocks = append(blocks, node)
+				continue
+			}
+			if t.Name.Space != "" && !storageInlineElements[t.Name.Local] {
+				flush()
+				raw, err := p.raw(start)
+				if err != nil {
+					return nil, err
+				}
+				blocks = append(blocks, storageExtension("extension", t, raw))
+				continue
+			}
+			nodes, err := p.inlineElement(t, start, nil)
+			if err != nil {
+				return nil, err
+			}
+			inline = append(inline, nodes...)
+		}
+	}
+}
+
+// block converts a block element whose start tag has been read.
+func (p *storageParser) block(t xml.StartElement) (ADFNode, error) {
+	switch name := t.Name.Local; name {
+	case "p":
+		content, err := p.inline(nil)
+		return ADFNode{Type: "paragraph", Content: content}, err
+
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		content, err := p.inline(nil)
+		return ADFNode{Type: "heading", Attrs: map[string]any{"level": float64(name[1] - '0')}, Content: content}, err
+
+	case "ul", "ol":
+		list := ADFNode{Type: "bulletList"}
+		if name == "ol" {
+			list.Type = "orderedList"
+		}
+		items, err := p.children("li", func() (ADFNode, error) {
+			content, err := p.blocks()
+			if len(content) == 0 {
+				content = []ADFNode{{Type: "paragraph"}}
+			}
+			return ADFNode{Type: "listItem", Content: content}, err
+		})
+		list.Content = items
+		return list, err
+
+	case "pre":
+		text, err := p.text()
+		node := ADFNode{Type: "codeBlock"}
+		if text != "" {
+			node.Content = []ADFNode{{Type: "text", Text: text}}
+		}
+		return node, err
+
+	case "blockquote":
+		content, err := p.blocks()
+		return ADFNode{Type: "blockquote", Content: content}, err
+
+	case "hr":
+		return ADFNode{Type: "rule"}, p.d.Skip()
+
+	default: // table
+		var rows []ADFNode
+		for {
+			tok, _, err := p.next()
+			if err != nil {
+				return ADFNode{}, err
+			}
+			if _, ok := tok.(xml.EndElement); ok {
+				return ADFNode{Type: "table", Content: rows}, nil
+			}
+			start, ok := tok.(xml.StartElement)
+			if !ok {
+				continue
+			}
+			switch start.Name.Local {
+			case "tbody", "thead", "tfoot":
+				// A section holds rows just like the table itself.
+				section, err := p.block(xml.StartElement{Name: xml.Name{Local: "table"}})
+				if err != nil {
+					return ADFNode{}, err
+				}
+				rows = append(rows, section.Content...)
+			case "tr":
+				row, err := p.tableRow()
+				if err != nil {
+					return ADFNode{}, err
+				}
+				rows = append(rows, row)
+			default:
+				if err := p.d.Skip(); err != nil {
+					return ADFNode{}, err
+				}
+			}
+		}
+	}
+}
+
+func (p *storageParser) tableRow() (ADFNode, error) {
+	row := ADFNode{Type: "tableRow"}
+	for {
+		tok, _, err := p.next()
+		if err != nil {
+			return row, err
+		}
+		if _, ok := tok.(xml.EndElement); ok {
+			return row, nil
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "th" && start.Name.Local != "td" {
+			if err := p.d.Skip(); err != nil {
+				return row, err
+			}
+			continue
+		}
+		content, err := p.blocks()
+		if err != nil {
+			return row, err
+		}
+		if len(content) == 0 {
+			content = []ADFNode{{Type: "paragraph"}}
+		}
+		cellType := "tableCell"
+		if start.Name.Local == "th" {
+			cellType = "tableHeader"
+		}
+		row.Content = append(row.Content, ADFNode{Type: cellType, Content: content})
+	}
+}
+
+// children reads the child elements named name with read, skipping anything
+// else, up to the end of the current element.
+func (p *storageParser) children(name string, read func() (ADFNode, error)) ([]ADFNode, error) {
+	var nodes []ADFNode
+	for {
+		tok, _, err := p.next()
+		if err != nil {
+			return nodes, err
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			return nodes, nil
+		case xml.StartElement:
+			if t.Name.Local != name {
+				if err := p.d.Skip(); err != nil {
+					return nodes, err
+				}
+				continue
+			}
+			node, err := read()
+			if err != nil {
+				return nodes, err
+			}
+			nodes = append(nodes, node)
+		}
+	}
+}
+
+// inline reads inline content up to the end of the current element.
+func (p *storageParser) inline(marks []ADFMark) ([]ADFNode, error) {
+	var nodes []ADFNode
+	for {
+		tok, start, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			return nodes, nil
+		case xml.CharData:
+			nodes = append(nodes, storageText(string(t), marks)...)
+		case xml.StartElement:
+			child, err := p.inlineElement(t, start, marks)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, child...)
+		}
+	}
+}
+
+// inlineElement converts an inline element whose start tag has been read.
+func (p *storageParser) inlineElement(t xml.StartElement, start int64, marks []ADFMark) ([]ADFNode, error) {
+	if t.Name.Space != "" {
+		raw, err := p.raw(start)
+		if err != nil {
+			return nil, err
+		}
+		return []ADFNode{storageExtension("inlineExtension", t, raw)}, nil
+	}
+
+	switch t.Name.Local {
+	case "br":
+		return []ADFNode{{Type: "hardBreak"}}, p.d.Skip()
+	case "a":
+		href := ""
+		for _, a := range t.Attr {
+			if a.Name.Local == "href" {
+				href = a.Value
+			}
+		}
+		return p.inline(append(copyMarks(marks), ADFMark{Type: "link", Attrs: map[string]any{"href": href}}))
+	}
+	if mark, ok := storageInlineMarks[t.Name.Local]; ok {
+		return p.inline(append(copyMarks(marks), mark))
+	}
+	// span and other wrappers keep their text.
+	return p.inline(marks)
+}
+
+// text returns the character data up to the end of the current element.
+func (p *storageParser) text() (string, error) {
+	var b strings.Builder
+	depth := 0
+	for {
+		tok, _, err := p.next()
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				return b.String(), nil
+			}
+			depth--
+		case xml.CharData:
+			b.Write(t)
+		}
+	}
+}
+
+// codeMacro converts a code macro to a codeBlock. A code macro with
+// parameters other than the language is kept as an extension.
+func (p *storageParser) codeMacro(t xml.StartElement, start int64) (ADFNode, error) {
+	raw, err := p.raw(start)
+	if err != nil {
+		return ADFNode{}, err
+	}
+
+	var macro struct {
+		Params []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"parameter"`
+		Body string `xml:"plain-text-body"`
+	}
+	if err := xml.Unmarshal([]byte(raw), &macro); err != nil {
+		return storageExtension("extension", t, raw), nil
+	}
+
+	node := ADFNode{Type: "codeBlock"}
+	for _, param := range macro.Params {
+		if param.Name != "language" {
+			return storageExtension("extension", t, raw), nil
+		}
+		node.Attrs = map[string]any{"language": param.Value}
+	}
+	if macro.Body != "" {
+		node.Content = []ADFNode{{Type: "text", Text: macro.Body}}
+	}
+	return node, nil
+}
+
+func isCodeMacro(t xml.StartElement) bool {
+	if t.Name.Local != "structured-macro" {
+		return false
+	}
+	for _, a := range t.Attr {
+		if a.Name.Local == "name" {
+			return a.Value == "code"
+		}
+	}
+	return false
+}
+
+func storageExtension(nodeType string, t xml.StartElement, raw string) ADFNode {
+	key := t.Name.Local
+	for _, a := range t.Attr {
+		if a.Name.Local == "name" {
+			key = a.Value
+		}
+	}
+	return ADFNode{Type: nodeType, Attrs: map[string]any{
+		"extensionType": storageExtensionType,
+		"extensionKey":  key,
+		"parameters":    map[string]any{"storage": raw},
+	}}
+}
+
+// storageText converts character data to a text node, collapsing runs of
+// whitespace to a single space as HTML would.
+func storageText(s string, marks []ADFMark) []ADFNode {
+	if s == "" {
+		return nil
+	}
+	text := strings.Join(strings.Fields(s), " ")
+	if text == "" {
+		text = " "
+	} else {
+		if isSpace(s[0]) {
+			text = " " + text
+		}
+		if isSpace(s[len(s)-1]) {
+			text += " "
+		}
+	}
+	return []ADFNode{wikiText(text, marks)}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+func hasVisibleContent(nodes []ADFNode) bool {
+	for _, n := range nodes {
+		if n.Type != "text" || strings.TrimSpace(n.Text) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ADFToStorage converts an ADF document to Confluence storage format.
+func ADFToStorage(doc *ADFNode) string {
+	if doc == nil {
+		return ""
+	}
+	var b strings.Builder
+	for i := range doc.Content {
+		storageBlock(&b, &doc.Content[i])
+	}
+	return b.String()
+}
+
+func storageBlock(b *strings.Builder, node *ADFNode) {
+	switch node.Type {
+	case "paragraph":
+		b.WriteString("<p>")
+		storageInline(b, node.Content)
+		b.WriteString("</p>")
+
+	case "heading":
+		level := attrInt(node.Attrs, "level")
+		if level < 1 || level > 6 {
+			level = 2
+		}
+		fmt.Fprintf(b, "<h%d>", level)
+		storageInline(b, node.Content)
+		fmt.Fprintf(b, "</h%d>", level)
+
+	case "bulletList", "orderedList":
+		tag := "ul"
+		if node.Type == "orderedList" {
+			tag = "ol"
+		}
+		b.WriteString("<" + tag + ">")
+		for _, item := range node.Content {
+			b.WriteString("<li>")
+			for i := range item.Content {
+				child := &item.Content[i]
+				if i == 0 && child.Type == "paragraph" {
+					storageInline(b, child.Content)
+				} else {
+					storageBlock(b, child)
+				}
+			}
+			b.WriteString("</li>")
+		}
+		b.WriteString("</" + tag + ">")
+
+	case "codeBlock":
+		var text strings.Builder
+		for _, child := range node.Content {
+			text.WriteString(child.Text)
+		}
+		b.WriteString(`<ac:structured-macro ac:name="code">`)
+		if lang := attrString(node.Attrs, "language"); lang != "" {
+			b.WriteString(`<ac:parameter ac:name="language">`)
+			xml.EscapeText(b, []byte(lang))
+			b.WriteString(`</ac:parameter>`)
+		}
+		b.WriteString("<ac:plain-text-body><![CDATA[")
+		b.WriteString(strings.ReplaceAll(text.String(), "]]>", "]]]]><![CDATA[>"))
+		b.WriteString("]]></ac:plain-text-body></ac:structured-macro>")
+
+	case "blockquote":
+		b.WriteString("<blockquote>")
+		for i := range node.Content {
+			storageBlock(b, &node.Content[i])
+		}
+		b.WriteString("</blockquote>")
+
+	case "rule":
+		b.WriteString("<hr />")
+
+	case "table":
+		b.WriteString("<table><tbody>")
+		for _, row := range node.Content {
+			b.WriteString("<tr>")
+			for _, cell := range row.Content {
+				tag := "td"
+				if cell.Type == "tableHeader" {
+					tag = "th"
+				}
+				b.WriteString("<" + tag + ">")
+				for i := range cell.Content {
+					storageBlock(b, &cell.Content[i])
+				}
+				b.WriteString("</" + tag + ">")
+			}
+			b.WriteString("</tr>")
+		}
+		b.WriteString("</tbody></table>")
+
+	case "extension", "bodiedExtension":
+		if raw, ok := storageRaw(node); ok {
+			b.WriteString(raw)
+		}
+
+	default:
+		if len(node.Content) > 0 && node.Content[0].Type == "text" {
+			b.WriteString("<p>")
+			storageInline(b, node.Content)
+			b.WriteString("</p>")
+			return
+		}
+		for i := range node.Content {
+			storageBlock(b, &node.Content[i])
+		}
+	}
+}
+
+func storageInline(b *strings.Builder, nodes []ADFNode) {
+	for i := range nodes {
+		node := &nodes[i]
+		switch node.Type {
+		case "text":
+			storageMarked(b, node.Text, node.Marks)
+		case "hardBreak":
+			b.WriteString("<br />")
+		case "mention":
+			b.WriteString(`<ac:link><ri:user ri:username="`)
+			xml.EscapeText(b, []byte(attrString(node.Attrs, "id")))
+			b.WriteString(`" /></ac:link>`)
+		case "inlineCard":
+			url := attrString(node.Attrs, "url")
+			storageMarked(b, url, []ADFMark{{Type: "link", Attrs: map[string]any{"href": url}}})
+		case "emoji":
+			text := attrString(node.Attrs, "text")
+			if text == "" {
+				text = attrString(node.Attrs, "shortName")
+			}
+			xml.EscapeText(b, []byte(text))
+		case "inlineExtension":
+			if raw, ok := storageRaw(node); ok {
+				b.WriteString(raw)
+			}
+		default:
+			storageInline(b, node.Content)
+		}
+	}
+}
+
+// storageMarked writes text wrapped in the elements for its marks.
+func storageMarked(b *strings.Builder, text string, marks []ADFMark) {
+	var closing []string
+	for _, m := range marks {
+		open, close := "", ""
+		switch m.Type {
+		case "strong":
+			open, close = "<strong>", "</strong>"
+		case "em":
+			open, close = "<em>", "</em>"
+		case "code":
+			open, close = "<code>", "</code>"
+		case "strike":
+			open, close = "<s>", "</s>"
+		case "underline":
+			open, close = "<u>", "</u>"
+		case "subsup":
+			if attrString(m.Attrs, "type") == "sub" {
+				open, close = "<sub>", "</sub>"
+			} else {
+				open, close = "<sup>", "</sup>"
+			}
+		case "link":
+			var href strings.Builder
+			xml.EscapeText(&href, []byte(attrString(m.Attrs, "href")))
+			open, close = `<a href="`+href.String()+`">`, "</a>"
+		default:
+			continue
+		}
+		b.WriteString(open)
+		closing = append(closing, close)
+	}
+	xml.EscapeText(b, []byte(text))
+	for i := len(closing) - 1; i >= 0; i-- {
+		b.WriteString(closing[i])
+	}
+}
+
+// storageRaw returns the storage XML kept in an extension node by StorageToADF.
+func storageRaw(node *ADFNode) (string, bool) {
+	if attrString(node.Attrs, "extensionType") != storageExtensionType {
+		return "", false
+	}
+	params, _ := node.Attrs["parameters"].(map[string]any)
+	raw, ok := params["storage"].(string)
+	return raw, ok
+}
@@ -1,5 +1,10 @@
 package jira
 
+import (
+	"encoding/json"
+	"strings"
+)
+
 // Issue represents a JIRA issue from the REST API v3.
 type Issue struct {
 	Key    string `json:"key"`
@@ -8,16 +13,51 @@ type Issue struct {
 
 // Fields contains the issue fields we care about.
 type Fields struct {
-	Summary     string    `json:"summary"`
-	Status      Status    `json:"status"`
-	IssueType   IssueType `json:"issuetype"`
-	Priority    Priority  `json:"priority,omitempty"`
-	Labels      []string  `json:"labels,omitempty"`
-	Assignee    *User     `json:"assignee,omitempty"`
-	Reporter    *User     `json:"reporter,omitempty"`
-	Description *ADFNode  `json:"description,omitempty"`
-	Comment     *Comments `json:"comment,omitempty"`
-	Updated     string    `json:"updated,omitempty"`
+	Summary     string        `json:"summary"`
+	Status      Status        `json:"status"`
+	IssueType   IssueType     `json:"issuetype"`
+	Project     *ProjectRef   `json:"project,omitempty"`
+	Priority    Priority      `json:"priority,omitempty"`
+	Labels      []string      `json:"labels,omitempty"`
+	Components  []Component   `json:"components,omitempty"`
+	FixVersions []Version     `json:"fixVersions,omitempty"`
+	Versions    []Version     `json:"versions,omitempty"` // affects versions
+	Assignee    *User         `json:"assignee,omitempty"`
+	Reporter    *User         `json:"reporter,omitempty"`
+	Description *ADFNode      `json:"description,omitempty"`
+	Comment     *Comments     `json:"comment,omitempty"`
+	IssueLinks  []IssueLink   `json:"issuelinks,omitempty"`
+	Subtasks    []LinkedIssue `json:"subtasks,omitempty"`
+	Parent      *LinkedIssue  `json:"parent,omitempty"`
+	Attachment  []Attachment  `json:"attachment,omitempty"`
+	Updated     string        `json:"updated,omitempty"`
+
+	// Custom holds the raw values of any customfield_* fields in the response.
+	Custom map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes the known fields and keeps non-null customfield_*
+// values as raw JSON in Custom.
+func (f *Fields) UnmarshalJSON(data []byte) error {
+	type plain Fields
+	if err := json.Unmarshal(data, (*plain)(f)); err != nil {
+		return err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for k, v := range all {
+		if !strings.HasPrefix(k, "customfield_") || string(v) == "null" {
+			continue
+		}
+		if f.Custom == nil {
+			f.Custom = make(map[string]json.RawMessage)
+		}
+		f.Custom[k] = v
+	}
+	return nil
 }
 
 // Status represents a JIRA status.
@@ -34,6 +74,7 @@ type StatusCategory struct {
 
 // IssueType represents a JIRA issue type.
 type IssueType struct {
+	ID   string `json:"id,omitempty"`
 	Name string `json:"name"`
 }
 
@@ -42,12 +83,95 @@ type Priority struct {
 	Name string `json:"name"`
 }
 
+// Component represents a project component.
+type Component struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+}
+
+// Version represents a project version (release).
+type Version struct {
+	ID       string `json:"id,omitempty"`
+	Name     string `json:"name"`
+	Released bool   `json:"released,omitempty"`
+	Archived bool   `json:"archived,omitempty"`
+}
+
+// ComponentNames returns the names of components, in order.
+func ComponentNames(components []Component) []string {
+	names := make([]string, 0, len(components))
+	for _, c := range components {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// VersionNames returns the names of versions, in order.
+func VersionNames(versions []Version) []string {
+	names := make([]string, 0, len(versions))
+	for _, v := range versions {
+		names = append(names, v.Name)
+	}
+	return names
+}
+
+// IssueLink is a link between two issues. On an issue's issuelinks field only
+// the other side is set: OutwardIssue when the issue is the subject of the
+// outward phrase ("blocks"), InwardIssue for the inward phrase ("is blocked by").
+// When creating a link both sides are set, reading "inward <outward phrase> outward".
+type IssueLink struct {
+	ID           string        `json:"id,omitempty"`
+	Type         IssueLinkType `json:"type"`
+	InwardIssue  *LinkedIssue  `json:"inwardIssue,omitempty"`
+	OutwardIssue *LinkedIssue  `json:"outwardIssue,omitempty"`
+}
+
+// IssueLinkType describes a kind of link, e.g. Blocks ("blocks" / "is blocked by").
+type IssueLinkType struct {
+	ID      string `json:"id,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Inward  string `json:"inward,omitempty"`
+	Outward string `json:"outward,omitempty"`
+}
+
+// LinkedIssue is the abbreviated issue returned for links, subtasks and parents.
+type LinkedIssue struct {
+	ID     string             `json:"id,omitempty"`
+	Key    string             `json:"key"`
+	Fields *LinkedIssueFields `json:"fields,omitempty"`
+}
+
+// LinkedIssueFields are the fields included with a LinkedIssue.
+type LinkedIssueFields struct {
+	Summary string `json:"summary"`
+	Status  Status `json:"status"`
+}
+
 // User represents a JIRA user.
 type User struct {
+	AccountID    string `json:"accountId,omitempty"`
+	Name         string `json:"name,omitempty"` // username; Data Center has no accountIds
 	EmailAddress string `json:"emailAddress"`
 	DisplayName  string `json:"displayName"`
 }
 
+// Ref returns the reference that identifies u in create and update payloads.
+func (u User) Ref() AccountRef {
+	return AccountRef{AccountID: u.AccountID, Name: u.Name}
+}
+
+// MyPermissionsResponse is the response from GET /rest/api/3/mypermissions.
+type MyPermissionsResponse struct {
+	Permissions map[string]Permission `json:"permissions"`
+}
+
+// Permission is one entry of MyPermissionsResponse.
+type Permission struct {
+	Key            string `json:"key"`
+	Name           string `json:"name"`
+	HavePermission bool   `json:"havePermission"`
+}
+
 // Comments wraps the comments array from the JIRA API.
 type Comments struct {
 	Comments []Comment `json:"comments"`
@@ -55,11 +179,75 @@ type Comments struct {
 
 // Comment represents a single JIRA comment.
 type Comment struct {
+	ID      string   `json:"id,omitempty"`
 	Author  User     `json:"author"`
 	Body    *ADFNode `json:"body"`
 	Created string   `json:"created"`
 }
 
+// Attachment is a file attached to an issue.
+type Attachment struct {
+	ID       string `json:"id"`
+	Filename string `json:"filename"`
+	MimeType string `json:"mimeType,omitempty"`
+	Size     int64  `json:"size"`
+	Created  string `json:"created,omitempty"`
+	Author   *User  `json:"author,omitempty"`
+}
+
+// Worklog is a single time tracking entry on an issue.
+type Worklog struct {
+	ID               string   `json:"id,omitempty"`
+	Author           User     `json:"author"`
+	Comment          *ADFNode `json:"comment,omitempty"`
+	Started          string   `json:"started"`
+	TimeSpent        string   `json:"timeSpent,omitempty"`
+	TimeSpentSeconds int      `json:"timeSpentSeconds"`
+}
+
+// WorklogPayload is the body for POST /rest/api/3/issue/{key}/worklog.
+type WorklogPayload struct {
+	TimeSpentSeconds int      `json:"timeSpentSeconds"`
+	Started          string   `json:"started"` // e.g. 2024-01-15T09:00:00.000+0000
+	Comment          *ADFNode `json:"comment,omitempty"`
+}
+
+// WorklogPage is one page of an issue's worklogs.
+type WorklogPage struct {
+	StartAt    int       `json:"startAt"`
+	MaxResults int       `json:"maxResults"`
+	Total      int       `json:"total"`
+	Worklogs   []Worklog `json:"worklogs"`
+}
+
+// ChangelogEntry is one change event in an issue's history; a single edit
+// can change several fields.
+type ChangelogEntry struct {
+	ID      string       `json:"id"`
+	Author  User         `json:"author"`
+	Created string       `json:"created"`
+	Items   []ChangeItem `json:"items"`
+}
+
+// ChangeItem is a change to a single field.
+type ChangeItem struct {
+	Field      string `json:"field"`
+	FieldType  string `json:"fieldtype,omitempty"`
+	From       string `json:"from,omitempty"`
+	FromString string `json:"fromString,omitempty"`
+	To         string `json:"to,omitempty"`
+	ToString   string `json:"toString,omitempty"`
+}
+
+// ChangelogPage is one page of an issue's changelog.
+type ChangelogPage struct {
+	StartAt    int              `json:"startAt"`
+	MaxResults int              `json:"maxResults"`
+	Total      int              `json:"total"`
+	IsLast     bool             `json:"isLast"`
+	Values     []ChangelogEntry `json:"values"`
+}
+
 // ADFNode represents a node in the Atlassian Document Format.
 type ADFNode struct {
 	Type    string         `json:"type"`
@@ -70,6 +258,22 @@ type ADFNode struct {
 	Marks   []ADFMark      `json:"marks,omitempty"`
 }
 
+// UnmarshalJSON decodes an ADF object, or a wiki markup string as API v2
+// returns for rich text on Data Center.
+func (n *ADFNode) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var wiki string
+		if err := json.Unmarshal(data, &wiki); err != nil {
+			return err
+		}
+		*n = *WikiToADF(wiki)
+		return nil
+	}
+
+	type plain ADFNode
+	return json.Unmarshal(data, (*plain)(n))
+}
+
 // ADFMark represents an inline formatting mark in ADF.
 type ADFMark struct {
 	Type  string         `json:"type"`
@@ -78,13 +282,13 @@ type ADFMark struct {
 
 // ConfluencePage represents a Confluence page from the REST API v2.
 type ConfluencePage struct {
-	ID      string         `json:"id"`
-	Title   string         `json:"title"`
-	Status  string         `json:"status"`
-	SpaceID string         `json:"spaceId"`
-	Version PageVersion    `json:"version"`
-	Body    PageBody       `json:"body"`
-	Links   PageLinks      `json:"_links"`
+	ID      string      `json:"id"`
+	Title   string      `json:"title"`
+	Status  string      `json:"status"`
+	SpaceID string      `json:"spaceId"`
+	Version PageVersion `json:"version"`
+	Body    PageBody    `json:"body"`
+	Links   PageLinks   `json:"_links"`
 }
 
 // ConfluenceComment represents a comment on a Confluence page (v2 API).
@@ -147,6 +351,13 @@ type ConfluenceSpace struct {
 	Name string `json:"name"`
 }
 
+// ConfluenceOperation is something the user may do in Confluence, such as
+// {Operation: "create", TargetType: "page"}.
+type ConfluenceOperation struct {
+	Operation  string `json:"operation"`
+	TargetType string `json:"targetType"`
+}
+
 // ConfluenceCreatePayload is the body for POST /wiki/api/v2/pages.
 type ConfluenceCreatePayload struct {
 	SpaceID  string               `json:"spaceId"`
@@ -169,9 +380,100 @@ type UpdatePayload struct {
 
 // UpdateFields contains the fields that can be updated.
 type UpdateFields struct {
-	Summary     string   `json:"summary,omitempty"`
-	Labels      []string `json:"labels,omitempty"`
-	Description *ADFNode `json:"description,omitempty"`
+	Summary     string      `json:"summary,omitempty"`
+	Labels      []string    `json:"labels,omitempty"`
+	Description *ADFNode    `json:"description,omitempty"`
+	Priority    *Priority   `json:"priority,omitempty"`
+	Assignee    *AccountRef `json:"assignee,omitempty"`
+	Reporter    *AccountRef `json:"reporter,omitempty"`
+
+	// Nil lists are omitted; empty lists are sent to clear the field.
+	Components  []Component `json:"components"`
+	FixVersions []Version   `json:"fixVersions"`
+	Versions    []Version   `json:"versions"`
+
+	// Custom holds customfield_* values, already in the shape the API expects.
+	Custom map[string]any `json:"-"`
+}
+
+// MarshalJSON merges Custom into the encoded fields object and drops nil
+// component and version lists.
+func (u UpdateFields) MarshalJSON() ([]byte, error) {
+	type plain UpdateFields
+	data, err := json.Marshal(plain(u))
+	if err != nil {
+		return nil, err
+	}
+
+	var merged map[string]any
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	for _, k := range []string{"components", "fixVersions", "versions"} {
+		if merged[k] == nil {
+			delete(merged, k)
+		}
+	}
+	for k, v := range u.Custom {
+		merged[k] = v
+	}
+	return json.Marshal(merged)
+}
+
+// FieldInfo describes a system or custom field from GET /rest/api/3/field.
+type FieldInfo struct {
+	ID     string      `json:"id"`
+	Name   string      `json:"name"`
+	Custom bool        `json:"custom"`
+	Schema FieldSchema `json:"schema"`
+}
+
+// FieldSchema describes the value shape of a field.
+type FieldSchema struct {
+	Type   string `json:"type"`             // "number", "string", "option", "user", "array", ...
+	Items  string `json:"items,omitempty"`  // element type when Type is "array"
+	Custom string `json:"custom,omitempty"` // custom field type key
+}
+
+// CreatePayload is the body for POST /rest/api/3/issue.
+type CreatePayload struct {
+	Fields CreateFields `json:"fields"`
+}
+
+// CreateFields contains the fields sent when creating an issue.
+type CreateFields struct {
+	Project     ProjectRef  `json:"project"`
+	Summary     string      `json:"summary"`
+	IssueType   IssueType   `json:"issuetype"`
+	Priority    *Priority   `json:"priority,omitempty"`
+	Labels      []string    `json:"labels,omitempty"`
+	Assignee    *AccountRef `json:"assignee,omitempty"`
+	Description *ADFNode    `json:"description,omitempty"`
+}
+
+// ProjectRef identifies a project by key in create payloads.
+type ProjectRef struct {
+	ID  string `json:"id,omitempty"`
+	Key string `json:"key"`
+}
+
+// AccountRef identifies a user in create and update payloads: by accountId
+// on Cloud, by username on Data Center.
+type AccountRef struct {
+	AccountID string `json:"accountId,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+// CreatedIssue is the response from POST /rest/api/3/issue.
+type CreatedIssue struct {
+	ID   string `json:"id"`
+	Key  string `json:"key"`
+	Self string `json:"self"`
+}
+
+// CommentPayload is the body for POST /rest/api/3/issue/{key}/comment.
+type CommentPayload struct {
+	Body *ADFNode `json:"body"`
 }
 
 // Transition is used to change issue status.
@@ -181,7 +483,8 @@ type Transition struct {
 
 // TransitionPayload is the body for POST /rest/api/3/issue/{key}/transitions.
 type TransitionPayload struct {
-	Transition Transition `json:"transition"`
+	Transition Transition     `json:"transition"`
+	Fields     map[string]any `json:"fields,omitempty"` // values for the transition screen
 }
 
 // TransitionsResponse is the response from GET transitions.
@@ -194,22 +497,78 @@ type TransitionInfo struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
 	To   Status `json:"to"`
+
+	// Fields on the transition screen, keyed by field id.
+	Fields map[string]TransitionField `json:"fields,omitempty"`
+}
+
+// TransitionField describes a field on a transition screen.
+type TransitionField struct {
+	Required        bool           `json:"required"`
+	HasDefaultValue bool           `json:"hasDefaultValue"`
+	Name            string         `json:"name"`
+	Schema          FieldSchema    `json:"schema"`
+	AllowedValues   []AllowedValue `json:"allowedValues,omitempty"`
+}
+
+// AllowedValue is one of the values a field accepts, such as a resolution
+// (ID and Name) or a select option (ID and Value).
+type AllowedValue struct {
+	ID    string `json:"id"`
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// WorkflowScheme maps a project's issue types to workflows.
+type WorkflowScheme struct {
+	Name              string            `json:"name"`
+	DefaultWorkflow   string            `json:"defaultWorkflow"`
+	IssueTypeMappings map[string]string `json:"issueTypeMappings"` // issue type id -> workflow name
+}
+
+// Workflow is a workflow with its statuses and transitions.
+type Workflow struct {
+	ID          WorkflowID           `json:"id"`
+	Statuses    []WorkflowStatus     `json:"statuses"`
+	Transitions []WorkflowTransition `json:"transitions"`
+}
+
+// WorkflowID identifies a workflow.
+type WorkflowID struct {
+	Name     string `json:"name"`
+	EntityID string `json:"entityId,omitempty"`
+}
+
+// WorkflowStatus is a status used by a workflow.
+type WorkflowStatus struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// WorkflowTransition is a transition in a workflow. From holds status ids;
+// it is empty for global transitions, which are available from any status.
+type WorkflowTransition struct {
+	ID   string   `json:"id"`
+	Name string   `json:"name"`
+	From []string `json:"from"`
+	To   string   `json:"to"`
+	Type string   `json:"type"` // "initial", "directed" or "global"
 }
 
-// SearchResult is the response from POST /rest/api/3/search/jql.
+// SearchResult is the response from POST /rest/api/3/search/jql. The endpoint
+// doesn't report a total; NextPageToken is set while more pages remain.
 type SearchResult struct {
-	Issues     []Issue `json:"issues"`
-	Total      int     `json:"total"`
-	MaxResults int     `json:"maxResults"`
-	StartAt    int     `json:"startAt"`
+	Issues        []Issue `json:"issues"`
+	NextPageToken string  `json:"nextPageToken,omitempty"`
+	IsLast        bool    `json:"isLast"`
 }
 
 // SearchPayload is the body for POST /rest/api/3/search/jql.
 type SearchPayload struct {
-	JQL        string   `json:"jql"`
-	MaxResults int      `json:"maxResults"`
-	StartAt    int      `json:"startAt"`
-	Fields     []string `json:"fields"`
+	JQL           string   `json:"jql"`
+	MaxResults    int      `json:"maxResults"`
+	NextPageToken string   `json:"nextPageToken,omitempty"`
+	Fields        []string `json:"fields"`
 }
 
 // ConfluenceChildrenResponse is the response from GET /wiki/api/v2/pages/{id}/children.
@@ -232,11 +591,12 @@ type PaginationLinks struct {
 
 // ConfluenceSearchResult is the response from Confluence content search.
 type ConfluenceSearchResult struct {
-	Results    []ConfluenceSearchEntry `json:"results"`
-	Start      int                     `json:"start"`
-	Limit      int                     `json:"limit"`
-	Size       int                     `json:"size"`
-	TotalSize  int                     `json:"totalSize"`
+	Results   []ConfluenceSearchEntry `json:"results"`
+	Start     int                     `json:"start"`
+	Limit     int                     `json:"limit"`
+	Size      int                     `json:"size"`
+	TotalSize int                     `json:"totalSize"`
+	Links     PaginationLinks         `json:"_links"`
 }
 
 // ConfluenceSearchEntry represents a single search result from Confluence.
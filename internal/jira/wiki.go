@@ -0,0 +1,613 @@
+package jira
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// JIRA's API v2, which Data Center uses, represents descriptions, comments
+// and other rich text as wiki markup strings rather than ADF. WikiToADF and
+// ADFToWiki convert between the two for the elements the markdown converter
+// understands; anything else is kept as plain text.
+
+var (
+	wikiHeading = regexp.MustCompile(`^h([1-6])\.\s+(.*)$`)
+	wikiList    = regexp.MustCompile(`^([*#-]+)\s+(.*)$`)
+	wikiCode    = regexp.MustCompile(`^\{(code|noformat)(?::([^}]*))?\}(.*)$`)
+)
+
+// WikiToADF converts JIRA wiki markup to an ADF document.
+func WikiToADF(s string) *ADFNode {
+	version := 1
+	return &ADFNode{Type: "doc", Version: &version, Content: wikiBlocks(strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n"))}
+}
+
+func wikiBlocks(lines []string) []ADFNode {
+	var blocks []ADFNode
+	var para []string
+
+	flush := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, ADFNode{Type: "paragraph", Content: wikiInline(strings.Join(para, "\n"), nil)})
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flush()
+
+		case wikiCode.MatchString(trimmed):
+			flush()
+			m := wikiCode.FindStringSubmatch(trimmed)
+			closing := "{" + m[1] + "}"
+			var code []string
+			rest := m[3]
+			for {
+				if end := strings.Index(rest, closing); end >= 0 {
+					code = append(code, rest[:end])
+					break
+				}
+				code = append(code, rest)
+				i++
+				if i >= len(lines) {
+					break
+				}
+				rest = lines[i]
+			}
+			if len(code) > 0 && code[0] == "" {
+				code = code[1:]
+			}
+			if len(code) > 0 && code[len(code)-1] == "" {
+				code = code[:len(code)-1]
+			}
+			node := ADFNode{Type: "codeBlock"}
+			if lang := wikiCodeLanguage(m[1], m[2]); lang != "" {
+				node.Attrs = map[string]any{"language": lang}
+			}
+			if text := strings.Join(code, "\n"); text != "" {
+				node.Content = []ADFNode{{Type: "text", Text: text}}
+			}
+			blocks = append(blocks, node)
+
+		case strings.HasPrefix(trimmed, "{quote}"):
+			flush()
+			var inner []string
+			rest := strings.TrimPrefix(trimmed, "{quote}")
+			for {
+				if end := strings.Index(rest, "{quote}"); end >= 0 {
+					inner = append(inner, rest[:end])
+					break
+				}
+				inner = append(inner, rest)
+				i++
+				if i >= len(lines) {
+					break
+				}
+				rest = lines[i]
+			}
+			blocks = append(blocks, ADFNode{Type: "blockquote", Content: wikiBlocks(inner)})
+
+		case strings.HasPrefix(trimmed, "bq. "):
+			flush()
+			blocks = append(blocks, ADFNode{Type: "blockquote", Content: []ADFNode{
+				{Type: "paragraph", Content: wikiInline(strings.TrimPrefix(trimmed, "bq. "), nil)},
+			}})
+
+		case trimmed == "----":
+			flush()
+			blocks = append(blocks, ADFNode{Type: "rule"})
+
+		case wikiHeading.MatchString(trimmed):
+			flush()
+			m := wikiHeading.FindStringSubmatch(trimmed)
+			blocks = append(blocks, ADFNode{
+				Type:    "heading",
+				Attrs:   map[string]any{"level": float64(m[1][0] - '0')},
+				Content: wikiInline(m[2], nil),
+			})
+
+		case wikiList.MatchString(trimmed):
+			flush()
+			var items []wikiListItem
+			kind := wikiList.FindStringSubmatch(trimmed)[1][0]
+			for ; i < len(lines); i++ {
+				m := wikiList.FindStringSubmatch(strings.TrimSpace(lines[i]))
+				if m == nil || m[1][0] != kind || strings.TrimSpace(lines[i]) == "----" {
+					break
+				}
+				items = append(items, wikiListItem{markers: m[1], text: m[2]})
+			}
+			i--
+			blocks = append(blocks, wikiListNode(items, 1))
+
+		case strings.HasPrefix(trimmed, "|"):
+			flush()
+			table := ADFNode{Type: "table"}
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				table.Content = append(table.Content, wikiTableRow(strings.TrimSpace(lines[i])))
+			}
+			i--
+			blocks = append(blocks, table)
+
+		default:
+			para = append(para, line)
+		}
+	}
+	flush()
+
+	return blocks
+}
+
+// wikiCodeLanguage returns the language of a {code} block, given either as
+// {code:go} or {code:language=go|title=...}.
+func wikiCodeLanguage(macro, params string) string {
+	if macro != "code" || params == "" {
+		return ""
+	}
+	for _, p := range strings.Split(params, "|") {
+		name, value, found := strings.Cut(p, "=")
+		if !found {
+			return strings.TrimSpace(name)
+		}
+		if strings.TrimSpace(name) == "language" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+type wikiListItem struct {
+	markers string // e.g. "*", "##", "#*"
+	text    string
+}
+
+// wikiListNode builds the list at depth from items, nesting deeper items
+// under the item before them.
+func wikiListNode(items []wikiListItem, depth int) ADFNode {
+	list := ADFNode{Type: "bulletList"}
+	if items[0].markers[depth-1] == '#' {
+		list.Type = "orderedList"
+	}
+
+	for i := 0; i < len(items); {
+		item := ADFNode{Type: "listItem", Content: []ADFNode{{Type: "paragraph", Content: wikiInline(items[i].text, nil)}}}
+		i++
+		j := i
+		for j < len(items) && len(items[j].markers) > depth {
+			j++
+		}
+		if j > i {
+			item.Content = append(item.Content, wikiListNode(items[i:j], depth+1))
+			i = j
+		}
+		list.Content = append(list.Content, item)
+	}
+	return list
+}
+
+// wikiTableRow parses a row such as "||Name||Value||" or "|a|[b|http://x]|".
+func wikiTableRow(line string) ADFNode {
+	row := ADFNode{Type: "tableRow"}
+	for len(line) > 0 {
+		cellType := "tableCell"
+		if strings.HasPrefix(line, "||") {
+			cellType = "tableHeader"
+			line = line[2:]
+		} else {
+			line = line[1:]
+		}
+
+		end := wikiCellEnd(line)
+		text := strings.TrimSpace(line[:end])
+		line = line[end:]
+		if text == "" && line == "" {
+			break
+		}
+		row.Content = append(row.Content, ADFNode{Type: cellType, Content: []ADFNode{
+			{Type: "paragraph", Content: wikiInline(text, nil)},
+		}})
+	}
+	return row
+}
+
+// wikiCellEnd returns the index of the | that ends a table cell, skipping
+// escaped bars and bars inside [links].
+func wikiCellEnd(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			if depth > 0 {
+				depth--
+			}
+		case '|':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// wikiMarks are the inline formatting characters and the ADF marks they stand for.
+var wikiMarks = map[byte]ADFMark{
+	'*': {Type: "strong"},
+	'_': {Type: "em"},
+	'-': {Type: "strike"},
+	'+': {Type: "underline"},
+	'^': {Type: "subsup", Attrs: map[string]any{"type": "sup"}},
+	'~': {Type: "subsup", Attrs: map[string]any{"type": "sub"}},
+}
+
+// wikiInline parses inline wiki markup into text nodes carrying marks.
+func wikiInline(s string, marks []ADFMark) []ADFNode {
+	var nodes []ADFNode
+	var text strings.Builder
+
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, wikiText(text.String(), marks))
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		switch {
+		case strings.HasPrefix(rest, `\\`):
+			flush()
+			nodes = append(nodes, ADFNode{Type: "hardBreak"})
+			i += 2
+
+		case rest[0] == '\\' && len(rest) > 1:
+			_, size := utf8.DecodeRuneInString(rest[1:])
+			text.WriteString(rest[1 : 1+size])
+			i += 1 + size
+
+		case rest[0] == '\n':
+			flush()
+			nodes = append(nodes, ADFNode{Type: "hardBreak"})
+			i++
+
+		case strings.HasPrefix(rest, "{{") && strings.Contains(rest[2:], "}}"):
+			flush()
+			end := strings.Index(rest[2:], "}}")
+			nodes = append(nodes, wikiText(rest[2:2+end], append(copyMarks(marks), ADFMark{Type: "code"})))
+			i += end + 4
+
+		case rest[0] == '[' && strings.Contains(rest, "]"):
+			flush()
+			end := strings.Index(rest, "]")
+			nodes = append(nodes, wikiLink(rest[1:end], marks)...)
+			i += end + 1
+
+		default:
+			if mark, ok := wikiMarks[rest[0]]; ok && wikiMarkOpens(s, i) {
+				if end := wikiMarkClose(s, i); end > 0 {
+					flush()
+					nodes = append(nodes, wikiInline(s[i+1:end], append(copyMarks(marks), mark))...)
+					i = end + 1
+					continue
+				}
+			}
+			_, size := utf8.DecodeRuneInString(rest)
+			text.WriteString(rest[:size])
+			i += size
+		}
+	}
+	flush()
+
+	return nodes
+}
+
+// wikiLink converts the inside of [...]: a link with optional text, or a
+// user mention.
+func wikiLink(inner string, marks []ADFMark) []ADFNode {
+	if strings.HasPrefix(inner, "~") {
+		name := strings.TrimPrefix(inner, "~")
+		return []ADFNode{{Type: "mention", Attrs: map[string]any{"id": name, "text": name}}}
+	}
+
+	text, href, found := strings.Cut(inner, "|")
+	if !found {
+		href = text
+	}
+	href = strings.TrimSpace(href)
+	return wikiInline(text, append(copyMarks(marks), ADFMark{Type: "link", Attrs: map[string]any{"href": href}}))
+}
+
+// wikiMarkOpens reports whether the formatting character at i can start a
+// mark: it follows a non-word character and precedes a non-space.
+func wikiMarkOpens(s string, i int) bool {
+	if i+1 >= len(s) || s[i+1] == ' ' || s[i+1] == s[i] {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return !isWordRune(r)
+}
+
+// wikiMarkClose returns the index of the character closing the mark opened
+// at i, or -1.
+func wikiMarkClose(s string, i int) int {
+	c := s[i]
+	for j := i + 2; j < len(s); j++ {
+		if s[j] == '\n' {
+			return -1
+		}
+		if s[j] != c || s[j-1] == ' ' || s[j-1] == '\\' {
+			continue
+		}
+		if j+1 == len(s) {
+			return j
+		}
+		if r, _ := utf8.DecodeRuneInString(s[j+1:]); !isWordRune(r) {
+			return j
+		}
+	}
+	return -1
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func wikiText(text string, marks []ADFMark) ADFNode {
+	node := ADFNode{Type: "text", Text: text}
+	if len(marks) > 0 {
+		node.Marks = copyMarks(marks)
+	}
+	return node
+}
+
+func copyMarks(marks []ADFMark) []ADFMark {
+	return append([]ADFMark(nil), marks...)
+}
+
+// ADFToWiki converts an ADF document to JIRA wiki markup.
+func ADFToWiki(doc *ADFNode) string {
+	if doc == nil {
+		return ""
+	}
+	return strings.TrimSpace(strings.Join(wikiBlockStrings(doc.Content), "\n\n"))
+}
+
+func wikiBlockStrings(nodes []ADFNode) []string {
+	var out []string
+	for i := range nodes {
+		if s := wikiBlock(&nodes[i]); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func wikiBlock(node *ADFNode) string {
+	switch node.Type {
+	case "paragraph":
+		return wikiInlineString(node.Content)
+
+	case "heading":
+		level := attrInt(node.Attrs, "level")
+		if level < 1 || level > 6 {
+			level = 2
+		}
+		return "h" + string(rune('0'+level)) + ". " + wikiInlineString(node.Content)
+
+	case "bulletList", "orderedList":
+		return strings.Join(wikiListLines(node, ""), "\n")
+
+	case "codeBlock":
+		open := "{code}"
+		if lang := attrString(node.Attrs, "language"); lang != "" {
+			open = "{code:" + lang + "}"
+		}
+		var text strings.Builder
+		for _, child := range node.Content {
+			text.WriteString(child.Text)
+		}
+		return open + "\n" + text.String() + "\n{code}"
+
+	case "blockquote":
+		return "{quote}\n" + strings.Join(wikiBlockStrings(node.Content), "\n\n") + "\n{quote}"
+
+	case "rule":
+		return "----"
+
+	case "table":
+		var rows []string
+		for _, row := range node.Content {
+			var b strings.Builder
+			for _, cell := range row.Content {
+				sep := "|"
+				if cell.Type == "tableHeader" {
+					sep = "||"
+				}
+				b.WriteString(sep)
+				b.WriteString(strings.ReplaceAll(strings.Join(wikiBlockStrings(cell.Content), `\\`), "\n", `\\`))
+				if b.Len() == len(sep) {
+					b.WriteString(" ")
+				}
+			}
+			if len(row.Content) > 0 {
+				if row.Content[len(row.Content)-1].Type == "tableHeader" {
+					b.WriteString("||")
+				} else {
+					b.WriteString("|")
+				}
+			}
+			rows = append(rows, b.String())
+		}
+		return strings.Join(rows, "\n")
+
+	default:
+		if len(node.Content) > 0 && node.Content[0].Type == "text" {
+			return wikiInlineString(node.Content)
+		}
+		return strings.Join(wikiBlockStrings(node.Content), "\n\n")
+	}
+}
+
+// wikiListLines renders a list, prefixing each item with the markers of the
+// lists it is nested in.
+func wikiListLines(list *ADFNode, prefix string) []string {
+	marker := "*"
+	if list.Type == "orderedList" {
+		marker = "#"
+	}
+	prefix += marker
+
+	var lines []string
+	for _, item := range list.Content {
+		var text []string
+		var nested []string
+		for i := range item.Content {
+			child := &item.Content[i]
+			if child.Type == "bulletList" || child.Type == "orderedList" {
+				nested = append(nested, wikiListLines(child, prefix)...)
+			} else {
+				text = append(text, wikiBlock(child))
+			}
+		}
+		lines = append(lines, prefix+" "+strings.ReplaceAll(strings.Join(text, `\\`), "\n", `\\`))
+		lines = append(lines, nested...)
+	}
+	return lines
+}
+
+func wikiInlineString(nodes []ADFNode) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		switch node.Type {
+		case "text":
+			b.WriteString(wikiMarked(node.Text, node.Marks))
+		case "hardBreak":
+			b.WriteString("\n")
+		case "mention":
+			id := attrString(node.Attrs, "id")
+			if id == "" {
+				id = strings.TrimPrefix(attrString(node.Attrs, "text"), "@")
+			}
+			b.WriteString("[~" + id + "]")
+		case "inlineCard":
+			b.WriteString("[" + attrString(node.Attrs, "url") + "]")
+		case "emoji":
+			if text := attrString(node.Attrs, "text"); text != "" {
+				b.WriteString(text)
+			} else {
+				b.WriteString(attrString(node.Attrs, "shortName"))
+			}
+		default:
+			b.WriteString(wikiInlineString(node.Content))
+		}
+	}
+	return b.String()
+}
+
+// wikiMarked renders text with its marks applied.
+func wikiMarked(text string, marks []ADFMark) string {
+	if hasMark(marks, "code") {
+		text = "{{" + text + "}}"
+	} else {
+		text = wikiEscape(text)
+	}
+
+	// Surrounding spaces go outside the mark characters, which must touch the text.
+	lead := text[:len(text)-len(strings.TrimLeft(text, " "))]
+	text = strings.TrimLeft(text, " ")
+	trail := text[len(strings.TrimRight(text, " ")):]
+	text = strings.TrimRight(text, " ")
+	if text == "" {
+		return lead + trail
+	}
+
+	for _, m := range marks {
+		switch m.Type {
+		case "strong":
+			text = "*" + text + "*"
+		case "em":
+			text = "_" + text + "_"
+		case "strike":
+			text = "-" + text + "-"
+		case "underline":
+			text = "+" + text + "+"
+		case "subsup":
+			if attrString(m.Attrs, "type") == "sub" {
+				text = "~" + text + "~"
+			} else {
+				text = "^" + text + "^"
+			}
+		}
+	}
+	for _, m := range marks {
+		if m.Type == "link" {
+			href := attrString(m.Attrs, "href")
+			if text == wikiEscape(href) {
+				text = "[" + href + "]"
+			} else {
+				text = "[" + text + "|" + href + "]"
+			}
+		}
+	}
+	return lead + text + trail
+}
+
+func hasMark(marks []ADFMark, markType string) bool {
+	for _, m := range marks {
+		if m.Type == markType {
+			return true
+		}
+	}
+	return false
+}
+
+// wikiEscape backslash-escapes characters that would otherwise be read as
+// markup: brackets, braces and bars always, and formatting characters where
+// they could open or close a mark.
+func wikiEscape(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case strings.ContainsRune(`\[]{}|`, r):
+			b.WriteByte('\\')
+		case r < utf8.RuneSelf && wikiMarks[byte(r)].Type != "":
+			before, _ := utf8.DecodeLastRuneInString(text[:i])
+			after, _ := utf8.DecodeRuneInString(text[i+size:])
+			if i == 0 || i+size == len(text) || !isWordRune(before) || !isWordRune(after) {
+				b.WriteByte('\\')
+			}
+		}
+		b.WriteString(text[i : i+size])
+		i += size
+	}
+	return b.String()
+}
+
+func attrString(attrs map[string]any, key string) string {
+	s, _ := attrs[key].(string)
+	return s
+}
+
+func attrInt(attrs map[string]any, key string) int {
+	switch v := attrs[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
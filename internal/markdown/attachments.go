@@ -0,0 +1,79 @@
+package markdown
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/mreider/a-cli/internal/jira"
+)
+
+// AttachmentFileNames returns the local file name for each attachment, in
+// order. Names are reduced to their base name; when several attachments share
+// a name, all but the first are prefixed with their attachment id.
+func AttachmentFileNames(attachments []jira.Attachment) []string {
+	names := make([]string, len(attachments))
+	used := make(map[string]bool, len(attachments))
+	for i, a := range attachments {
+		name := path.Base(strings.ReplaceAll(a.Filename, `\`, "/"))
+		if name == "." || name == "/" || name == ".." {
+			name = a.ID
+		}
+		if used[strings.ToLower(name)] {
+			name = a.ID + "-" + name
+		}
+		used[strings.ToLower(name)] = true
+		names[i] = name
+	}
+	return names
+}
+
+// imageLinkRe matches markdown images: ![alt](target) or ![alt](target "title").
+var imageLinkRe = regexp.MustCompile(`!\[[^\]]*\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+
+// LocalImagePaths returns the relative file paths referenced by markdown image
+// links in body, in order of first appearance. URLs and absolute paths are skipped.
+func LocalImagePaths(body string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, m := range imageLinkRe.FindAllStringSubmatch(body, -1) {
+		target := m[1]
+		if strings.Contains(target, "://") || strings.HasPrefix(target, "data:") ||
+			strings.HasPrefix(target, "/") || strings.HasPrefix(target, "#") {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(target); err == nil {
+			target = unescaped
+		}
+		if !seen[target] {
+			seen[target] = true
+			paths = append(paths, target)
+		}
+	}
+	return paths
+}
+
+// writeAttachments renders the ## Attachments section linking to the files
+// downloaded into assetsDir.
+func writeAttachments(b *strings.Builder, attachments []jira.Attachment, assetsDir string) {
+	b.WriteString("## Attachments\n\n")
+	for i, name := range AttachmentFileNames(attachments) {
+		target := (&url.URL{Path: path.Join(assetsDir, name)}).String()
+		b.WriteString(fmt.Sprintf("- [%s](%s) (%s)\n", attachments[i].Filename, target, formatSize(attachments[i].Size)))
+	}
+	b.WriteString("\n")
+}
+
+// formatSize formats a byte count as e.g. "512 B", "24 KB" or "1.5 MB".
+func formatSize(n int64) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%d KB", (n+512)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	}
+}
@@ -0,0 +1,71 @@
+package markdown
+
+import (
+	"encoding/json"
+
+	"github.com/mreider/a-cli/internal/jira"
+)
+
+// CustomFieldValue converts a raw JIRA custom field value into a plain value
+// suitable for YAML frontmatter. Options, users and other objects are reduced
+// to their display value (option value, email, name); arrays are reduced
+// element-wise; rich-text fields are rendered as markdown. Returns nil for
+// values that have no sensible plain form.
+func CustomFieldValue(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	// Rich-text (textarea) fields hold an ADF document
+	var node jira.ADFNode
+	if json.Unmarshal(raw, &node) == nil && node.Type == "doc" {
+		return ADFToBody(&node)
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil
+	}
+	return simplifyFieldValue(v)
+}
+
+// simplifyFieldValue reduces decoded JSON to scalars and lists of scalars.
+func simplifyFieldValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		// Order matters: users carry both emailAddress and displayName,
+		// sprints and teams carry both name and id.
+		for _, k := range []string{"value", "emailAddress", "name", "displayName", "key", "id"} {
+			if s, ok := val[k]; ok && s != nil && s != "" {
+				return s
+			}
+		}
+		return nil
+	case []interface{}:
+		out := make([]interface{}, 0, len(val))
+		for _, e := range val {
+			if s := simplifyFieldValue(e); s != nil {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return val
+	}
+}
+
+// customFieldValues renders the issue's mapped custom fields, keyed by
+// friendly name. Fields that are unset on the issue are omitted.
+func customFieldValues(issue *jira.Issue, fieldMap map[string]string) map[string]interface{} {
+	values := make(map[string]interface{})
+	for name, id := range fieldMap {
+		raw, ok := issue.Fields.Custom[id]
+		if !ok {
+			continue
+		}
+		if v := CustomFieldValue(raw); v != nil {
+			values[name] = v
+		}
+	}
+	return values
+}
@@ -0,0 +1,80 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mreider/a-cli/internal/jira"
+)
+
+// HistoryRow is a single field change, flattened from the changelog.
+type HistoryRow struct {
+	Time   string `json:"time"`
+	Author string `json:"author"`
+	Field  string `json:"field"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// HistoryRows flattens changelog entries into one row per changed field, in
+// changelog order. If fields is non-empty, only changes to those fields
+// (case-insensitive) are kept.
+func HistoryRows(entries []jira.ChangelogEntry, fields ...string) []HistoryRow {
+	var rows []HistoryRow
+	for _, e := range entries {
+		for _, item := range e.Items {
+			if len(fields) > 0 && !containsFold(fields, item.Field) {
+				continue
+			}
+			rows = append(rows, HistoryRow{
+				Time:   e.Created,
+				Author: userLabel(e.Author),
+				Field:  item.Field,
+				From:   changeValue(item.FromString, item.From),
+				To:     changeValue(item.ToString, item.To),
+			})
+		}
+	}
+	return rows
+}
+
+// changeValue prefers the display string of a changed value over its raw id.
+func changeValue(display, raw string) string {
+	if display != "" {
+		return display
+	}
+	return raw
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeHistory renders the ## History section as a table of field changes.
+func writeHistory(b *strings.Builder, entries []jira.ChangelogEntry) {
+	b.WriteString("## History\n\n")
+	rows := HistoryRows(entries)
+	if len(rows) == 0 {
+		b.WriteString("(No changes)\n\n")
+		return
+	}
+
+	b.WriteString("| Time | Author | Field | From | To |\n")
+	b.WriteString("| --- | --- | --- | --- | --- |\n")
+	for _, r := range rows {
+		b.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s |\n",
+			FormatDateTime(r.Time), tableCell(r.Author), tableCell(r.Field), tableCell(r.From), tableCell(r.To)))
+	}
+	b.WriteString("\n")
+}
+
+// tableCell flattens a value onto one line and escapes pipes for a markdown table.
+func tableCell(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -21,8 +21,24 @@ const (
 	// UnmarshalConfluencePage can reliably strip it (avoids false positives if
 	// the page body itself contains a "## Comments" heading).
 	ConfluenceCommentsMarker = "<!-- a-cli:comments -->"
+
+	// sectionsMarker is written after an issue's description. Everything
+	// below it (attachments, worklog, history, links and comments) is written
+	// on pull, so headings of the same names in the description are left alone.
+	sectionsMarker = "<!-- a-cli:sections -->"
+
+	// noDescription stands in for an issue without a description.
+	noDescription = "(No description)"
+
+	// commentIDPrefix starts the hidden marker written under each JIRA comment
+	// heading so that edits and deletions can be mapped back to the comment.
+	commentIDPrefix = "<!-- a-cli:comment id="
 )
 
+// Now returns the time written to a pulled file's synced: field. Replaying
+// a recorded session replaces it so the output matches the original run.
+var Now = time.Now
+
 // yamlQuote returns a YAML-safe string value. If the value contains characters
 // that are syntactically significant in YAML (colon, hash, brackets, etc.),
 // it is wrapped in double quotes with internal quotes escaped.
@@ -35,6 +51,20 @@ func yamlQuote(s string) string {
 	return s
 }
 
+// yamlList formats items as a YAML flow sequence, quoting items as needed.
+func yamlList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		if strings.Contains(item, ",") && yamlQuote(item) == item {
+			// A bare comma would split the item in a flow sequence
+			quoted[i] = `"` + item + `"`
+		} else {
+			quoted[i] = yamlQuote(item)
+		}
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
 // Human-readable descriptions for preserved ADF node types.
 var preservedDescriptions = map[string]string{
 	"mediaSingle":          "Inline image",
@@ -58,19 +88,60 @@ var preservedDescriptions = map[string]string{
 	"placeholder":          "Placeholder",
 }
 
+// MarshalOptions controls the optional parts of Marshal output.
+type MarshalOptions struct {
+	// CustomFields maps friendly frontmatter names to customfield ids. Values
+	// set on the issue are rendered in the frontmatter.
+	CustomFields map[string]string
+
+	// AssetsDir is the directory, relative to the markdown file, that the
+	// issue's attachments were downloaded to. When set, an ## Attachments
+	// section links to the local copies.
+	AssetsDir string
+
+	// Worklogs, when non-nil, are summarized in a ## Worklog section with the
+	// time spent per author.
+	Worklogs []jira.Worklog
+
+	// History, when non-nil, is rendered as a ## History table of field changes.
+	History []jira.ChangelogEntry
+
+	// Profile is the config profile the issue was pulled with, recorded next
+	// to the site so the file is only pushed back to the same site.
+	Profile string
+}
+
+// Origin identifies where a file was pulled from: the site, and the config
+// profile used to reach it ("" for the default settings).
+type Origin struct {
+	Site    string
+	Profile string
+}
+
+// writeOrigin writes the site: and profile: frontmatter lines.
+func writeOrigin(b *strings.Builder, origin Origin) {
+	if origin.Site != "" {
+		b.WriteString(fmt.Sprintf("site: %s\n", strings.TrimRight(origin.Site, "/")))
+	}
+	if origin.Profile != "" {
+		b.WriteString(fmt.Sprintf("profile: %s\n", yamlQuote(origin.Profile)))
+	}
+}
+
 // Marshal converts a JIRA issue into a markdown string with YAML frontmatter.
 // If customProps is non-nil, those properties are preserved after the JIRA-managed
 // fields. This allows user-added frontmatter (e.g., local_update_pending, para)
 // to survive re-pulls.
-func Marshal(issue *jira.Issue, baseURL string, customProps map[string]interface{}) (string, error) {
+func Marshal(issue *jira.Issue, baseURL string, customProps map[string]interface{}, opts MarshalOptions) (string, error) {
 	baseURL = strings.TrimRight(baseURL, "/")
 
 	var b strings.Builder
 
-	// YAML frontmatter (read-only metadata — not pushed back to JIRA)
+	// YAML frontmatter (pushed back by apply only for the keys listed)
 	b.WriteString("---\n")
-	b.WriteString("# READ-ONLY metadata pulled from JIRA. Changes here are NOT pushed back.\n")
-	b.WriteString("# Only the document body (below the frontmatter) is synced on push.\n")
+	b.WriteString("# Metadata pulled from JIRA. push syncs only the document body below the frontmatter.\n")
+	b.WriteString("# apply also pushes title, status, labels, priority, assignee, reporter, components,\n")
+	b.WriteString("# fixVersions, affectsVersions and mapped custom fields; other keys are read-only.\n")
 	b.WriteString(fmt.Sprintf("key: %s\n", yamlQuote(issue.Key)))
 	b.WriteString(fmt.Sprintf("title: %s\n", yamlQuote(issue.Fields.Summary)))
 	b.WriteString(fmt.Sprintf("status: %s\n", yamlQuote(issue.Fields.Status.Name)))
@@ -86,20 +157,45 @@ func Marshal(issue *jira.Issue, baseURL string, customProps map[string]interface
 	} else {
 		b.WriteString("labels: []\n")
 	}
+	b.WriteString(fmt.Sprintf("components: %s\n", yamlList(jira.ComponentNames(issue.Fields.Components))))
+	b.WriteString(fmt.Sprintf("fixVersions: %s\n", yamlList(jira.VersionNames(issue.Fields.FixVersions))))
+	b.WriteString(fmt.Sprintf("affectsVersions: %s\n", yamlList(jira.VersionNames(issue.Fields.Versions))))
 	if issue.Fields.Assignee != nil {
 		b.WriteString(fmt.Sprintf("assignee: %s\n", yamlQuote(issue.Fields.Assignee.EmailAddress)))
 	}
 	if issue.Fields.Reporter != nil {
 		b.WriteString(fmt.Sprintf("reporter: %s\n", yamlQuote(issue.Fields.Reporter.EmailAddress)))
 	}
+	if issue.Fields.Parent != nil {
+		b.WriteString(fmt.Sprintf("parent: %s\n", yamlQuote(issue.Fields.Parent.Key)))
+	}
+	if len(issue.Fields.Subtasks) > 0 {
+		keys := make([]string, 0, len(issue.Fields.Subtasks))
+		for _, st := range issue.Fields.Subtasks {
+			keys = append(keys, st.Key)
+		}
+		b.WriteString(fmt.Sprintf("subtasks: %s\n", yamlList(keys)))
+	}
+	// Custom fields from the config field map
+	if len(opts.CustomFields) > 0 {
+		b.WriteString(FormatCustomProperties(customFieldValues(issue, opts.CustomFields)))
+	}
 	b.WriteString(fmt.Sprintf("url: %s/browse/%s\n", baseURL, issue.Key))
+	writeOrigin(&b, Origin{Site: baseURL, Profile: opts.Profile})
 	if issue.Fields.Updated != "" {
 		b.WriteString(fmt.Sprintf("updated: %s\n", issue.Fields.Updated))
 	}
-	b.WriteString(fmt.Sprintf("synced: %s\n", time.Now().UTC().Format(time.RFC3339)))
-	// Preserve custom frontmatter properties from existing file
+	b.WriteString(fmt.Sprintf("synced: %s\n", Now().UTC().Format(time.RFC3339)))
+	// Preserve custom frontmatter properties from existing file (mapped custom
+	// fields were already rendered above)
 	if len(customProps) > 0 {
-		extra := FormatCustomProperties(customProps)
+		props := make(map[string]interface{}, len(customProps))
+		for k, v := range customProps {
+			if _, mapped := opts.CustomFields[k]; !mapped {
+				props[k] = v
+			}
+		}
+		extra := FormatCustomProperties(props)
 		if extra != "" {
 			b.WriteString(extra)
 		}
@@ -118,20 +214,48 @@ func Marshal(issue *jira.Issue, baseURL string, customProps map[string]interface
 			b.WriteString("\n")
 		}
 	} else {
-		b.WriteString("(No description)\n")
+		b.WriteString(noDescription + "\n")
+	}
+	b.WriteString("\n" + sectionsMarker + "\n\n")
+
+	// Attachments
+	if opts.AssetsDir != "" && len(issue.Fields.Attachment) > 0 {
+		writeAttachments(&b, issue.Fields.Attachment, opts.AssetsDir)
+	}
+
+	// Worklog
+	if opts.Worklogs != nil {
+		writeWorklog(&b, opts.Worklogs)
+	}
+
+	// History
+	if opts.History != nil {
+		writeHistory(&b, opts.History)
+	}
+
+	// Links
+	if len(issue.Fields.IssueLinks) > 0 {
+		b.WriteString("## Links\n\n")
+		for _, l := range issue.Fields.IssueLinks {
+			phrase, other := LinkPhrase(l)
+			if other == nil {
+				continue
+			}
+			b.WriteString(fmt.Sprintf("- %s %s", phrase, other.Key))
+			if other.Fields != nil && other.Fields.Summary != "" {
+				b.WriteString(fmt.Sprintf(": %s", other.Fields.Summary))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
 	}
-	b.WriteString("\n")
 
 	// Comments
 	if issue.Fields.Comment != nil && len(issue.Fields.Comment.Comments) > 0 {
 		b.WriteString("## Comments\n\n")
 		for _, c := range issue.Fields.Comment.Comments {
-			author := c.Author.EmailAddress
-			if author == "" {
-				author = c.Author.DisplayName
-			}
-			date := formatDate(c.Created)
-			b.WriteString(fmt.Sprintf("### %s - %s\n\n", author, date))
+			b.WriteString(commentHeading(c))
+			b.WriteString("\n")
 			if c.Body != nil {
 				body := renderADF(c.Body)
 				b.WriteString(body)
@@ -146,10 +270,34 @@ func Marshal(issue *jira.Issue, baseURL string, customProps map[string]interface
 	return b.String(), nil
 }
 
+// commentHeading returns a comment's "### author - date" heading line and,
+// when it has an id, the hidden id marker line below it.
+func commentHeading(c jira.Comment) string {
+	author := c.Author.EmailAddress
+	if author == "" {
+		author = c.Author.DisplayName
+	}
+	heading := fmt.Sprintf("### %s - %s\n", author, formatDate(c.Created))
+	if c.ID != "" {
+		heading += fmt.Sprintf("%s%s -->\n", commentIDPrefix, c.ID)
+	}
+	return heading
+}
+
+// LinkPhrase returns the phrase describing a link from the point of view of
+// the issue holding it ("blocks", "is blocked by", ...) and the issue on the
+// other side.
+func LinkPhrase(l jira.IssueLink) (string, *jira.LinkedIssue) {
+	if l.OutwardIssue != nil {
+		return l.Type.Outward, l.OutwardIssue
+	}
+	return l.Type.Inward, l.InwardIssue
+}
+
 // MarshalConfluencePage converts a Confluence page (with ADF body) into markdown
 // with YAML frontmatter. Reuses the same ADF→markdown converter as JIRA issues.
 // If customProps is non-nil, those properties are preserved after the Confluence-managed fields.
-func MarshalConfluencePage(page *jira.ConfluencePage, space *jira.ConfluenceSpace, customProps map[string]interface{}, footerComments, inlineComments []jira.ConfluenceComment) (string, error) {
+func MarshalConfluencePage(page *jira.ConfluencePage, space *jira.ConfluenceSpace, origin Origin, customProps map[string]interface{}, footerComments, inlineComments []jira.ConfluenceComment) (string, error) {
 	var b strings.Builder
 
 	// YAML frontmatter (read-only)
@@ -168,7 +316,8 @@ func MarshalConfluencePage(page *jira.ConfluencePage, space *jira.ConfluenceSpac
 	if page.Links.Base != "" && page.Links.WebUI != "" {
 		b.WriteString(fmt.Sprintf("url: %s%s\n", page.Links.Base, page.Links.WebUI))
 	}
-	b.WriteString(fmt.Sprintf("synced: %s\n", time.Now().UTC().Format(time.RFC3339)))
+	writeOrigin(&b, origin)
+	b.WriteString(fmt.Sprintf("synced: %s\n", Now().UTC().Format(time.RFC3339)))
 	// Preserve custom frontmatter properties from existing file
 	if len(customProps) > 0 {
 		extra := FormatCustomProperties(customProps)
@@ -239,6 +388,12 @@ func MarshalConfluencePage(page *jira.ConfluencePage, space *jira.ConfluenceSpac
 	return b.String(), nil
 }
 
+// ADFToBody converts an ADF document to a markdown body string.
+// It is the inverse of BodyToADF.
+func ADFToBody(node *jira.ADFNode) string {
+	return strings.TrimSpace(renderADF(node))
+}
+
 // renderADF converts an ADF node tree to markdown.
 func renderADF(node *jira.ADFNode) string {
 	if node == nil {
@@ -570,16 +725,29 @@ func stripHTMLTags(s string) string {
 }
 
 func formatDate(isoDate string) string {
-	t, err := time.Parse("2006-01-02T15:04:05.000-0700", isoDate)
-	if err != nil {
-		// Try alternative formats
-		t, err = time.Parse("2006-01-02T15:04:05.000Z0700", isoDate)
-		if err != nil {
-			t, err = time.Parse(time.RFC3339, isoDate)
-			if err != nil {
-				return isoDate // Return as-is if we can't parse
-			}
-		}
+	t, ok := parseJiraTime(isoDate)
+	if !ok {
+		return isoDate // Return as-is if we can't parse
 	}
 	return t.Format("2006-01-02")
 }
+
+// FormatDateTime formats a JIRA timestamp as "2006-01-02 15:04" in the
+// timestamp's own offset. Unparseable input is returned as-is.
+func FormatDateTime(isoDate string) string {
+	t, ok := parseJiraTime(isoDate)
+	if !ok {
+		return isoDate
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+// parseJiraTime parses the timestamp formats returned by the JIRA API.
+func parseJiraTime(isoDate string) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02T15:04:05.000-0700", "2006-01-02T15:04:05.000Z0700", time.RFC3339} {
+		if t, err := time.Parse(layout, isoDate); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -9,19 +9,46 @@ type Ticket struct {
 	Priority string
 	Labels   []string
 	Assignee string
+
+	// Nil when the key is absent from the frontmatter (leave unchanged);
+	// an empty list clears the field.
+	Components      []string
+	FixVersions     []string
+	AffectsVersions []string
+
 	Reporter string
+	Parent   string   // read-only
+	Subtasks []string // read-only
 	URL      string
+	Site     string // site the file was pulled from; empty in older files
+	Profile  string // config profile it was pulled with
 	Updated  string
 	Synced   string
 	Body     string // markdown description
 	Comments []TicketComment
+
+	// Links is nil when the file has no ## Links section (leave links
+	// unchanged); an empty section removes every link.
+	Links []TicketLink
+
+	// Extra holds frontmatter keys not managed by a-cli itself: user-added
+	// properties and custom fields from the config field map.
+	Extra map[string]interface{}
 }
 
 // TicketComment represents a single comment in the intermediate format.
 type TicketComment struct {
+	ID     string // JIRA comment id; empty for new comments and files pulled before ids were recorded
 	Author string
 	Date   string
 	Body   string
+	New    bool // written locally under a "### new" heading and not yet in JIRA
+}
+
+// TicketLink is a line in the ## Links section, e.g. "- blocks PRODUCT-12".
+type TicketLink struct {
+	Phrase string // link type phrase as JIRA shows it: "blocks", "is blocked by", ...
+	Key    string
 }
 
 // ConfluenceDoc is the intermediate representation between Confluence and markdown.
@@ -33,6 +60,8 @@ type ConfluenceDoc struct {
 	SpaceName string
 	Version   int
 	URL       string
+	Site      string // site the file was pulled from; empty in older files
+	Profile   string // config profile it was pulled with
 	Synced    string
 	Body      string // markdown body (without frontmatter and title heading)
 }
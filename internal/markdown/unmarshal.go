@@ -16,22 +16,24 @@ import (
 // Any key NOT in this set is considered a user-added custom property.
 var jiraOwnedKeys = map[string]bool{
 	"key": true, "title": true, "status": true, "statusCategory": true,
-	"type": true, "priority": true, "labels": true, "assignee": true,
-	"reporter": true, "url": true, "updated": true, "synced": true,
+	"type": true, "priority": true, "labels": true, "components": true,
+	"fixVersions": true, "affectsVersions": true, "parent": true, "subtasks": true, "assignee": true,
+	"reporter": true, "url": true, "site": true, "profile": true, "updated": true, "synced": true,
 }
 
 // confluenceOwnedKeys are frontmatter keys regenerated from Confluence data on each pull.
 var confluenceOwnedKeys = map[string]bool{
 	"source": true, "pageId": true, "title": true, "status": true,
 	"spaceKey": true, "spaceName": true, "version": true, "url": true,
-	"synced": true,
+	"site": true, "profile": true, "synced": true,
 }
 
 // ExtractCustomProperties parses YAML frontmatter from an existing file and
 // returns any properties that are NOT managed by the jira CLI. These are
 // user-added custom properties (e.g., local_update_pending, discuss_with,
-// customer_evidence, last_reviewed, para).
-func ExtractCustomProperties(content string) (map[string]interface{}, error) {
+// customer_evidence, last_reviewed, para). ownedKeys names additional keys
+// that are regenerated on pull, such as mapped custom fields.
+func ExtractCustomProperties(content string, ownedKeys ...string) (map[string]interface{}, error) {
 	fm, _, err := splitFrontmatter(content)
 	if err != nil {
 		return nil, err
@@ -42,9 +44,14 @@ func ExtractCustomProperties(content string) (map[string]interface{}, error) {
 		return nil, err
 	}
 
+	owned := make(map[string]bool, len(ownedKeys))
+	for _, k := range ownedKeys {
+		owned[k] = true
+	}
+
 	custom := make(map[string]interface{})
 	for k, v := range all {
-		if !jiraOwnedKeys[k] {
+		if !jiraOwnedKeys[k] && !owned[k] {
 			custom[k] = v
 		}
 	}
@@ -102,35 +109,75 @@ func FormatCustomProperties(props map[string]interface{}) string {
 
 // frontmatter is the YAML frontmatter fields for JIRA issues.
 type frontmatter struct {
-	Key            string   `yaml:"key"`
-	Title          string   `yaml:"title"`
-	Status         string   `yaml:"status"`
-	StatusCategory string   `yaml:"statusCategory"`
-	Type           string   `yaml:"type"`
-	Priority       string   `yaml:"priority"`
-	Labels         []string `yaml:"labels"`
-	Assignee       string   `yaml:"assignee"`
-	Reporter       string   `yaml:"reporter"`
-	URL            string   `yaml:"url"`
-	Updated        string   `yaml:"updated"`
-	Synced         string   `yaml:"synced"`
+	Key             string   `yaml:"key"`
+	Title           string   `yaml:"title"`
+	Status          string   `yaml:"status"`
+	StatusCategory  string   `yaml:"statusCategory"`
+	Type            string   `yaml:"type"`
+	Priority        string   `yaml:"priority"`
+	Labels          []string `yaml:"labels"`
+	Components      []string `yaml:"components"`
+	FixVersions     []string `yaml:"fixVersions"`
+	AffectsVersions []string `yaml:"affectsVersions"`
+	Assignee        string   `yaml:"assignee"`
+	Reporter        string   `yaml:"reporter"`
+	Parent          string   `yaml:"parent"`
+	Subtasks        []string `yaml:"subtasks"`
+	URL             string   `yaml:"url"`
+	Site            string   `yaml:"site"`
+	Profile         string   `yaml:"profile"`
+	Updated         string   `yaml:"updated"`
+	Synced          string   `yaml:"synced"`
 }
 
 // confluenceFrontmatter is the YAML frontmatter fields for Confluence pages.
 type confluenceFrontmatter struct {
 	Source    string `yaml:"source"`
-	PageID   string `yaml:"pageId"`
-	Title    string `yaml:"title"`
-	Status   string `yaml:"status"`
-	SpaceKey string `yaml:"spaceKey"`
+	PageID    string `yaml:"pageId"`
+	Title     string `yaml:"title"`
+	Status    string `yaml:"status"`
+	SpaceKey  string `yaml:"spaceKey"`
 	SpaceName string `yaml:"spaceName"`
-	Version  int    `yaml:"version"`
-	URL      string `yaml:"url"`
-	Synced   string `yaml:"synced"`
+	Version   int    `yaml:"version"`
+	URL       string `yaml:"url"`
+	Site      string `yaml:"site"`
+	Profile   string `yaml:"profile"`
+	Synced    string `yaml:"synced"`
 }
 
 // Unmarshal parses a markdown file with YAML frontmatter into a Ticket.
 func Unmarshal(content string) (*Ticket, error) {
+	ticket, err := parseTicket(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if ticket.Key == "" {
+		return nil, fmt.Errorf("frontmatter missing required 'key' field")
+	}
+
+	return ticket, nil
+}
+
+// UnmarshalDraft parses a markdown file for an issue that does not exist in
+// JIRA yet. Unlike Unmarshal, the 'key' field is optional.
+func UnmarshalDraft(content string) (*Ticket, error) {
+	return parseTicket(content)
+}
+
+// SetDraftKey returns a draft with key set in its frontmatter, for recording
+// the key of an issue that was created but couldn't be pulled back.
+func SetDraftKey(content, key string) (string, error) {
+	fm, body, err := splitFrontmatter(content)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("---\nkey: %s\n%s\n---\n\n%s", yamlQuote(key), fm, body), nil
+}
+
+// parseTicket parses frontmatter, description and comments without
+// validating required fields.
+func parseTicket(content string) (*Ticket, error) {
 	fm, body, err := splitFrontmatter(content)
 	if err != nil {
 		return nil, err
@@ -141,30 +188,53 @@ func Unmarshal(content string) (*Ticket, error) {
 		return nil, fmt.Errorf("parsing frontmatter: %w", err)
 	}
 
-	if meta.Key == "" {
-		return nil, fmt.Errorf("frontmatter missing required 'key' field")
+	var all map[string]interface{}
+	if err := yaml.Unmarshal([]byte(fm), &all); err != nil {
+		return nil, fmt.Errorf("parsing frontmatter: %w", err)
+	}
+	extra := make(map[string]interface{})
+	for k, v := range all {
+		if !jiraOwnedKeys[k] {
+			extra[k] = v
+		}
 	}
 
 	// Strip the title heading (# KEY: Title) if present
 	body = stripTitleHeading(body, meta.Key)
 
-	// Split body from comments section
-	desc, comments := splitComments(body)
+	// Split the description from the sections written on pull
+	desc, links, comments, err := splitSections(stripDescriptionHeading(body))
+	if err != nil {
+		return nil, err
+	}
+	desc = strings.TrimSpace(desc)
+	if desc == noDescription {
+		desc = ""
+	}
 
 	ticket := &Ticket{
-		Key:      meta.Key,
-		Title:    meta.Title,
-		Status:   meta.Status,
-		Type:     meta.Type,
-		Priority: meta.Priority,
-		Labels:   meta.Labels,
-		Assignee: meta.Assignee,
-		Reporter: meta.Reporter,
-		URL:      meta.URL,
-		Updated:  meta.Updated,
-		Synced:   meta.Synced,
-		Body:     strings.TrimSpace(desc),
-		Comments: comments,
+		Key:             meta.Key,
+		Title:           meta.Title,
+		Status:          meta.Status,
+		Type:            meta.Type,
+		Priority:        meta.Priority,
+		Labels:          meta.Labels,
+		Components:      meta.Components,
+		FixVersions:     meta.FixVersions,
+		AffectsVersions: meta.AffectsVersions,
+		Assignee:        meta.Assignee,
+		Reporter:        meta.Reporter,
+		Parent:          meta.Parent,
+		Subtasks:        meta.Subtasks,
+		URL:             meta.URL,
+		Site:            meta.Site,
+		Profile:         meta.Profile,
+		Updated:         meta.Updated,
+		Synced:          meta.Synced,
+		Body:            desc,
+		Comments:        comments,
+		Links:           links,
+		Extra:           extra,
 	}
 
 	return ticket, nil
@@ -188,6 +258,43 @@ func ToUpdatePayload(ticket *Ticket) (*jira.UpdatePayload, error) {
 	return payload, nil
 }
 
+// defaultIssueType is used when a draft has no 'type' in its frontmatter.
+const defaultIssueType = "Task"
+
+// ToCreatePayload converts a draft Ticket into a JIRA API create payload for
+// the given project. The assignee is left unset because it must be resolved
+// to an accountId by the caller.
+func ToCreatePayload(ticket *Ticket, projectKey string) (*jira.CreatePayload, error) {
+	if strings.TrimSpace(ticket.Title) == "" {
+		return nil, fmt.Errorf("frontmatter missing required 'title' field")
+	}
+
+	adf, err := markdownToADF(ticket.Body)
+	if err != nil {
+		return nil, fmt.Errorf("converting description to ADF: %w", err)
+	}
+
+	issueType := ticket.Type
+	if issueType == "" {
+		issueType = defaultIssueType
+	}
+
+	payload := &jira.CreatePayload{
+		Fields: jira.CreateFields{
+			Project:     jira.ProjectRef{Key: projectKey},
+			Summary:     ticket.Title,
+			IssueType:   jira.IssueType{Name: issueType},
+			Labels:      ticket.Labels,
+			Description: adf,
+		},
+	}
+	if ticket.Priority != "" {
+		payload.Fields.Priority = &jira.Priority{Name: ticket.Priority}
+	}
+
+	return payload, nil
+}
+
 // splitFrontmatter separates YAML frontmatter from the body.
 func splitFrontmatter(content string) (string, string, error) {
 	content = strings.TrimSpace(content)
@@ -225,24 +332,103 @@ func stripTitleHeading(body string, key string) string {
 	return body
 }
 
-// splitComments separates the description from the ## Comments section.
-func splitComments(body string) (string, []TicketComment) {
-	// Strip the "## Description" heading if present (always, not just when comments exist)
-	body = stripDescriptionHeading(body)
+// splitSections separates the description from the sections Marshal writes
+// below the sections marker, returning the links and comments found there.
+// The reference-only sections (attachments, worklog, history) are only ever
+// written below the marker and are dropped with it.
+// Files pulled before the marker was written are only split at the first
+// ## Comments heading: a ## Links heading there may be the user's own, so
+// their links are nil and left alone on apply.
+func splitSections(body string) (string, []TicketLink, []TicketComment, error) {
+	desc, sections, marked := strings.Cut(body, sectionsMarker)
+	if !marked {
+		desc, comments, err := splitComments(body)
+		return desc, nil, comments, err
+	}
+
+	sections, comments, err := splitComments(sections)
+	_, links := splitLinks(sections)
+	return desc, links, comments, err
+}
 
+// splitComments separates the text before the ## Comments section from the
+// comments.
+func splitComments(body string) (string, []TicketComment, error) {
 	// Look for "## Comments" (case-insensitive)
 	re := regexp.MustCompile(`(?m)^## Comments\s*$`)
 	loc := re.FindStringIndex(body)
 	if loc == nil {
-		return body, nil
+		return body, nil, nil
 	}
 
 	desc := body[:loc[0]]
 	commentSection := body[loc[1]:]
 
-	comments := parseComments(commentSection)
+	comments, err := parseComments(commentSection)
+
+	return desc, comments, err
+}
+
+// RecordPostedComments rewrites the "### new" headings in content's comments
+// section, in order, as the heading and id marker of the comment posted for
+// each, so the next apply doesn't post them again. A nil entry leaves its
+// heading alone.
+func RecordPostedComments(content string, posted []*jira.Comment) string {
+	start := strings.Index(content, sectionsMarker)
+	if start < 0 {
+		start = 0
+	}
+	loc := regexp.MustCompile(`(?m)^## Comments\s*$`).FindStringIndex(content[start:])
+	if loc == nil {
+		return content
+	}
+	head, section := content[:start+loc[1]], content[start+loc[1]:]
+
+	lines := strings.Split(section, "\n")
+	n := 0
+	for i, line := range lines {
+		m := commentHeadingRe.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if m == nil || m[2] != "" || !strings.EqualFold(m[1], "new") {
+			continue
+		}
+		if i+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i+1]), commentIDPrefix) {
+			continue
+		}
+		if n < len(posted) && posted[n] != nil {
+			lines[i] = strings.TrimSuffix(commentHeading(*posted[n]), "\n")
+		}
+		n++
+	}
+	return head + strings.Join(lines, "\n")
+}
+
+// linkLineRe matches "- <phrase> <KEY>" lines; anything after the key (such as
+// the summary written on pull) is ignored.
+var linkLineRe = regexp.MustCompile(`^[-*]\s+(.+?)\s+([A-Za-z][A-Za-z0-9_]*-\d+)\b`)
+
+// splitLinks separates the ## Links section from the text before it. The
+// section ends at the next ## heading. The links are nil when there is no
+// such section.
+func splitLinks(desc string) (string, []TicketLink) {
+	re := regexp.MustCompile(`(?m)^## Links\s*$`)
+	loc := re.FindStringIndex(desc)
+	if loc == nil {
+		return desc, nil
+	}
+
+	links := []TicketLink{}
+	for _, line := range strings.Split(desc[loc[1]:], "\n") {
+		if strings.HasPrefix(line, "## ") {
+			break
+		}
+		m := linkLineRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		links = append(links, TicketLink{Phrase: m[1], Key: strings.ToUpper(m[2])})
+	}
 
-	return desc, comments
+	return desc[:loc[0]], links
 }
 
 // stripDescriptionHeading removes "## Description" from the beginning of the description.
@@ -256,37 +442,80 @@ func stripDescriptionHeading(desc string) string {
 	return desc
 }
 
-// parseComments parses the comments section into TicketComment structs.
-func parseComments(section string) []TicketComment {
-	// Comments are under ### headings
-	re := regexp.MustCompile(`(?m)^### (.+) - (\S+)\s*$`)
-	matches := re.FindAllStringSubmatchIndex(section, -1)
+// commentHeadingRe matches a comment heading: "### author - date" on pulled
+// comments and "### new" on comments written locally.
+var commentHeadingRe = regexp.MustCompile(`^### (.+?)(?: - (\S+))?\s*$`)
 
-	if len(matches) == 0 {
-		return nil
-	}
+// parseComments parses the comments section into TicketComment structs.
+// A pulled comment starts at its "### author - date" heading followed by the
+// hidden id marker, and a comment written locally starts at a "### new"
+// heading. Any other ### heading is part of the comment it appears in. Files
+// pulled before ids were recorded have no markers, so there every dated
+// heading starts a comment. Text before the first comment is an error, since
+// it would otherwise be dropped.
+func parseComments(section string) ([]TicketComment, error) {
+	legacy := !strings.Contains(section, commentIDPrefix)
+	lines := strings.Split(section, "\n")
 
 	var comments []TicketComment
-	for i, match := range matches {
-		author := section[match[2]:match[3]]
-		date := section[match[4]:match[5]]
-
-		var body string
-		start := match[1]
-		if i+1 < len(matches) {
-			body = section[start:matches[i+1][0]]
+	var body []string
+	var stray string
+	start := func(c TicketComment) {
+		if n := len(comments); n > 0 {
+			comments[n-1].Body = strings.TrimSpace(strings.Join(body, "\n"))
 		} else {
-			body = section[start:]
+			stray = strings.TrimSpace(strings.Join(body, "\n"))
 		}
+		comments = append(comments, c)
+		body = nil
+	}
 
-		comments = append(comments, TicketComment{
-			Author: author,
-			Date:   date,
-			Body:   strings.TrimSpace(body),
-		})
+	for i := 0; i < len(lines); i++ {
+		m := commentHeadingRe.FindStringSubmatch(strings.TrimRight(lines[i], "\r"))
+		if m == nil {
+			body = append(body, lines[i])
+			continue
+		}
+		author, date := m[1], m[2]
+
+		switch {
+		case i+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i+1]), commentIDPrefix):
+			id, _ := extractCommentID(strings.TrimSpace(lines[i+1]))
+			start(TicketComment{ID: id, Author: author, Date: date})
+			i++
+		case date == "" && strings.EqualFold(author, "new"):
+			start(TicketComment{Author: author, New: true})
+		case legacy && date != "":
+			start(TicketComment{Author: author, Date: date})
+		default:
+			body = append(body, lines[i])
+		}
+	}
+	if n := len(comments); n > 0 {
+		comments[n-1].Body = strings.TrimSpace(strings.Join(body, "\n"))
+	} else {
+		stray = strings.TrimSpace(strings.Join(body, "\n"))
+	}
+
+	if stray != "" {
+		first, _, _ := strings.Cut(stray, "\n")
+		return nil, fmt.Errorf("## Comments: %q is not under a comment heading; start new comments with a \"### new\" heading", strings.TrimSpace(first))
 	}
+	return comments, nil
+}
 
-	return comments
+// extractCommentID strips the hidden comment id marker from the start of a
+// comment body and returns the id along with the remaining body.
+func extractCommentID(body string) (string, string) {
+	if !strings.HasPrefix(body, commentIDPrefix) {
+		return "", body
+	}
+	end := strings.Index(body, "-->")
+	if end < 0 {
+		return "", body
+	}
+	id := strings.TrimSpace(body[len(commentIDPrefix):end])
+	return id, body[end+len("-->"):]
 }
 
 // markdownToADF converts markdown text to an ADF document node.
@@ -742,6 +971,8 @@ func UnmarshalConfluencePage(content string) (*ConfluenceDoc, error) {
 		SpaceName: meta.SpaceName,
 		Version:   meta.Version,
 		URL:       meta.URL,
+		Site:      meta.Site,
+		Profile:   meta.Profile,
 		Synced:    meta.Synced,
 		Body:      strings.TrimSpace(body),
 	}
@@ -781,8 +1012,8 @@ func parseInline(text string) []jira.ADFNode {
 	// Process inline formatting using a simple state machine
 	// Order of patterns matters: check longer patterns first
 	patterns := []struct {
-		re      *regexp.Regexp
-		markFn  func(match []string) ([]jira.ADFNode, bool)
+		re     *regexp.Regexp
+		markFn func(match []string) ([]jira.ADFNode, bool)
 	}{
 		// Links: [text](url)
 		{
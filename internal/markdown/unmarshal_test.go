@@ -0,0 +1,174 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mreider/a-cli/internal/jira"
+)
+
+// mustADF converts markdown to ADF for building test issues.
+func mustADF(t *testing.T, md string) *jira.ADFNode {
+	t.Helper()
+	node, err := markdownToADF(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return node
+}
+
+// testIssue returns an issue with the given description and comments.
+func testIssue(t *testing.T, description string, comments ...jira.Comment) *jira.Issue {
+	issue := &jira.Issue{Key: "PROJ-1", Fields: jira.Fields{
+		Summary:     "Fix login",
+		Description: mustADF(t, description),
+	}}
+	if len(comments) > 0 {
+		issue.Fields.Comment = &jira.Comments{Comments: comments}
+	}
+	return issue
+}
+
+// roundTrip marshals issue and parses the result back.
+func roundTrip(t *testing.T, issue *jira.Issue, opts MarshalOptions) *Ticket {
+	t.Helper()
+	content, err := Marshal(issue, "https://example.atlassian.net", nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticket, err := Unmarshal(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ticket
+}
+
+func TestRoundTrip_CommentWithHeading(t *testing.T) {
+	body := "Seen on Safari only.\n\n### Steps\n\n1. Open the login page\n2. Submit"
+	issue := testIssue(t, "Steps to reproduce.",
+		jira.Comment{ID: "100", Author: jira.User{EmailAddress: "ana@example.com"}, Body: mustADF(t, body), Created: "2024-03-01T10:00:00.000+0000"},
+		jira.Comment{ID: "101", Author: jira.User{EmailAddress: "bo@example.com"}, Body: mustADF(t, "Fixed."), Created: "2024-03-02T10:00:00.000+0000"},
+	)
+
+	ticket := roundTrip(t, issue, MarshalOptions{})
+	if len(ticket.Comments) != 2 {
+		t.Fatalf("expected 2 comments, got %d: %+v", len(ticket.Comments), ticket.Comments)
+	}
+	first := ticket.Comments[0]
+	if first.ID != "100" || first.New || first.Author != "ana@example.com" {
+		t.Errorf("unexpected first comment: %+v", first)
+	}
+	if first.Body != ADFToBody(issue.Fields.Comment.Comments[0].Body) {
+		t.Errorf("comment body changed in the round trip:\n%s", first.Body)
+	}
+	if second := ticket.Comments[1]; second.ID != "101" || second.Body != "Fixed." {
+		t.Errorf("unexpected second comment: %+v", second)
+	}
+}
+
+func TestParseComments_NewComments(t *testing.T) {
+	section := "\n### ana@example.com - 2024-03-01\n" + commentIDPrefix + "100 -->\n\nLooks good.\n\n" +
+		"### new\n\nDeployed to staging.\n\n### Notes\n\nStill part of the new comment.\n"
+
+	comments, err := parseComments(section)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 2 {
+		t.Fatalf("expected 2 comments, got %+v", comments)
+	}
+	if c := comments[0]; c.ID != "100" || c.New || c.Body != "Looks good." {
+		t.Errorf("unexpected pulled comment: %+v", c)
+	}
+	if c := comments[1]; !c.New || c.Body != "Deployed to staging.\n\n### Notes\n\nStill part of the new comment." {
+		t.Errorf("unexpected new comment: %+v", c)
+	}
+}
+
+func TestParseComments_WithoutIDs(t *testing.T) {
+	// Files pulled before comment ids were recorded
+	section := "\n### ana@example.com - 2024-03-01\n\nFirst.\n\n### bo@example.com - 2024-03-02\n\nSecond.\n"
+
+	comments, err := parseComments(section)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 2 || comments[0].Body != "First." || comments[1].Date != "2024-03-02" {
+		t.Fatalf("unexpected comments: %+v", comments)
+	}
+	for _, c := range comments {
+		if c.ID != "" || c.New {
+			t.Errorf("expected an existing comment without id, got %+v", c)
+		}
+	}
+}
+
+func TestRoundTrip_DescriptionWithLinksHeading(t *testing.T) {
+	issue := testIssue(t, "## Links\n\n- blocks PROJ-9 in the old tracker\n\nSee the design doc.")
+	issue.Fields.IssueLinks = []jira.IssueLink{{
+		Type:         jira.IssueLinkType{Name: "Blocks", Inward: "is blocked by", Outward: "blocks"},
+		OutwardIssue: &jira.LinkedIssue{Key: "PROJ-2"},
+	}}
+
+	ticket := roundTrip(t, issue, MarshalOptions{})
+	if ticket.Body != ADFToBody(issue.Fields.Description) {
+		t.Errorf("description changed in the round trip:\n%s", ticket.Body)
+	}
+	if len(ticket.Links) != 1 || ticket.Links[0] != (TicketLink{Phrase: "blocks", Key: "PROJ-2"}) {
+		t.Errorf("expected only the pulled link, got %+v", ticket.Links)
+	}
+}
+
+func TestUnmarshal_WithoutSectionsMarker(t *testing.T) {
+	// Files pulled before the sections marker was written never had
+	// generated links or reference sections, so these headings are the
+	// user's own and stay in the description
+	content := "---\nkey: PROJ-1\ntitle: Fix login\n---\n\n# PROJ-1: Fix login\n\n## Description\n\nIntro.\n\n" +
+		"## Links\n\n- blocks PROJ-2: Release\n\n## Rollout\n\nStaged.\n\n## History\n\nRegressed in 2.3.\n\n## Comments\n\n### new\n\nDone.\n"
+
+	ticket, err := Unmarshal(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Intro.\n\n## Links\n\n- blocks PROJ-2: Release\n\n## Rollout\n\nStaged.\n\n## History\n\nRegressed in 2.3."
+	if ticket.Body != want {
+		t.Errorf("expected the description untouched, got %q", ticket.Body)
+	}
+	if ticket.Links != nil {
+		t.Errorf("expected links to be left alone, got %+v", ticket.Links)
+	}
+	if len(ticket.Comments) != 1 || !ticket.Comments[0].New {
+		t.Errorf("unexpected comments: %+v", ticket.Comments)
+	}
+}
+
+func TestParseComments_TextBeforeFirstComment(t *testing.T) {
+	for _, section := range []string{
+		"\n### Notes\n\nMeant as a new comment.\n",
+		"\nForgot the heading.\n\n### new\n\nDone.\n",
+	} {
+		if _, err := parseComments(section); err == nil || !strings.Contains(err.Error(), "### new") {
+			t.Errorf("expected an error for %q, got %v", section, err)
+		}
+	}
+}
+
+func TestUnmarshal_NoDescription(t *testing.T) {
+	ticket := roundTrip(t, &jira.Issue{Key: "PROJ-1", Fields: jira.Fields{Summary: "Fix login"}}, MarshalOptions{})
+	if ticket.Body != "" {
+		t.Errorf("expected an empty description, got %q", ticket.Body)
+	}
+}
+
+func TestRoundTrip_DescriptionWithSectionHeadings(t *testing.T) {
+	issue := testIssue(t, "Intro.\n\n## Attachments\n\nScreenshots are in the wiki.\n\n## Worklog\n\nEstimate first.\n\n## History\n\nRegressed in 2.3.")
+	opts := MarshalOptions{
+		Worklogs: []jira.Worklog{{Author: jira.User{EmailAddress: "ana@example.com"}, TimeSpentSeconds: 3600}},
+		History:  []jira.ChangelogEntry{},
+	}
+
+	ticket := roundTrip(t, issue, opts)
+	if ticket.Body != ADFToBody(issue.Fields.Description) {
+		t.Errorf("description changed in the round trip:\n%s", ticket.Body)
+	}
+}
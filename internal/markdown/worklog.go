@@ -0,0 +1,47 @@
+package markdown
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mreider/a-cli/internal/dateparse"
+	"github.com/mreider/a-cli/internal/jira"
+)
+
+// WorklogAuthor returns the email address of a worklog's author, falling back
+// to the display name when the email is hidden.
+func WorklogAuthor(w jira.Worklog) string {
+	return userLabel(w.Author)
+}
+
+// userLabel identifies a user by email address, or by display name when the
+// email is hidden by the user's privacy settings.
+func userLabel(u jira.User) string {
+	if u.EmailAddress != "" {
+		return u.EmailAddress
+	}
+	return u.DisplayName
+}
+
+// writeWorklog renders the ## Worklog section: time spent per author and in total.
+func writeWorklog(b *strings.Builder, worklogs []jira.Worklog) {
+	perAuthor := make(map[string]int)
+	total := 0
+	for _, w := range worklogs {
+		perAuthor[WorklogAuthor(w)] += w.TimeSpentSeconds
+		total += w.TimeSpentSeconds
+	}
+
+	authors := make([]string, 0, len(perAuthor))
+	for a := range perAuthor {
+		authors = append(authors, a)
+	}
+	sort.Strings(authors)
+
+	b.WriteString("## Worklog\n\n")
+	for _, a := range authors {
+		b.WriteString(fmt.Sprintf("- %s: %s\n", a, dateparse.FormatDuration(perAuthor[a])))
+	}
+	b.WriteString(fmt.Sprintf("- **Total**: %s\n\n", dateparse.FormatDuration(total)))
+}
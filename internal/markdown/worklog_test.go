@@ -0,0 +1,64 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mreider/a-cli/internal/jira"
+)
+
+func TestWriteWorklog(t *testing.T) {
+	var b strings.Builder
+	writeWorklog(&b, []jira.Worklog{
+		{Author: jira.User{EmailAddress: "bo@example.com"}, TimeSpentSeconds: 45 * 60},
+		{Author: jira.User{EmailAddress: "ana@example.com"}, TimeSpentSeconds: 2 * 3600},
+		{Author: jira.User{DisplayName: "Hidden Email"}, TimeSpentSeconds: 30 * 60},
+		{Author: jira.User{EmailAddress: "bo@example.com"}, TimeSpentSeconds: 15 * 60},
+	})
+
+	want := "## Worklog\n\n" +
+		"- Hidden Email: 30m\n" +
+		"- ana@example.com: 2h\n" +
+		"- bo@example.com: 1h\n" +
+		"- **Total**: 3h 30m\n\n"
+	if b.String() != want {
+		t.Errorf("unexpected worklog section:\n%s", b.String())
+	}
+
+	// An empty worklog still shows the total
+	b.Reset()
+	writeWorklog(&b, []jira.Worklog{})
+	if b.String() != "## Worklog\n\n- **Total**: 0m\n\n" {
+		t.Errorf("unexpected empty worklog section:\n%s", b.String())
+	}
+}
+
+func TestUnmarshal_WorklogSection(t *testing.T) {
+	issue := testIssue(t, "Steps to reproduce.")
+	issue.Fields.IssueLinks = []jira.IssueLink{{
+		Type:         jira.IssueLinkType{Name: "Blocks", Inward: "is blocked by", Outward: "blocks"},
+		OutwardIssue: &jira.LinkedIssue{Key: "PROJ-2"},
+	}}
+	content, err := Marshal(issue, "https://example.atlassian.net", nil, MarshalOptions{
+		Worklogs: []jira.Worklog{{Author: jira.User{EmailAddress: "ana@example.com"}, TimeSpentSeconds: 3600}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content, "## Worklog\n\n- ana@example.com: 1h\n- **Total**: 1h\n") {
+		t.Fatalf("expected a worklog section:\n%s", content)
+	}
+
+	// The worklog is for reference: it isn't part of the description, and its
+	// lines aren't read as links
+	ticket, err := Unmarshal(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticket.Body != "Steps to reproduce." {
+		t.Errorf("expected the description alone, got %q", ticket.Body)
+	}
+	if len(ticket.Links) != 1 || ticket.Links[0].Key != "PROJ-2" {
+		t.Errorf("expected only the pulled link, got %+v", ticket.Links)
+	}
+}
@@ -0,0 +1,112 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"time"
+)
+
+// callbackPath is where the loopback listener expects the redirect.
+const callbackPath = "/callback"
+
+// A Listener receives the authorization redirect on the loopback interface.
+type Listener struct {
+	ln net.Listener
+
+	// RedirectURL is the callback URL to register with the app and pass
+	// as Config.RedirectURL.
+	RedirectURL string
+}
+
+// Listen starts listening on 127.0.0.1:port. Atlassian only redirects to
+// the exact callback URL registered for the app, so the port is fixed
+// rather than chosen by the system.
+func Listen(port int) (*Listener, error) {
+	ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return nil, fmt.Errorf("listening for the OAuth redirect: %w", err)
+	}
+	return &Listener{
+		ln:          ln,
+		RedirectURL: fmt.Sprintf("http://%s%s", ln.Addr(), callbackPath),
+	}, nil
+}
+
+// NewState returns a random state value for AuthCodeURL.
+func NewState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Wait serves the listener until the browser is redirected back with an
+// authorization code for state, the user denies access, or ctx is done. It
+// closes the listener before returning.
+func (l *Listener) Wait(ctx context.Context, state string) (string, error) {
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(callbackPath, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var res result
+		switch {
+		case q.Get("state") != state:
+			// Not ours (or forged); keep waiting for the real redirect.
+			http.Error(w, "Unexpected state parameter.", http.StatusBadRequest)
+			return
+		case q.Get("error") != "":
+			res.err = fmt.Errorf("authorization failed: %s", q.Get("error"))
+			if desc := q.Get("error_description"); desc != "" {
+				res.err = fmt.Errorf("authorization failed: %s: %s", q.Get("error"), desc)
+			}
+		case q.Get("code") == "":
+			res.err = errors.New("authorization failed: no code in the redirect")
+		default:
+			res.code = q.Get("code")
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if res.err != nil {
+			fmt.Fprintf(w, "<p>%s</p><p>You can close this window.</p>", html.EscapeString(res.err.Error()))
+		} else {
+			fmt.Fprint(w, "<p>a-cli is signed in. You can close this window.</p>")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+	})
+
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(l.ln)
+	defer func() {
+		// Let the browser get its page before the server goes away.
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	select {
+	case res := <-results:
+		return res.code, res.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Close stops listening.
+func (l *Listener) Close() error {
+	return l.ln.Close()
+}
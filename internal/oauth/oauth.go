@@ -0,0 +1,288 @@
+// Package oauth implements Atlassian's OAuth 2.0 (3LO) authorization-code
+// flow with PKCE: building the authorization URL, receiving the redirect on
+// a loopback listener, exchanging and refreshing tokens, and finding the
+// cloud id that requests are routed through the API gateway with.
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoint holds the URLs of an OAuth authorization server.
+type Endpoint struct {
+	AuthURL      string
+	TokenURL     string
+	ResourcesURL string // lists the sites a token grants access to
+}
+
+// Atlassian is the Atlassian Cloud authorization server.
+var Atlassian = Endpoint{
+	AuthURL:      "https://auth.atlassian.com/authorize",
+	TokenURL:     "https://auth.atlassian.com/oauth/token",
+	ResourcesURL: "https://api.atlassian.com/oauth/token/accessible-resources",
+}
+
+// GatewayURL is the API gateway OAuth requests go through, as
+// {GatewayURL}/ex/jira/{cloudid} and {GatewayURL}/ex/confluence/{cloudid}.
+var GatewayURL = "https://api.atlassian.com"
+
+// Scopes are requested when authorizing: read and write access to JIRA
+// issues and Confluence pages, and offline_access for a refresh token.
+var Scopes = []string{
+	"read:jira-work",
+	"write:jira-work",
+	"read:jira-user",
+	"read:page:confluence",
+	"write:page:confluence",
+	"read:space:confluence",
+	"read:comment:confluence",
+	"read:hierarchical-content:confluence",
+	"read:attachment:confluence",
+	"search:confluence",
+	"offline_access",
+}
+
+// expiryDelta is how long before it expires an access token is refreshed,
+// so it doesn't expire in flight.
+const expiryDelta = time.Minute
+
+// Config describes an OAuth app registered in the Atlassian developer
+// console.
+type Config struct {
+	ClientID     string
+	ClientSecret string // may be empty for apps relying on PKCE alone
+	RedirectURL  string
+	Endpoint     Endpoint
+
+	// HTTPClient sends token requests; nil means http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Token is the token endpoint's answer.
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+
+	// Expiry is when AccessToken stops working, worked out from ExpiresIn.
+	Expiry time.Time `json:"-"`
+}
+
+// Resource is a site a token grants access to.
+type Resource struct {
+	ID     string   `json:"id"` // the cloud id
+	URL    string   `json:"url"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// NewVerifier returns a random PKCE code verifier.
+func NewVerifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating code verifier: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// challenge returns the S256 PKCE code challenge for verifier.
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the URL the user opens to grant access. state is
+// echoed back on the redirect; verifier must be passed to Exchange.
+func (c Config) AuthCodeURL(state, verifier string) string {
+	params := url.Values{}
+	params.Set("audience", "api.atlassian.com")
+	params.Set("client_id", c.ClientID)
+	params.Set("scope", strings.Join(Scopes, " "))
+	params.Set("redirect_uri", c.RedirectURL)
+	params.Set("state", state)
+	params.Set("response_type", "code")
+	params.Set("prompt", "consent")
+	params.Set("code_challenge", challenge(verifier))
+	params.Set("code_challenge_method", "S256")
+	return c.Endpoint.AuthURL + "?" + params.Encode()
+}
+
+// Exchange trades an authorization code for tokens.
+func (c Config) Exchange(ctx context.Context, code, verifier string) (*Token, error) {
+	return c.token(ctx, map[string]string{
+		"grant_type":    "authorization_code",
+		"code":          code,
+		"redirect_uri":  c.RedirectURL,
+		"code_verifier": verifier,
+	})
+}
+
+// Refresh trades a refresh token for a new access token. The answer usually
+// carries a new refresh token too, replacing the old one.
+func (c Config) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	return c.token(ctx, map[string]string{
+		"grant_type":    "refresh_token",
+		"refresh_token": refreshToken,
+	})
+}
+
+func (c Config) token(ctx context.Context, params map[string]string) (*Token, error) {
+	params["client_id"] = c.ClientID
+	if c.ClientSecret != "" {
+		params["client_secret"] = c.ClientSecret
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling token request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.Endpoint.TokenURL, bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("creating token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	var token Token
+	if err := c.send(req, &token); err != nil {
+		return nil, fmt.Errorf("requesting OAuth token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("requesting OAuth token: no access token in the response")
+	}
+	if token.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return &token, nil
+}
+
+// Resources lists the sites accessToken grants access to.
+func (c Config) Resources(ctx context.Context, accessToken string) ([]Resource, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.Endpoint.ResourcesURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	var resources []Resource
+	if err := c.send(req, &resources); err != nil {
+		return nil, fmt.Errorf("listing accessible sites: %w", err)
+	}
+	return resources, nil
+}
+
+// send performs req and decodes a 2xx JSON response into v. Other statuses
+// are returned as errors carrying the server's error description.
+func (c Config) send(req *http.Request, v any) error {
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var body struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if json.Unmarshal(data, &body) == nil && body.Error != "" {
+			if body.Description != "" {
+				return fmt.Errorf("%s (%d): %s", body.Error, resp.StatusCode, body.Description)
+			}
+			return fmt.Errorf("%s (%d)", body.Error, resp.StatusCode)
+		}
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
+// FindResource returns the resource for siteURL, or the only resource when
+// siteURL is empty.
+func FindResource(resources []Resource, siteURL string) (Resource, error) {
+	site := strings.TrimRight(siteURL, "/")
+	for _, r := range resources {
+		if (site == "" && len(resources) == 1) || strings.EqualFold(strings.TrimRight(r.URL, "/"), site) {
+			return r, nil
+		}
+	}
+	var urls []string
+	for _, r := range resources {
+		urls = append(urls, r.URL)
+	}
+	if len(urls) == 0 {
+		return Resource{}, fmt.Errorf("the authorization grants access to no sites")
+	}
+	return Resource{}, fmt.Errorf("the authorization doesn't grant access to %s; it grants: %s", siteURL, strings.Join(urls, ", "))
+}
+
+// A TokenSource hands out access tokens, refreshing them as they expire.
+// It is safe for concurrent use.
+type TokenSource struct {
+	cfg      Config
+	onRotate func(refreshToken string)
+
+	mu      sync.Mutex
+	refresh string
+	access  string
+	expiry  time.Time
+}
+
+// NewTokenSource returns a TokenSource that starts from refreshToken.
+// onRotate, if not nil, is called with each new refresh token the server
+// issues so it can be saved for later runs.
+func NewTokenSource(cfg Config, refreshToken string, onRotate func(refreshToken string)) *TokenSource {
+	return &TokenSource{cfg: cfg, refresh: refreshToken, onRotate: onRotate}
+}
+
+// AccessToken returns a current access token, refreshing it first when it
+// is missing or about to expire.
+func (s *TokenSource) AccessToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.access != "" && (s.expiry.IsZero() || time.Until(s.expiry) > expiryDelta) {
+		return s.access, nil
+	}
+
+	token, err := s.cfg.Refresh(ctx, s.refresh)
+	if err != nil {
+		return "", fmt.Errorf("%w (run 'a-cli config --oauth' to sign in again)", err)
+	}
+	s.access, s.expiry = token.AccessToken, token.Expiry
+	if token.RefreshToken != "" && token.RefreshToken != s.refresh {
+		s.refresh = token.RefreshToken
+		if s.onRotate != nil {
+			s.onRotate(token.RefreshToken)
+		}
+	}
+	return s.access, nil
+}
+
+// StaticToken is a fixed access token, for tests and replays.
+type StaticToken string
+
+// AccessToken returns t.
+func (t StaticToken) AccessToken(context.Context) (string, error) {
+	return string(t), nil
+}
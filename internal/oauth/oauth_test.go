@@ -0,0 +1,174 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tokenServer answers token requests with consecutive access and refresh
+// tokens, recording each request body.
+func tokenServer(t *testing.T, requests *[]map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		*requests = append(*requests, body)
+		if body["refresh_token"] == "revoked" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error":"unauthorized_client","error_description":"refresh_token is invalid"}`))
+			return
+		}
+		n := len(*requests)
+		fmt.Fprintf(w, `{"access_token":"access-%d","refresh_token":"refresh-%d","expires_in":3600}`, n, n)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAuthCodeURLAndExchange(t *testing.T) {
+	var requests []map[string]string
+	srv := tokenServer(t, &requests)
+
+	app := Config{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://127.0.0.1:8765/callback",
+		Endpoint:     Endpoint{AuthURL: "https://auth.example/authorize", TokenURL: srv.URL},
+	}
+	verifier, err := NewVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(app.AuthCodeURL("xyz", verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("state") != "xyz" || q.Get("code_challenge") != challenge(verifier) || q.Get("code_challenge_method") != "S256" {
+		t.Errorf("unexpected authorization URL: %s", u)
+	}
+	if !strings.Contains(q.Get("scope"), "offline_access") {
+		t.Errorf("expected offline_access scope, got %q", q.Get("scope"))
+	}
+
+	token, err := app.Exchange(context.Background(), "the-code", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "access-1" || token.RefreshToken != "refresh-1" || time.Until(token.Expiry) < 59*time.Minute {
+		t.Errorf("unexpected token: %+v", token)
+	}
+	got := requests[0]
+	if got["grant_type"] != "authorization_code" || got["code"] != "the-code" || got["code_verifier"] != verifier ||
+		got["client_secret"] != "secret" || got["redirect_uri"] != app.RedirectURL {
+		t.Errorf("unexpected token request: %v", got)
+	}
+}
+
+func TestTokenSourceRefreshesAndRotates(t *testing.T) {
+	var requests []map[string]string
+	srv := tokenServer(t, &requests)
+
+	var rotated []string
+	ts := NewTokenSource(Config{ClientID: "client", Endpoint: Endpoint{TokenURL: srv.URL}}, "refresh-0", func(token string) {
+		rotated = append(rotated, token)
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		token, err := ts.AccessToken(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "access-1" {
+			t.Errorf("expected the cached access token, got %s", token)
+		}
+	}
+	if len(requests) != 1 || requests[0]["refresh_token"] != "refresh-0" || requests[0]["grant_type"] != "refresh_token" {
+		t.Fatalf("expected one refresh with the saved token, got %v", requests)
+	}
+
+	// Once it is about to expire, the token is refreshed with the rotated
+	// refresh token.
+	ts.expiry = time.Now().Add(30 * time.Second)
+	if token, err := ts.AccessToken(ctx); err != nil || token != "access-2" {
+		t.Fatalf("expected a refreshed token, got %s, %v", token, err)
+	}
+	if requests[1]["refresh_token"] != "refresh-1" {
+		t.Errorf("expected the rotated refresh token to be used, got %v", requests[1])
+	}
+	if len(rotated) != 2 || rotated[1] != "refresh-2" {
+		t.Errorf("expected each new refresh token to be reported, got %v", rotated)
+	}
+}
+
+func TestTokenSourceRefreshError(t *testing.T) {
+	var requests []map[string]string
+	srv := tokenServer(t, &requests)
+
+	ts := NewTokenSource(Config{ClientID: "client", Endpoint: Endpoint{TokenURL: srv.URL}}, "revoked", nil)
+	_, err := ts.AccessToken(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "refresh_token is invalid") || !strings.Contains(err.Error(), "config --oauth") {
+		t.Errorf("expected the server's reason and a hint, got %v", err)
+	}
+}
+
+func TestListenerWait(t *testing.T) {
+	l, err := Listen(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	done := make(chan struct{})
+	var code string
+	var waitErr error
+	go func() {
+		code, waitErr = l.Wait(context.Background(), "good")
+		close(done)
+	}()
+
+	// A redirect with the wrong state is refused and doesn't end the wait.
+	resp, err := http.Get(l.RedirectURL + "?state=bad&code=forged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for the wrong state, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(l.RedirectURL + "?state=good&code=abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	<-done
+	if waitErr != nil || code != "abc" {
+		t.Errorf("expected code abc, got %q, %v", code, waitErr)
+	}
+}
+
+func TestFindResource(t *testing.T) {
+	resources := []Resource{
+		{ID: "1", URL: "https://a.atlassian.net"},
+		{ID: "2", URL: "https://b.atlassian.net"},
+	}
+	if r, err := FindResource(resources, "https://B.atlassian.net/"); err != nil || r.ID != "2" {
+		t.Errorf("expected site b, got %+v, %v", r, err)
+	}
+	if _, err := FindResource(resources, "https://c.atlassian.net"); err == nil || !strings.Contains(err.Error(), "https://a.atlassian.net") {
+		t.Errorf("expected an error listing the sites, got %v", err)
+	}
+	if r, err := FindResource(resources[:1], ""); err != nil || r.ID != "1" {
+		t.Errorf("expected the only site, got %+v, %v", r, err)
+	}
+}